@@ -23,6 +23,15 @@ var defaultConf = map[string]*configItem{
 		label:        "<address>:<port>",
 		description: `
 Specifies the address and the port number of a daemon in a form <code><var>address</var>:<var>port</var></code>.
+`,
+	},
+	"node_url": {
+		defaultValue: "",
+		label:        "<url>",
+		description: `
+Specifies the base URL, such as ` + "`" + `http://10.0.0.1:8080` + "`" + `, at which other instances reach the API of this instance.  If specified, a job pushed to [another instance][section-backup] immediately wakes up the dispatcher of this instance when it is active on the queue of the job.  Otherwise, the job is noticed at the next check of the queue.
+
+This is in effect only when [the queue driver](#env-queue-driver) is ` + "`" + `mysql` + "`" + `, ` + "`" + `postgres` + "`" + ` or ` + "`" + `redis` + "`" + `.
 `,
 	},
 	"pid": {
@@ -90,9 +99,11 @@ Specifies an interval, in milliseconds, at which a Fireworq daemon checks if con
 		defaultValue: "mysql",
 		label:        "<driver>",
 		description: `
-Specifies a driver for job queues and repositories.  The available values are ` + "`" + `mysql` + "`" + ` and ` + "`in-memory`" + `.
+Specifies a driver for job queues and repositories.  The available values are ` + "`" + `mysql` + "`" + `, ` + "`" + `postgres` + "`" + `, ` + "`" + `sqlite` + "`" + ` and ` + "`in-memory`" + `.
+
+` + "`" + `sqlite` + "`" + ` driver stores everything in a single file in [the data directory](#env-sqlite-dir).  It is intended for a small deployment with a single instance; never run more than one instance against the same directory.
 
-Note that ` + "`in-memory`" + ` driver is not for production use.  It is intended to be used for just playing with Fireworq without a storage middleware or to show the upper bound of performance in a benchmark.
+Note that ` + "`in-memory`" + ` driver is not for production use.  It is intended to be used for just playing with Fireworq without a storage middleware or to show the upper bound of performance in a benchmark.  Its data survive restarts only if [the snapshot](#env-inmemory-snapshot) is enabled.
 `,
 	},
 	"mysql_dsn": {
@@ -107,6 +118,43 @@ Specifies a data source name for the job queue and the repository database in a
 		label:        "<DSN>",
 		description: `
 Specifies a data source name for the repository database in a form <code><var>user</var>:<var>password</var>@tcp(<var>mysql_host</var>:<var>mysql_port</var>)/<var>database</var>?<var>options</var></code>.  This is in effect only when the [driver](#env-driver) is ` + "`" + `mysql` + "`" + ` and overrides [the default DSN](#env-mysql-dsn).  This should be used when you want to specify a DSN differs from [the queue DSN](#env-queue-mysql-dsn).
+`,
+	},
+	"postgres_dsn": {
+		defaultValue: "",
+		label:        "<DSN>",
+		description: `
+Specifies a data source name for the job queue and the repository database in a form <code>postgres://<var>user</var>:<var>password</var>@<var>postgres_host</var>:<var>postgres_port</var>/<var>database</var>?<var>options</var></code> or <code>host=<var>postgres_host</var> dbname=<var>database</var> <var>...</var></code>.  This is in effect only when [the driver](#env-driver) is ` + "`" + `postgres` + "`" + `.  If it is empty, the connection parameters are taken from the environment variables of libpq such as ` + "`" + `PGHOST` + "`" + `.
+`,
+	},
+	"repository_postgres_dsn": {
+		defaultValue: "",
+		label:        "<DSN>",
+		description: `
+Specifies a data source name for the repository database in the same form as [the default DSN](#env-postgres-dsn).  This is in effect only when the [driver](#env-driver) is ` + "`" + `postgres` + "`" + ` and overrides [the default DSN](#env-postgres-dsn).  This should be used when you want to specify a DSN differs from [the queue DSN](#env-queue-postgres-dsn).
+`,
+	},
+	"sqlite_dir": {
+		defaultValue: ".",
+		label:        "<directory>",
+		description: `
+Specifies a directory where the database file ` + "`" + `fireworq.db` + "`" + ` for the job queues and the repositories is stored.  The directory is created if it doesn't exist.  This is in effect only when [the driver](#env-driver) is ` + "`" + `sqlite` + "`" + `.
+`,
+	},
+	"inmemory_snapshot": {
+		defaultValue: "",
+		label:        "<file>",
+		description: `
+Specifies a file where the job queues and the repositories of ` + "`in-memory`" + ` driver are saved [at every interval](#env-inmemory-snapshot-interval) and on shutting down.  They are restored from the file on starting up.  Jobs running at the time of saving are restored as waiting ones and tried again.  If it is empty, nothing is saved.
+
+This is in effect only when [the driver](#env-driver) or [the queue driver](#env-queue-driver) is ` + "`in-memory`" + `.  Jobs completed after the last snapshot may be tried again and jobs pushed after it may be lost when the daemon dies unexpectedly.
+`,
+	},
+	"inmemory_snapshot_interval": {
+		defaultValue: "60",
+		label:        "<seconds>",
+		description: `
+Specifies an interval, in seconds, at which [the snapshot](#env-inmemory-snapshot) is saved.  If it is zero or negative, the snapshot is saved only on shutting down.
 `,
 	},
 	"queue_default": {
@@ -154,6 +202,22 @@ Specifies the value of ` + "`" + `tag` + "`" + ` field in a job queue log item J
 		description: `
 Specifies a log level of the job queue logs.  ` + logLevelDescription + `
 If none of these values is specified, the level is determined by ` + "`" + `DEBUG` + "`" + ` environment variable.  If ` + "`" + `DEBUG` + "`" + ` has a non-empty value, then the level is ` + "`" + `debug` + "`" + `.  Otherwise, the level is ` + "`" + `info` + "`" + `.
+`,
+	},
+	"queue_driver": {
+		defaultValue: "",
+		label:        "<driver>",
+		description: `
+Specifies a driver only for job queues, which overrides [the driver](#env-driver).  The available values are those of [the driver](#env-driver) and ` + "`" + `redis` + "`" + `.  If it is empty, job queues use [the driver](#env-driver).
+
+` + "`" + `redis` + "`" + ` driver stores jobs in a Redis server specified by [the Redis URL](#env-queue-redis-url) while repositories are still stored by [the driver](#env-driver).  It suits a high throughput of short-lived jobs.  Note that jobs may be lost when the Redis server dies unless it is configured to persist data.
+`,
+	},
+	"queue_max_kept_retries": {
+		defaultValue: "100",
+		label:        "<number>",
+		description: `
+Specifies the maximum number of failures of a job up to which its retries do not consume its ` + "`" + `retry_count` + "`" + ` when a worker responds with ` + "`" + `keep_retry_count` + "`" + `.  Once a job has failed more times than this value, ` + "`" + `keep_retry_count` + "`" + ` is ignored and the job eventually fails permanently.
 `,
 	},
 	"queue_mysql_dsn": {
@@ -161,6 +225,27 @@ If none of these values is specified, the level is determined by ` + "`" + `DEBU
 		label:        "<DSN>",
 		description: `
 Specifies a data source name for the job queue database in a form <code><var>user</var>:<var>password</var>@tcp(<var>mysql_host</var>:<var>mysql_port</var>)/<var>database</var>?<var>options</var></code>.  This is in effect only when the [driver](#env-driver) is ` + "`" + `mysql` + "`" + ` and overrides [the default DSN](#env-mysql-dsn).  This should be used when you want to specify a DSN differs from [the repository DSN](#env-repository-mysql-dsn).
+`,
+	},
+	"queue_mysql_shards": {
+		defaultValue: "",
+		label:        "<name>=<DSN> ...",
+		description: `
+Specifies MySQL databases which a queue can be sharded across as whitespace-separated pairs of a shard name and a data source name, such as ` + "`" + `shard1=user:password@tcp(10.0.0.1:3306)/fireworq shard2=user:password@tcp(10.0.0.2:3306)/fireworq` + "`" + `.  A queue refers to the shards by their names in its ` + "`" + `shards` + "`" + ` definition.  This is in effect only when the [driver](#env-driver) is ` + "`" + `mysql` + "`" + `.
+`,
+	},
+	"queue_postgres_dsn": {
+		defaultValue: "",
+		label:        "<DSN>",
+		description: `
+Specifies a data source name for the job queue database in the same form as [the default DSN](#env-postgres-dsn).  This is in effect only when the [driver](#env-driver) is ` + "`" + `postgres` + "`" + ` and overrides [the default DSN](#env-postgres-dsn).  This should be used when you want to specify a DSN differs from [the repository DSN](#env-repository-postgres-dsn).
+`,
+	},
+	"queue_redis_url": {
+		defaultValue: "redis://localhost:6379/0",
+		label:        "<url>",
+		description: `
+Specifies the URL of a Redis server for job queues in a form <code>redis://:<var>password</var>@<var>redis_host</var>:<var>redis_port</var>/<var>db</var></code>.  This is in effect only when [the queue driver](#env-queue-driver) is ` + "`" + `redis` + "`" + `.
 `,
 	},
 	"dispatch_user_agent": {
@@ -173,21 +258,86 @@ Specifies the value of ` + "`" + `User-Agent` + "`" + ` header field used for an
 	"dispatch_keep_alive": {
 		label: "true|false",
 		description: `
-Specifies whether a connection to a worker should be reused.  This overrides [the default keep-alive setting](#env-keep-alive).
+Specifies whether a connection to a worker should be reused.  This overrides [the default keep-alive setting](#env-keep-alive).  A queue may override it by ` + "`" + `keep_alive` + "`" + `.
 `,
 	},
 	"dispatch_max_conns_per_host": {
 		defaultValue: "10",
 		label:        "<number>",
 		description: `
-Specifies maximum idle connections to keep per-host. This value works only when [connections of the dispatcher are reused](#env-dispatch-keep-alive).
+Specifies maximum idle connections to keep per-host. This value works only when [connections of the dispatcher are reused](#env-dispatch-keep-alive).  A queue may override it by ` + "`" + `max_idle_conns_per_host` + "`" + `.
 `,
 	},
 	"dispatch_idle_conn_timeout": {
 		defaultValue: "0",
 		label:        "<seconds>",
 		description: `
-Specifies the maximum amount of time of an idle (keep-alive) connection will remain idle before closing itself. If zero, an idle connections will not be closed. 
+Specifies the maximum amount of time of an idle (keep-alive) connection will remain idle before closing itself. If zero, an idle connections will not be closed.  A queue may override it by ` + "`" + `idle_conn_timeout` + "`" + `.
+`,
+	},
+	"dispatch_max_retry_after": {
+		defaultValue: "86400",
+		label:        "<seconds>",
+		description: `
+Specifies the maximum delay, in seconds, before retrying a job which a worker can request by ` + "`" + `Retry-After` + "`" + ` header field or ` + "`" + `retry_after` + "`" + ` of a result.  A longer delay is shortened to this value.  If zero, the delay is not limited.
+`,
+	},
+	"dispatch_command_enabled": {
+		defaultValue: "false",
+		label:        "true|false",
+		description: `
+Specifies whether a queue may be configured to run a command on the host of Fireworq for each job instead of making an HTTP request to a worker.  Enable this only if the API is accessible only by trusted clients since anyone who can define a queue can run any command.
+`,
+	},
+	"dispatch_kicker": {
+		defaultValue: "adaptive",
+		label:        "adaptive|polling",
+		description: `
+Specifies how a dispatcher checks the arrival of new jobs.  If ` + "`" + `polling` + "`" + `, it checks on the fixed ` + "`" + `polling_interval` + "`" + ` of the queue.  If ` + "`" + `adaptive` + "`" + `, it checks immediately when a job is pushed to the daemon or the earliest deferred job becomes due, and otherwise on an interval which starts from ` + "`" + `polling_interval` + "`" + `, grows up to five times as long while no job arrives and shrinks down to a fifth while more jobs are due than the dispatcher can take at once.
+`,
+	},
+	"dispatch_circuit_breaker_threshold": {
+		defaultValue: "0",
+		label:        "<number>",
+		description: `
+Specifies the number of consecutive failures of requests to a worker host, which are internal failures such as connection errors or responses with 5xx status codes, to open the circuit breaker for the host.  While the breaker is open, jobs to the host are postponed without being counted as failures.  If zero, the circuit breaker is disabled.
+`,
+	},
+	"dispatch_circuit_breaker_timeout": {
+		defaultValue: "30",
+		label:        "<seconds>",
+		description: `
+Specifies how long the circuit breaker stays open before trying a single job to the host.  If the job succeeds, the breaker closes; otherwise it opens again.
+`,
+	},
+	"dispatch_tls_cert_file": {
+		defaultValue: "",
+		label:        "<file>",
+		description: `
+Specifies a PEM file of a client certificate presented to a worker over HTTPS.  This should be specified with [the key file](#env-dispatch-tls-key-file).  A queue may override it by ` + "`" + `tls_cert_file` + "`" + `.
+
+Certificate files are reloaded when the daemon receives ` + "`" + `SIGUSR1` + "`" + ` or when they are modified.
+`,
+	},
+	"dispatch_tls_key_file": {
+		defaultValue: "",
+		label:        "<file>",
+		description: `
+Specifies a PEM file of the private key of [the client certificate](#env-dispatch-tls-cert-file).  A queue may override it by ` + "`" + `tls_key_file` + "`" + `.
+`,
+	},
+	"dispatch_tls_ca_file": {
+		defaultValue: "",
+		label:        "<file>",
+		description: `
+Specifies a PEM file of CA certificates to verify a worker over HTTPS instead of the system CA certificates.  A queue may override it by ` + "`" + `tls_ca_file` + "`" + `.
+`,
+	},
+	"dispatch_tls_min_version": {
+		defaultValue: "",
+		label:        "1.0|1.1|1.2|1.3",
+		description: `
+Specifies the minimum TLS version of a connection to a worker.  If empty, the default of the Go runtime is used.  A queue may override it by ` + "`" + `tls_min_version` + "`" + `.
 `,
 	},
 }
@@ -0,0 +1,7 @@
+SELECT job_id FROM `{{.JobQueue}}` AS j
+WHERE status = 'grabbed' AND NOT EXISTS (
+  SELECT 1 FROM `fireworq_grabber` AS g
+  WHERE g.grabber_id = j.grabber_id
+    AND g.expires_at > FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000)
+)
+LIMIT 1000
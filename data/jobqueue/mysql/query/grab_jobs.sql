@@ -1,5 +1,9 @@
-SELECT job_id FROM `{{.JobQueue}}`
+SELECT job_id FROM `{{.JobQueue}}` AS j
 WHERE status = 'claimed'
   AND next_try <= FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000)
+  AND (group_id = '' OR NOT EXISTS (
+    SELECT 1 FROM `{{.JobQueue}}` AS h
+    WHERE h.group_id = j.group_id AND h.job_id < j.job_id
+  ))
 ORDER BY next_try ASC
 LIMIT
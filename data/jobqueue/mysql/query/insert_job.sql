@@ -1,2 +1,2 @@
-INSERT INTO `{{.JobQueue}}` (next_try, created_at, retry_count, retry_delay, fail_count, category, url, payload, timeout)
-VALUES (FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000) + ?, FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000), ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO `{{.JobQueue}}` (next_try, created_at, retry_count, retry_delay, fail_count, category, url, payload, timeout, concurrency_key, concurrency_limit, group_id, dead_letter)
+VALUES (FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000) + ?, FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
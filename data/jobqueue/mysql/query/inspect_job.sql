@@ -1,2 +1,2 @@
-SELECT job_id, category, url, payload, next_try, status, created_at, retry_count, retry_delay, fail_count, timeout FROM `{{.JobQueue}}`
+SELECT job_id, category, url, payload, next_try, status, created_at, retry_count, retry_delay, fail_count, timeout, concurrency_key, concurrency_limit, group_id, dead_letter FROM `{{.JobQueue}}`
 WHERE job_id = ?
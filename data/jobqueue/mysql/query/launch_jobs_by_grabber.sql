@@ -0,0 +1,3 @@
+UPDATE `{{.JobQueue}}`
+SET status = 'grabbed', grabber_id = ?
+WHERE job_id IN
@@ -0,0 +1,3 @@
+DELETE FROM `{{.Failure}}`
+WHERE failure_id <= ?
+ORDER BY failure_id ASC LIMIT
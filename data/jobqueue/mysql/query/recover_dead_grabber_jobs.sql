@@ -0,0 +1,8 @@
+UPDATE `{{.JobQueue}}` AS j USE INDEX (PRIMARY)
+SET status = 'claimed',
+    grabber_id = NULL
+WHERE status = 'grabbed' AND NOT EXISTS (
+  SELECT 1 FROM `fireworq_grabber` AS g
+  WHERE g.grabber_id = j.grabber_id
+    AND g.expires_at > FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000)
+) AND job_id IN
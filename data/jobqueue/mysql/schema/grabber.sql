@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS `fireworq_grabber` (
+  `grabber_id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `queue_name` VARCHAR(255) NOT NULL,
+  `host` VARCHAR(255) NOT NULL,
+  `url` VARCHAR(255) NOT NULL DEFAULT '',
+  `expires_at` BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY (`grabber_id`),
+  KEY `expiry` (`queue_name`, `expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary AUTO_INCREMENT=4294967296;
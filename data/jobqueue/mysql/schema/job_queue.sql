@@ -12,7 +12,12 @@ CREATE TABLE IF NOT EXISTS `{{.JobQueue}}` (
   `url` BLOB,
   `payload` MEDIUMBLOB,
   `timeout` INT UNSIGNED,
+  `concurrency_key` VARCHAR(255) NOT NULL DEFAULT '',
+  `concurrency_limit` INT UNSIGNED NOT NULL DEFAULT 0,
+  `group_id` VARCHAR(255) NOT NULL DEFAULT '',
+  `dead_letter` BOOLEAN NOT NULL DEFAULT FALSE,
 
   PRIMARY KEY (`job_id`),
-  KEY `grab` (`status`, `next_try`)
+  KEY `grab` (`status`, `next_try`),
+  KEY `group_head` (`group_id`, `job_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=binary;
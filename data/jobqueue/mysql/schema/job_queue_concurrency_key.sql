@@ -0,0 +1,3 @@
+ALTER TABLE `{{.JobQueue}}`
+  ADD COLUMN `concurrency_key` VARCHAR(255) NOT NULL DEFAULT '' AFTER `timeout`,
+  ADD COLUMN `concurrency_limit` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `concurrency_key`
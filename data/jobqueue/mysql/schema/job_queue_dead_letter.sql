@@ -0,0 +1,2 @@
+ALTER TABLE `{{.JobQueue}}`
+  ADD COLUMN `dead_letter` BOOLEAN NOT NULL DEFAULT FALSE AFTER `group_id`
@@ -0,0 +1,3 @@
+ALTER TABLE `{{.JobQueue}}`
+  ADD COLUMN `group_id` VARCHAR(255) NOT NULL DEFAULT '' AFTER `concurrency_limit`,
+  ADD KEY `group_head` (`group_id`, `job_id`)
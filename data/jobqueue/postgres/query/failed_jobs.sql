@@ -0,0 +1,3 @@
+SELECT failure_id, job_id, category, url, payload, result, fail_count, failed_at, created_at FROM "{{.Failure}}"
+WHERE created_at <= $1 AND (created_at != $2 OR failure_id <= $3)
+ORDER BY created_at DESC, failure_id DESC LIMIT $4
@@ -0,0 +1,17 @@
+WITH g AS (
+  SELECT job_id FROM "{{.JobQueue}}" AS j
+  WHERE status = 'claimed'
+    AND next_try <= FLOOR(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) * 1000)::BIGINT
+    AND (group_id = '' OR NOT EXISTS (
+      SELECT 1 FROM "{{.JobQueue}}" AS h
+      WHERE h.group_id = j.group_id AND h.job_id < j.job_id
+    ))
+  ORDER BY next_try ASC
+  LIMIT $1
+  FOR UPDATE OF j SKIP LOCKED
+)
+UPDATE "{{.JobQueue}}" AS q
+SET status = 'grabbed', grabber_id = pg_backend_pid()
+FROM g
+WHERE q.job_id = g.job_id
+RETURNING q.job_id, q.category, q.url, q.payload, q.next_try, q.status, q.created_at, q.retry_count, q.retry_delay, q.fail_count, q.timeout, q.concurrency_key, q.concurrency_limit, q.group_id, q.dead_letter
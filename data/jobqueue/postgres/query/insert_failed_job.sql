@@ -0,0 +1,2 @@
+INSERT INTO "{{.Failure}}" (job_id, category, url, payload, result, fail_count, failed_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
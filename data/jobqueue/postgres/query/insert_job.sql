@@ -0,0 +1,3 @@
+INSERT INTO "{{.JobQueue}}" (next_try, created_at, retry_count, retry_delay, fail_count, category, url, payload, timeout, concurrency_key, concurrency_limit, group_id, dead_letter)
+VALUES (FLOOR(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) * 1000)::BIGINT + $1, FLOOR(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) * 1000)::BIGINT, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING job_id
@@ -0,0 +1,6 @@
+SELECT job_id, category, url, payload, next_try, status, created_at, retry_count, retry_delay, fail_count, timeout, concurrency_key, concurrency_limit, group_id, dead_letter FROM "{{.JobQueue}}"
+WHERE status = $1
+  AND next_try > $2
+  AND next_try <= $3
+  AND job_id <= $4
+ORDER BY next_try DESC, job_id DESC LIMIT $5
@@ -0,0 +1,6 @@
+DELETE FROM "{{.Failure}}"
+WHERE failure_id IN (
+  SELECT failure_id FROM "{{.Failure}}"
+  WHERE failure_id <= $1
+  ORDER BY failure_id ASC LIMIT $2
+)
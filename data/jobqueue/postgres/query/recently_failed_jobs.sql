@@ -0,0 +1,3 @@
+SELECT failure_id, job_id, category, url, payload, result, fail_count, failed_at, created_at FROM "{{.Failure}}"
+WHERE $1::BIGINT = $2::BIGINT AND failure_id <= $3
+ORDER BY failure_id DESC LIMIT $4
@@ -0,0 +1,11 @@
+WITH o AS (
+  SELECT job_id FROM "{{.JobQueue}}"
+  WHERE status = 'grabbed' AND grabber_id != pg_backend_pid()
+  LIMIT 1000
+  FOR UPDATE SKIP LOCKED
+)
+UPDATE "{{.JobQueue}}" AS q
+SET status = 'claimed',
+    grabber_id = NULL
+FROM o
+WHERE q.job_id = o.job_id
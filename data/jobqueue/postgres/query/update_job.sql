@@ -0,0 +1,4 @@
+UPDATE "{{.JobQueue}}"
+SET grabber_id = NULL, status = 'claimed',
+	next_try = FLOOR(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) * 1000)::BIGINT + $1, retry_count = $2, fail_count = $3
+WHERE job_id = $4
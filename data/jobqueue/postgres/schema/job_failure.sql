@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "{{.Failure}}" (
+  failure_id BIGSERIAL NOT NULL,
+  job_id BIGINT NOT NULL,
+  category VARCHAR(255) NOT NULL,
+  url TEXT,
+  payload TEXT,
+  result TEXT,
+  fail_count INTEGER NOT NULL,
+  failed_at BIGINT NOT NULL,
+  created_at BIGINT NOT NULL,
+  PRIMARY KEY (failure_id)
+);
+CREATE INDEX IF NOT EXISTS "{{.Failure}}_creation_order" ON "{{.Failure}}" (created_at);
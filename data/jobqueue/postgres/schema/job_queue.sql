@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS "{{.JobQueue}}" (
+  job_id BIGSERIAL NOT NULL,
+  next_try BIGINT NOT NULL,
+  grabber_id BIGINT,
+  status VARCHAR(8) NOT NULL DEFAULT 'claimed' CHECK (status IN ('claimed', 'grabbed')),
+  created_at BIGINT NOT NULL,
+  retry_count INTEGER NOT NULL DEFAULT 0,
+  retry_delay INTEGER NOT NULL DEFAULT 0,
+  fail_count INTEGER NOT NULL DEFAULT 0,
+
+  category VARCHAR(255) NOT NULL,
+  url TEXT,
+  payload TEXT,
+  timeout INTEGER,
+  concurrency_key VARCHAR(255) NOT NULL DEFAULT '',
+  concurrency_limit INTEGER NOT NULL DEFAULT 0,
+  group_id VARCHAR(255) NOT NULL DEFAULT '',
+  dead_letter BOOLEAN NOT NULL DEFAULT FALSE,
+
+  PRIMARY KEY (job_id)
+);
+CREATE INDEX IF NOT EXISTS "{{.JobQueue}}_grab" ON "{{.JobQueue}}" (status, next_try);
+CREATE INDEX IF NOT EXISTS "{{.JobQueue}}_group_head" ON "{{.JobQueue}}" (group_id, job_id);
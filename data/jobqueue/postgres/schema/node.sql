@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS fireworq_node (
+  connection_id BIGINT NOT NULL,
+  url VARCHAR(255) NOT NULL,
+  PRIMARY KEY (connection_id)
+);
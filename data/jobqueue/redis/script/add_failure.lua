@@ -0,0 +1,19 @@
+-- ARGV: prefix, job_id, category, url, payload, result, fail_count,
+--       failed_at, created_at
+local p = ARGV[1]
+local id = redis.call('INCR', p .. 'failure_id')
+local m = string.format('%020d', id)
+
+redis.call('HSET', p .. 'failure:' .. id,
+  'job_id', ARGV[2],
+  'category', ARGV[3],
+  'url', ARGV[4],
+  'payload', ARGV[5],
+  'result', ARGV[6],
+  'fail_count', ARGV[7],
+  'failed_at', ARGV[8],
+  'created_at', ARGV[9])
+redis.call('ZADD', p .. 'failures', ARGV[9], m)
+redis.call('ZADD', p .. 'recent_failures', id, m)
+
+return id
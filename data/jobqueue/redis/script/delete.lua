@@ -0,0 +1,25 @@
+-- ARGV: prefix, job_id
+local p = ARGV[1]
+local key = p .. 'job:' .. ARGV[2]
+local m = string.format('%020d', tonumber(ARGV[2]))
+
+redis.call('ZREM', p .. 'claimed', m)
+redis.call('ZREM', p .. 'grabbed', m)
+redis.call('ZREM', p .. 'ready', m)
+
+-- The next job of the group gets ready when the head is deleted.
+local g = redis.call('HGET', key, 'group_id')
+if g and g ~= '' then
+  local group = p .. 'group:' .. g
+  local head = redis.call('ZRANGE', group, 0, 0)[1] == m
+  redis.call('ZREM', group, m)
+  if head then
+    local next = redis.call('ZRANGE', group, 0, 0)[1]
+    if next then
+      local nextTry = redis.call('HGET', p .. 'job:' .. string.match(next, '^0*(%d+)$'), 'next_try')
+      redis.call('ZADD', p .. 'ready', nextTry, next)
+    end
+  end
+end
+
+return redis.call('DEL', key)
@@ -0,0 +1,8 @@
+-- ARGV: prefix, failure_id
+local p = ARGV[1]
+local m = string.format('%020d', tonumber(ARGV[2]))
+
+redis.call('ZREM', p .. 'failures', m)
+redis.call('ZREM', p .. 'recent_failures', m)
+
+return redis.call('DEL', p .. 'failure:' .. ARGV[2])
@@ -0,0 +1,22 @@
+-- ARGV: prefix, now, limit
+local p = ARGV[1]
+
+-- The ready index contains only jobs which can be grabbed, i.e. jobs
+-- without a group and the heads of groups.
+local grabbed = {}
+local ms = redis.call('ZRANGEBYSCORE', p .. 'ready', '-inf', ARGV[2],
+  'WITHSCORES', 'LIMIT', 0, tonumber(ARGV[3]))
+for i = 1, #ms, 2 do
+  local m = ms[i]
+  local key = p .. 'job:' .. string.match(m, '^0*(%d+)$')
+
+  redis.call('ZREM', p .. 'ready', m)
+  redis.call('ZREM', p .. 'claimed', m)
+  redis.call('ZADD', p .. 'grabbed', ms[i + 1], m)
+  redis.call('HSET', key, 'status', 'grabbed')
+  local j = redis.call('HGETALL', key)
+  table.insert(j, 1, m)
+  grabbed[#grabbed + 1] = j
+end
+
+return grabbed
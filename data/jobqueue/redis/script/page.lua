@@ -0,0 +1,46 @@
+-- KEYS: an index sorted by score and then by member
+-- ARGV: prefix of entry keys, cursor score, cursor member, score
+--       bound (exclusive), limit, order ('asc' or 'desc')
+local prefix = ARGV[1]
+local score = tonumber(ARGV[2])
+local member = ARGV[3]
+local limit = tonumber(ARGV[5])
+local asc = ARGV[6] == 'asc'
+
+local entries = {}
+local offset = 0
+while #entries < limit do
+  local ms
+  if asc then
+    ms = redis.call('ZRANGEBYSCORE', KEYS[1], ARGV[2], '(' .. ARGV[4],
+      'WITHSCORES', 'LIMIT', offset, limit)
+  else
+    ms = redis.call('ZREVRANGEBYSCORE', KEYS[1], ARGV[2], '(' .. ARGV[4],
+      'WITHSCORES', 'LIMIT', offset, limit)
+  end
+  if #ms == 0 then
+    break
+  end
+  offset = offset + #ms / 2
+
+  for i = 1, #ms, 2 do
+    local m = ms[i]
+    -- Skip entries before the cursor among those with the same score.
+    local before = false
+    if tonumber(ms[i + 1]) == score then
+      if asc then
+        before = m < member
+      else
+        before = m > member
+      end
+    end
+
+    if not before and #entries < limit then
+      local e = redis.call('HGETALL', prefix .. string.match(m, '^0*(%d+)$'))
+      table.insert(e, 1, m)
+      entries[#entries + 1] = e
+    end
+  end
+end
+
+return entries
@@ -0,0 +1,29 @@
+-- ARGV: prefix, failed_before, max_rows, limit
+local p = ARGV[1]
+local before = tonumber(ARGV[2])
+local maxRows = tonumber(ARGV[3])
+
+-- Failed jobs older than the newest max_rows ones are pruned first.
+local excess = 0
+if maxRows > 0 then
+  excess = redis.call('ZCARD', p .. 'recent_failures') - maxRows
+end
+
+local n = 0
+local members = redis.call('ZRANGE', p .. 'recent_failures', 0, tonumber(ARGV[4]) - 1)
+for _, m in ipairs(members) do
+  local key = p .. 'failure:' .. (string.gsub(m, '^0+(%d)', '%1'))
+  if n >= excess then
+    local failedAt = tonumber(redis.call('HGET', key, 'failed_at'))
+    if before <= 0 or failedAt == nil or failedAt >= before then
+      break
+    end
+  end
+
+  redis.call('ZREM', p .. 'failures', m)
+  redis.call('ZREM', p .. 'recent_failures', m)
+  redis.call('DEL', key)
+  n = n + 1
+end
+
+return n
@@ -0,0 +1,38 @@
+-- ARGV: prefix, next_try, created_at, retry_count, retry_delay,
+--       fail_count, category, url, payload, timeout, concurrency_key,
+--       concurrency_limit, group_id, dead_letter
+local p = ARGV[1]
+local id = redis.call('INCR', p .. 'id')
+local m = string.format('%020d', id)
+
+redis.call('HSET', p .. 'job:' .. id,
+  'next_try', ARGV[2],
+  'status', 'claimed',
+  'created_at', ARGV[3],
+  'retry_count', ARGV[4],
+  'retry_delay', ARGV[5],
+  'fail_count', ARGV[6],
+  'category', ARGV[7],
+  'url', ARGV[8],
+  'payload', ARGV[9],
+  'timeout', ARGV[10],
+  'concurrency_key', ARGV[11],
+  'concurrency_limit', ARGV[12],
+  'group_id', ARGV[13],
+  'dead_letter', ARGV[14])
+redis.call('ZADD', p .. 'claimed', ARGV[2], m)
+
+-- Only a job without a group or the head of a group is ready to be
+-- grabbed.  The next job of a group gets ready when the head is
+-- deleted.
+local ready = true
+if ARGV[13] ~= '' then
+  local group = p .. 'group:' .. ARGV[13]
+  redis.call('ZADD', group, id, m)
+  ready = redis.call('ZCARD', group) == 1
+end
+if ready then
+  redis.call('ZADD', p .. 'ready', ARGV[2], m)
+end
+
+return id
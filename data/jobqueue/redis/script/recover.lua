@@ -0,0 +1,21 @@
+-- ARGV: prefix
+local p = ARGV[1]
+
+local recovered = 0
+while true do
+  local ms = redis.call('ZRANGE', p .. 'grabbed', 0, 999, 'WITHSCORES')
+  if #ms == 0 then
+    break
+  end
+
+  for i = 1, #ms, 2 do
+    local m = ms[i]
+    redis.call('ZREM', p .. 'grabbed', m)
+    redis.call('ZADD', p .. 'claimed', ms[i + 1], m)
+    redis.call('ZADD', p .. 'ready', ms[i + 1], m)
+    redis.call('HSET', p .. 'job:' .. string.match(m, '^0*(%d+)$'), 'status', 'claimed')
+    recovered = recovered + 1
+  end
+end
+
+return recovered
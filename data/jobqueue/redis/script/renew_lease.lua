@@ -0,0 +1,6 @@
+-- KEYS: the lease
+-- ARGV: holder, duration in milliseconds
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
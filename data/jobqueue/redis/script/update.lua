@@ -0,0 +1,19 @@
+-- ARGV: prefix, job_id, next_try, retry_count, fail_count
+local p = ARGV[1]
+local key = p .. 'job:' .. ARGV[2]
+if redis.call('EXISTS', key) == 0 then
+  return 0
+end
+local m = string.format('%020d', tonumber(ARGV[2]))
+
+redis.call('HSET', key,
+  'status', 'claimed',
+  'next_try', ARGV[3],
+  'retry_count', ARGV[4],
+  'fail_count', ARGV[5])
+redis.call('ZREM', p .. 'grabbed', m)
+redis.call('ZADD', p .. 'claimed', ARGV[3], m)
+-- A grabbed job remains the head of its group.
+redis.call('ZADD', p .. 'ready', ARGV[3], m)
+
+return 1
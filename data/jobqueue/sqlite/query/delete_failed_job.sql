@@ -0,0 +1,2 @@
+DELETE FROM "{{.Failure}}"
+WHERE failure_id = ?
@@ -0,0 +1,2 @@
+DELETE FROM "{{.JobQueue}}"
+WHERE job_id = ?
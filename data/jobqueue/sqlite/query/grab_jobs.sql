@@ -0,0 +1,14 @@
+UPDATE "{{.JobQueue}}"
+SET status = 'grabbed'
+WHERE job_id IN (
+  SELECT job_id FROM "{{.JobQueue}}" AS j
+  WHERE status = 'claimed'
+    AND next_try <= ?
+    AND (group_id = '' OR NOT EXISTS (
+      SELECT 1 FROM "{{.JobQueue}}" AS h
+      WHERE h.group_id = j.group_id AND h.job_id < j.job_id
+    ))
+  ORDER BY next_try ASC
+  LIMIT ?
+)
+RETURNING job_id, category, url, payload, next_try, status, created_at, retry_count, retry_delay, fail_count, timeout, concurrency_key, concurrency_limit, group_id, dead_letter
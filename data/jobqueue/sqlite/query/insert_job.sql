@@ -0,0 +1,2 @@
+INSERT INTO "{{.JobQueue}}" (next_try, created_at, retry_count, retry_delay, fail_count, category, url, payload, timeout, concurrency_key, concurrency_limit, group_id, dead_letter)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
@@ -0,0 +1,3 @@
+SELECT MIN(next_try) FROM "{{.JobQueue}}"
+WHERE status = 'claimed'
+  AND next_try > ?
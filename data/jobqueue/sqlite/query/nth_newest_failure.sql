@@ -0,0 +1,2 @@
+SELECT failure_id FROM "{{.Failure}}"
+ORDER BY failure_id DESC LIMIT 1 OFFSET ?
@@ -0,0 +1,2 @@
+SELECT failure_id, failed_at FROM "{{.Failure}}"
+ORDER BY failure_id ASC LIMIT ?
@@ -0,0 +1,6 @@
+DELETE FROM "{{.Failure}}"
+WHERE failure_id IN (
+  SELECT failure_id FROM "{{.Failure}}"
+  WHERE failure_id <= ?
+  ORDER BY failure_id ASC LIMIT ?
+)
@@ -0,0 +1,3 @@
+UPDATE "{{.JobQueue}}"
+SET status = 'claimed'
+WHERE status = 'grabbed'
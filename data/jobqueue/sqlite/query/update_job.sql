@@ -0,0 +1,4 @@
+UPDATE "{{.JobQueue}}"
+SET status = 'claimed',
+	next_try = ?, retry_count = ?, fail_count = ?
+WHERE job_id = ?
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "{{.Failure}}" (
+  failure_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  job_id INTEGER NOT NULL,
+  category TEXT NOT NULL,
+  url TEXT,
+  payload TEXT,
+  result BLOB,
+  fail_count INTEGER NOT NULL,
+  failed_at INTEGER NOT NULL,
+  created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS "{{.Failure}}_creation_order" ON "{{.Failure}}" (created_at);
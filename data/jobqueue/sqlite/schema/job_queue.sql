@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS "{{.JobQueue}}" (
+  job_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+  next_try INTEGER NOT NULL,
+  status TEXT NOT NULL DEFAULT 'claimed' CHECK (status IN ('claimed', 'grabbed')),
+  created_at INTEGER NOT NULL,
+  retry_count INTEGER NOT NULL DEFAULT 0,
+  retry_delay INTEGER NOT NULL DEFAULT 0,
+  fail_count INTEGER NOT NULL DEFAULT 0,
+
+  category TEXT NOT NULL,
+  url TEXT,
+  payload TEXT,
+  timeout INTEGER,
+  concurrency_key TEXT NOT NULL DEFAULT '',
+  concurrency_limit INTEGER NOT NULL DEFAULT 0,
+  group_id TEXT NOT NULL DEFAULT '',
+  dead_letter INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS "{{.JobQueue}}_grab" ON "{{.JobQueue}}" (status, next_try);
+CREATE INDEX IF NOT EXISTS "{{.JobQueue}}_group_head" ON "{{.JobQueue}}" (group_id, job_id);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `host_limit` (
+  `host` VARCHAR(255) NOT NULL,
+  `max_concurrency` INT UNSIGNED NOT NULL,
+  `max_requests_per_second` FLOAT UNSIGNED NOT NULL,
+  `max_burst_size` INT UNSIGNED NOT NULL,
+  PRIMARY KEY (`host`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
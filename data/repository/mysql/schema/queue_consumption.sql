@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS `queue_consumption` (
+  `name` VARCHAR(255) NOT NULL,
+  `consumption_mode` VARCHAR(32) NOT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `queue_dead_letter` (
+  `name` VARCHAR(255) NOT NULL,
+  `dead_letter_queue` VARCHAR(255) NOT NULL,
+  `dead_letter_category` VARCHAR(255) NOT NULL,
+  `dead_letter_url` BLOB,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
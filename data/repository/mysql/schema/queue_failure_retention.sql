@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `queue_failure_retention` (
+  `name` VARCHAR(255) NOT NULL,
+  `max_age` INT UNSIGNED NOT NULL,
+  `max_rows` INT UNSIGNED NOT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS `queue_secret` (
+  `name` VARCHAR(255) NOT NULL,
+  `secrets` BLOB NOT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `queue_shard` (
+  `name` VARCHAR(255) NOT NULL,
+  `shard_policy` VARCHAR(32) NOT NULL,
+  `shards` BLOB,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
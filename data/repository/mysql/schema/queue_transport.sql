@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `queue_transport` (
+  `name` VARCHAR(255) NOT NULL,
+  `keep_alive` TINYINT(1),
+  `max_conns_per_host` INT UNSIGNED NOT NULL,
+  `max_idle_conns_per_host` INT UNSIGNED NOT NULL DEFAULT 0,
+  `idle_conn_timeout` INT UNSIGNED NOT NULL,
+  `proxy_url` BLOB,
+  `default_timeout` INT UNSIGNED NOT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
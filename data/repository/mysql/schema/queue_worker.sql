@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `queue_worker` (
+  `name` VARCHAR(255) NOT NULL,
+  `worker_type` VARCHAR(255) NOT NULL,
+  `command` BLOB,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=binary;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS host_limit (
+  host VARCHAR(255) NOT NULL,
+  max_concurrency INTEGER NOT NULL,
+  max_requests_per_second DOUBLE PRECISION NOT NULL,
+  max_burst_size INTEGER NOT NULL,
+  PRIMARY KEY (host)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue (
+  name VARCHAR(255) NOT NULL,
+  polling_interval INTEGER NOT NULL,
+  max_workers INTEGER NOT NULL,
+  PRIMARY KEY (name)
+);
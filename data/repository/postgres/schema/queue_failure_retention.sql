@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue_failure_retention (
+  name VARCHAR(255) NOT NULL,
+  max_age BIGINT NOT NULL,
+  max_rows BIGINT NOT NULL,
+  PRIMARY KEY (name)
+);
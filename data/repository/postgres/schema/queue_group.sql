@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS queue_group (
+  name VARCHAR(255) NOT NULL,
+  group_failure_policy VARCHAR(32) NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue_response (
+  name VARCHAR(255) NOT NULL,
+  response_mode VARCHAR(32) NOT NULL,
+  status_mapping TEXT,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS queue_secret (
+  name VARCHAR(255) NOT NULL,
+  secrets TEXT NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue_throttle (
+  name VARCHAR(255) NOT NULL,
+  max_dispatches_per_second DOUBLE PRECISION NOT NULL,
+  max_burst_size INTEGER NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS queue_tls (
+  name VARCHAR(255) NOT NULL,
+  tls_cert_file TEXT,
+  tls_key_file TEXT,
+  tls_ca_file TEXT,
+  tls_min_version VARCHAR(8) NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS routing (
+  job_category VARCHAR(255) NOT NULL,
+  queue_name VARCHAR(255) NOT NULL,
+  PRIMARY KEY (job_category),
+  UNIQUE (job_category, queue_name)
+);
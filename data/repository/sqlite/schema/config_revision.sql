@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS config_revision (
+  name TEXT NOT NULL,
+  revision INTEGER NOT NULL,
+  PRIMARY KEY (name)
+);
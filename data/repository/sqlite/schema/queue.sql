@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue (
+  name TEXT NOT NULL,
+  polling_interval INTEGER NOT NULL,
+  max_workers INTEGER NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS queue_consumption (
+  name TEXT NOT NULL,
+  consumption_mode TEXT NOT NULL,
+  PRIMARY KEY (name)
+);
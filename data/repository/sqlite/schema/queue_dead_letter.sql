@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS queue_dead_letter (
+  name TEXT NOT NULL,
+  dead_letter_queue TEXT NOT NULL,
+  dead_letter_category TEXT NOT NULL,
+  dead_letter_url BLOB,
+  PRIMARY KEY (name)
+);
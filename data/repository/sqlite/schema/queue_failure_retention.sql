@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue_failure_retention (
+  name TEXT NOT NULL,
+  max_age INTEGER NOT NULL,
+  max_rows INTEGER NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS queue_group (
+  name TEXT NOT NULL,
+  group_failure_policy TEXT NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue_response (
+  name TEXT NOT NULL,
+  response_mode TEXT NOT NULL,
+  status_mapping BLOB,
+  PRIMARY KEY (name)
+);
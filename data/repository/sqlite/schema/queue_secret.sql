@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS queue_secret (
+  name TEXT NOT NULL,
+  secrets BLOB NOT NULL,
+  PRIMARY KEY (name)
+);
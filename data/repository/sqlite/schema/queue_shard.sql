@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue_shard (
+  name TEXT NOT NULL,
+  shard_policy TEXT NOT NULL,
+  shards BLOB,
+  PRIMARY KEY (name)
+);
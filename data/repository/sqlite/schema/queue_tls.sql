@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS queue_tls (
+  name TEXT NOT NULL,
+  tls_cert_file BLOB,
+  tls_key_file BLOB,
+  tls_ca_file BLOB,
+  tls_min_version TEXT NOT NULL,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS queue_transport (
+  name TEXT NOT NULL,
+  keep_alive INTEGER,
+  max_conns_per_host INTEGER NOT NULL,
+  max_idle_conns_per_host INTEGER NOT NULL DEFAULT 0,
+  idle_conn_timeout INTEGER NOT NULL,
+  proxy_url BLOB,
+  default_timeout INTEGER NOT NULL,
+  PRIMARY KEY (name)
+);
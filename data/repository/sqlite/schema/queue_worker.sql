@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS queue_worker (
+  name TEXT NOT NULL,
+  worker_type TEXT NOT NULL,
+  command BLOB,
+  PRIMARY KEY (name)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS routing (
+  job_category TEXT NOT NULL,
+  queue_name TEXT NOT NULL,
+  PRIMARY KEY (job_category),
+  UNIQUE (job_category, queue_name)
+);
@@ -0,0 +1,177 @@
+package dispatcher
+
+import (
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/dispatcher/worker"
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+// States of a circuit breaker.
+const (
+	CircuitBreakerClosed   = "closed"
+	CircuitBreakerOpen     = "open"
+	CircuitBreakerHalfOpen = "half-open"
+)
+
+// While a probe job is running on a half-open breaker, other jobs
+// are postponed for this duration at most.
+const maxProbeWait = time.Second
+
+var breakers = newCircuitBreakers(0, 0)
+
+// A worker listening on a Unix domain socket is identified by the
+// path of the socket with this prefix in place of its host.
+const unixHostPrefix = "unix:"
+
+func initCircuitBreakers() {
+	threshold, err := strconv.ParseUint(config.Get("dispatch_circuit_breaker_threshold"), 10, 32)
+	if err != nil {
+		threshold, _ = strconv.ParseUint(config.GetDefault("dispatch_circuit_breaker_threshold"), 10, 32)
+	}
+
+	timeout, err := strconv.ParseUint(config.Get("dispatch_circuit_breaker_timeout"), 10, 32)
+	if err != nil {
+		timeout, _ = strconv.ParseUint(config.GetDefault("dispatch_circuit_breaker_timeout"), 10, 32)
+	}
+
+	breakers = newCircuitBreakers(uint(threshold), time.Duration(timeout)*time.Second)
+}
+
+// CircuitBreakerStats contains statistics of a circuit breaker for a
+// worker host.
+type CircuitBreakerStats struct {
+	State               string `json:"state"`
+	ConsecutiveFailures uint   `json:"consecutive_failures"`
+}
+
+type circuitBreakers struct {
+	sync.Mutex
+	threshold uint
+	timeout   time.Duration
+	m         map[string]*circuitBreaker
+}
+
+type circuitBreaker struct {
+	state     string
+	failures  uint
+	changedAt time.Time
+}
+
+func newCircuitBreakers(threshold uint, timeout time.Duration) *circuitBreakers {
+	return &circuitBreakers{
+		threshold: threshold,
+		timeout:   timeout,
+		m:         make(map[string]*circuitBreaker),
+	}
+}
+
+func hostOf(rawurl string) string {
+	if socket, _, ok := worker.ParseUnixURL(rawurl); ok {
+		return unixHostPrefix + socket
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func (cbs *circuitBreakers) enabled() bool {
+	return cbs.threshold > 0
+}
+
+func (cbs *circuitBreakers) get(host string) *circuitBreaker {
+	cb, ok := cbs.m[host]
+	if !ok {
+		cb = &circuitBreaker{state: CircuitBreakerClosed}
+		cbs.m[host] = cb
+	}
+	return cb
+}
+
+// allow reports whether a job to host can be dispatched now.  If not,
+// it also returns how long the job should be postponed.
+func (cbs *circuitBreakers) allow(host string) (bool, time.Duration) {
+	if !cbs.enabled() {
+		return true, 0
+	}
+
+	cbs.Lock()
+	defer cbs.Unlock()
+
+	cb := cbs.get(host)
+	now := time.Now()
+	elapsed := now.Sub(cb.changedAt)
+
+	switch cb.state {
+	case CircuitBreakerOpen:
+		if elapsed < cbs.timeout {
+			return false, cbs.timeout - elapsed
+		}
+		// Let this job be a probe.
+		cb.state = CircuitBreakerHalfOpen
+		cb.changedAt = now
+		return true, 0
+	case CircuitBreakerHalfOpen:
+		if elapsed < cbs.timeout {
+			wait := cbs.timeout - elapsed
+			if wait > maxProbeWait {
+				wait = maxProbeWait
+			}
+			return false, wait
+		}
+		// The probe seems to be lost; try another one.
+		cb.changedAt = now
+		return true, 0
+	}
+
+	return true, 0
+}
+
+// report records the result of a job dispatched to host.
+func (cbs *circuitBreakers) report(host string, rslt *jobqueue.Result) {
+	if !cbs.enabled() {
+		return
+	}
+
+	cbs.Lock()
+	defer cbs.Unlock()
+
+	cb := cbs.get(host)
+
+	if rslt.Status != jobqueue.ResultStatusInternalFailure && rslt.Code < 500 {
+		cb.failures = 0
+		if cb.state != CircuitBreakerClosed {
+			cb.state = CircuitBreakerClosed
+			cb.changedAt = time.Now()
+		}
+		return
+	}
+
+	cb.failures++
+	if cb.state == CircuitBreakerHalfOpen || cb.failures >= cbs.threshold {
+		cb.state = CircuitBreakerOpen
+		cb.changedAt = time.Now()
+	}
+}
+
+func (cbs *circuitBreakers) stats(hosts []string) map[string]CircuitBreakerStats {
+	if !cbs.enabled() || len(hosts) == 0 {
+		return nil
+	}
+
+	cbs.Lock()
+	defer cbs.Unlock()
+
+	stats := make(map[string]CircuitBreakerStats, len(hosts))
+	for _, host := range hosts {
+		cb := cbs.get(host)
+		stats[host] = CircuitBreakerStats{cb.state, cb.failures}
+	}
+	return stats
+}
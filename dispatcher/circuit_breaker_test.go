@@ -0,0 +1,149 @@
+package dispatcher
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/dispatcher/worker"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	cbs := newCircuitBreakers(2, 100*time.Millisecond)
+
+	failure := &jobqueue.Result{Status: jobqueue.ResultStatusInternalFailure}
+	serverError := &jobqueue.Result{Status: jobqueue.ResultStatusFailure, Code: 503}
+	clientError := &jobqueue.Result{Status: jobqueue.ResultStatusFailure, Code: 404}
+	success := &jobqueue.Result{Status: jobqueue.ResultStatusSuccess, Code: 200}
+
+	if ok, _ := cbs.allow("example.com"); !ok {
+		t.Error("A breaker should be closed at first")
+	}
+
+	cbs.report("example.com", failure)
+	cbs.report("example.com", clientError)
+	cbs.report("example.com", failure)
+	if ok, _ := cbs.allow("example.com"); !ok {
+		t.Error("A breaker should be closed unless failures are consecutive")
+	}
+
+	cbs.report("example.com", serverError)
+	if ok, delay := cbs.allow("example.com"); ok || delay <= 0 {
+		t.Error("A breaker should be open after consecutive failures")
+	}
+	if ok, _ := cbs.allow("example.org"); !ok {
+		t.Error("A breaker should be independent of other hosts")
+	}
+
+	if s := cbs.stats([]string{"example.com"})["example.com"]; s.State != CircuitBreakerOpen || s.ConsecutiveFailures != 2 {
+		t.Errorf("Wrong stats: %v", s)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if ok, _ := cbs.allow("example.com"); !ok {
+		t.Error("A probe should be allowed after the timeout")
+	}
+	if ok, _ := cbs.allow("example.com"); ok {
+		t.Error("Only one probe should be allowed")
+	}
+	if s := cbs.stats([]string{"example.com"})["example.com"]; s.State != CircuitBreakerHalfOpen {
+		t.Errorf("Wrong stats: %v", s)
+	}
+
+	cbs.report("example.com", failure)
+	if ok, _ := cbs.allow("example.com"); ok {
+		t.Error("A breaker should be open again after a failed probe")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if ok, _ := cbs.allow("example.com"); !ok {
+		t.Error("A probe should be allowed after the timeout")
+	}
+	cbs.report("example.com", success)
+	if ok, _ := cbs.allow("example.com"); !ok {
+		t.Error("A breaker should be closed after a successful probe")
+	}
+	if s := cbs.stats([]string{"example.com"})["example.com"]; s.State != CircuitBreakerClosed || s.ConsecutiveFailures != 0 {
+		t.Errorf("Wrong stats: %v", s)
+	}
+}
+
+func TestHostOf(t *testing.T) {
+	for rawurl, host := range map[string]string{
+		"http://worker1.example.com/job":      "worker1.example.com",
+		"http://worker2.example.com:8080/job": "worker2.example.com:8080",
+		"unix:///var/run/worker.sock:/job":    "unix:/var/run/worker.sock",
+		"unix:///var/run/worker.sock":         "unix:/var/run/worker.sock",
+		"://worker1.example.com/job":          "",
+	} {
+		if h := hostOf(rawurl); h != host {
+			t.Errorf("Wrong host of %s: %s", rawurl, h)
+		}
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cbs := newCircuitBreakers(0, time.Second)
+
+	for i := 0; i < 10; i++ {
+		cbs.report("example.com", &jobqueue.Result{Status: jobqueue.ResultStatusInternalFailure})
+	}
+	if ok, _ := cbs.allow("example.com"); !ok {
+		t.Error("A disabled breaker should never be open")
+	}
+	if cbs.stats([]string{"example.com"}) != nil {
+		t.Error("A disabled breaker should report nothing")
+	}
+}
+
+func TestCircuitBreakerPostponesJobs(t *testing.T) {
+	orig := breakers
+	breakers = newCircuitBreakers(2, time.Minute)
+	defer func() { breakers = orig }()
+
+	jobs := make([]jobqueue.Job, 0)
+	for i := 0; i < 5; i++ {
+		jobs = append(jobs, &urlJob{job{fmt.Sprintf("%d", i)}, "http://down.example.com/"})
+	}
+	jq := &dummyJobQueue{jobs: jobs}
+
+	cfg := Config{
+		Kicker: &dummyKickerConfig{instance: &dummyKicker{}},
+		Worker: &failingWorker{},
+	}
+	d := cfg.Start(jq, &model.Queue{MaxWorkers: 1})
+	defer func() { <-d.Stop() }()
+
+	d.(*dispatcher).Kick()
+	time.Sleep(200 * time.Millisecond)
+
+	jq.Lock()
+	if len(jq.completed) != 2 {
+		t.Errorf("Jobs should be dispatched until the breaker opens: %d", len(jq.completed))
+	}
+	if len(jq.postponed) != 3 {
+		t.Errorf("Jobs should be postponed while the breaker is open: %d", len(jq.postponed))
+	}
+	jq.Unlock()
+
+	stats := d.Stats()
+	if s, ok := stats.CircuitBreakers["down.example.com"]; !ok || s.State != CircuitBreakerOpen {
+		t.Errorf("Stats should report the breaker state: %v", stats.CircuitBreakers)
+	}
+}
+
+type failingWorker struct{}
+
+func (w *failingWorker) NewWorker() worker.Worker { return w }
+
+func (w *failingWorker) Work(job jobqueue.Job) *jobqueue.Result {
+	return &jobqueue.Result{
+		Status:  jobqueue.ResultStatusFailure,
+		Code:    503,
+		Message: "Service Unavailable",
+	}
+}
@@ -0,0 +1,111 @@
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+// concurrencyKeys tracks jobs running for each concurrency key and
+// holds jobs whose key is at its limit until a running job of the
+// same key finishes.
+//
+// A key holds at most as many jobs as its limit, so that held jobs
+// never outnumber running ones and a backlogged key cannot occupy
+// the capacity for jobs of the other keys.
+type concurrencyKeys struct {
+	sync.Mutex
+	running map[string]uint
+	waiting map[string][]jobqueue.Job
+	held    int
+}
+
+// Results of concurrencyKeys.admit().
+const (
+	keyAdmitted = iota // the job can run now
+	keyHeld            // the job is held until done() returns it
+	keyFull            // the job should be returned to the queue
+)
+
+// A job rejected by a key which already holds enough jobs is
+// postponed for this duration.
+const fullKeyDelay = time.Second
+
+func newConcurrencyKeys() *concurrencyKeys {
+	return &concurrencyKeys{
+		running: make(map[string]uint),
+		waiting: make(map[string][]jobqueue.Job),
+	}
+}
+
+func concurrencyLimit(job jobqueue.Job) uint {
+	limit := job.ConcurrencyLimit()
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
+// admit returns keyAdmitted if job can run now.  Otherwise, job is
+// held and will be returned by done() later, or rejected with keyFull
+// if the key of job already holds as many jobs as its limit.
+func (ck *concurrencyKeys) admit(job jobqueue.Job) int {
+	key := job.ConcurrencyKey()
+	if key == "" {
+		return keyAdmitted
+	}
+
+	ck.Lock()
+	defer ck.Unlock()
+
+	limit := concurrencyLimit(job)
+	if len(ck.waiting[key]) == 0 && ck.running[key] < limit {
+		ck.running[key]++
+		return keyAdmitted
+	}
+	if uint(len(ck.waiting[key])) >= limit {
+		return keyFull
+	}
+
+	ck.waiting[key] = append(ck.waiting[key], job)
+	ck.held++
+	return keyHeld
+}
+
+// done marks job as finished and returns a held job of the same key
+// which can run instead, if any.
+func (ck *concurrencyKeys) done(job jobqueue.Job) jobqueue.Job {
+	key := job.ConcurrencyKey()
+	if key == "" {
+		return nil
+	}
+
+	ck.Lock()
+	defer ck.Unlock()
+
+	if waiting := ck.waiting[key]; len(waiting) > 0 {
+		next := waiting[0]
+		if len(waiting) > 1 {
+			ck.waiting[key] = waiting[1:]
+		} else {
+			delete(ck.waiting, key)
+		}
+		ck.held--
+		return next
+	}
+
+	if ck.running[key] > 1 {
+		ck.running[key]--
+	} else {
+		delete(ck.running, key)
+	}
+	return nil
+}
+
+// size returns the number of held jobs.
+func (ck *concurrencyKeys) size() int {
+	ck.Lock()
+	defer ck.Unlock()
+	return ck.held
+}
@@ -0,0 +1,215 @@
+package dispatcher
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/dispatcher/worker"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+func TestConcurrencyKeys(t *testing.T) {
+	ck := newConcurrencyKeys()
+
+	a1 := &keyedJob{job{"a1"}, "a", 1}
+	a2 := &keyedJob{job{"a2"}, "a", 1}
+	a3 := &keyedJob{job{"a3"}, "a", 1}
+	b1 := &keyedJob{job{"b1"}, "b", 2}
+	b2 := &keyedJob{job{"b2"}, "b", 2}
+
+	b3 := &keyedJob{job{"b3"}, "b", 2}
+
+	if ck.admit(a1) != keyAdmitted {
+		t.Error("The first job of a key should be admitted")
+	}
+	if ck.admit(a2) != keyHeld {
+		t.Error("A job whose key is at its limit should be held")
+	}
+	if ck.admit(a3) != keyFull {
+		t.Error("A key should not hold more jobs than its limit")
+	}
+	if ck.admit(b1) != keyAdmitted || ck.admit(b2) != keyAdmitted {
+		t.Error("Jobs of another key should be admitted")
+	}
+	if ck.admit(b3) != keyHeld {
+		t.Error("A job whose key is at its limit should be held")
+	}
+	if ck.admit(&job{"x"}) != keyAdmitted {
+		t.Error("A job without a key should be admitted")
+	}
+	if ck.size() != 2 {
+		t.Errorf("Wrong number of held jobs: %d", ck.size())
+	}
+
+	if next := ck.done(a1); next != a2 {
+		t.Error("A held job should be returned in order")
+	}
+	if ck.admit(a3) != keyHeld {
+		t.Error("A job should be held after a held job is returned")
+	}
+	if next := ck.done(a2); next != a3 {
+		t.Error("A held job should be returned in order")
+	}
+	if next := ck.done(a3); next != nil {
+		t.Error("No job should be returned")
+	}
+	if next := ck.done(b1); next != b3 {
+		t.Error("A held job should be returned in order")
+	}
+	if ck.size() != 0 {
+		t.Errorf("Wrong number of held jobs: %d", ck.size())
+	}
+
+	if ck.admit(a1) != keyAdmitted {
+		t.Error("A job should be admitted after the others finished")
+	}
+}
+
+func TestConcurrencyKeyAcrossPops(t *testing.T) {
+	jobs := make([]jobqueue.Job, 0)
+	for i := 0; i < 4; i++ {
+		jobs = append(jobs, &keyedJob{job{fmt.Sprintf("a%d", i)}, "a", 1})
+	}
+	for i := 0; i < 4; i++ {
+		jobs = append(jobs, &keyedJob{job{fmt.Sprintf("b%d", i)}, "b", 2})
+	}
+	for i := 0; i < 4; i++ {
+		jobs = append(jobs, &job{fmt.Sprintf("x%d", i)})
+	}
+	jq := &dummyJobQueue{jobs: jobs}
+	w := &keyCountingWorker{running: make(map[string]int), max: make(map[string]int)}
+
+	cfg := Config{
+		MinBufferSize: 3,
+		Kicker:        &dummyKickerConfig{instance: &dummyKicker{}},
+		Worker:        w,
+	}
+	d := cfg.Start(jq, &model.Queue{MaxWorkers: 3})
+	defer func() { <-d.Stop() }()
+
+	for i := 0; i < 20; i++ {
+		d.(*dispatcher).Kick()
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	jq.Lock()
+	if len(jq.completed)+len(jq.postponed) != len(jobs) {
+		t.Errorf("All the jobs should be completed or postponed: %d, %d", len(jq.completed), len(jq.postponed))
+	}
+	jq.Unlock()
+
+	w.Lock()
+	defer w.Unlock()
+	if w.max["a"] != 1 {
+		t.Errorf("Jobs of a key should be serialized: %d", w.max["a"])
+	}
+	if w.max["b"] > 2 {
+		t.Errorf("Jobs of a key should be limited: %d", w.max["b"])
+	}
+}
+
+func TestConcurrencyKeyBacklog(t *testing.T) {
+	const bufferSize = 10
+
+	// A key is backlogged beyond the buffer size ahead of a job of
+	// another key.
+	jobs := make([]jobqueue.Job, 0)
+	for i := 0; i < bufferSize*3; i++ {
+		jobs = append(jobs, &keyedJob{job{fmt.Sprintf("a%d", i)}, "a", 1})
+	}
+	jobs = append(jobs, &keyedJob{job{"b"}, "b", 1})
+	jq := &dummyJobQueue{jobs: jobs}
+	w := &keyBlockingWorker{key: "a", ch: make(chan struct{})}
+
+	cfg := Config{
+		MinBufferSize: bufferSize,
+		Kicker:        &dummyKickerConfig{instance: &dummyKicker{}},
+		Worker:        w,
+	}
+	d := cfg.Start(jq, &model.Queue{MaxWorkers: 2})
+	defer func() {
+		close(w.ch)
+		<-d.Stop()
+	}()
+
+	// The running job of "a" blocks while the others are popped.
+	for i := 0; i < 5; i++ {
+		d.(*dispatcher).Kick()
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	jq.Lock()
+	defer jq.Unlock()
+	found := false
+	for _, r := range jq.completed {
+		if r.Message == "b" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("A job of another key should be dispatched: %v", jq.completed)
+	}
+	if len(jq.postponed) < bufferSize*3-2 {
+		t.Errorf("Jobs beyond the held ones should be returned to the queue: %d", len(jq.postponed))
+	}
+}
+
+type keyedJob struct {
+	job
+	key   string
+	limit uint
+}
+
+func (j *keyedJob) ConcurrencyKey() string { return j.key }
+func (j *keyedJob) ConcurrencyLimit() uint { return j.limit }
+
+// keyBlockingWorker blocks jobs of a key until ch is closed.
+type keyBlockingWorker struct {
+	key string
+	ch  chan struct{}
+}
+
+func (w *keyBlockingWorker) NewWorker() worker.Worker { return w }
+
+func (w *keyBlockingWorker) Work(job jobqueue.Job) *jobqueue.Result {
+	if job.ConcurrencyKey() == w.key {
+		<-w.ch
+	}
+	return &jobqueue.Result{
+		Status:  jobqueue.ResultStatusSuccess,
+		Message: job.Payload(),
+	}
+}
+
+type keyCountingWorker struct {
+	sync.Mutex
+	running map[string]int
+	max     map[string]int
+}
+
+func (w *keyCountingWorker) NewWorker() worker.Worker { return w }
+
+func (w *keyCountingWorker) Work(job jobqueue.Job) *jobqueue.Result {
+	key := job.ConcurrencyKey()
+
+	w.Lock()
+	w.running[key]++
+	if w.running[key] > w.max[key] {
+		w.max[key] = w.running[key]
+	}
+	w.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	w.Lock()
+	w.running[key]--
+	w.Unlock()
+
+	return &jobqueue.Result{
+		Status:  jobqueue.ResultStatusSuccess,
+		Message: job.Payload(),
+	}
+}
@@ -3,7 +3,9 @@ package dispatcher
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/dispatcher/kicker"
 	"github.com/fireworq/fireworq/dispatcher/worker"
 	"github.com/fireworq/fireworq/jobqueue"
@@ -17,11 +19,31 @@ import (
 
 const defaultMinBufferSize = 1000
 
+// Kinds of kickers.
+const (
+	KickerAdaptive = "adaptive"
+	KickerPolling  = "polling"
+)
+
+var defaultKicker = KickerAdaptive
+
 // Init initializes global parameters of dispatchers by configuration values.
 //
 // Configuration keys prefixed by "dispatch_" are considered.
 func Init() {
 	worker.HTTPInit()
+	worker.CommandInit()
+	initCircuitBreakers()
+
+	defaultKicker = config.Get("dispatch_kicker")
+	if defaultKicker != KickerPolling {
+		defaultKicker = KickerAdaptive
+	}
+}
+
+// ReloadTLS reloads TLS certificates used to dispatch jobs.
+func ReloadTLS() error {
+	return worker.ReloadTLS()
 }
 
 // Config contains information to create a dispatcher instance.
@@ -35,8 +57,13 @@ type Config struct {
 // configuration.
 //
 // The instance watches a queue specified by q in a way specified by
-// m.
+// m.  If the worker type of m is model.WorkerTypePull, the instance
+// does not dispatch jobs but implements Puller instead.
 func (cfg Config) Start(q JobQueue, m *model.Queue) Dispatcher {
+	if m.WorkerType == model.WorkerTypePull {
+		return startPull(q, m)
+	}
+
 	logger := log.With().Str("package", "dispatcher").Str("queue", q.Name()).Logger()
 
 	bufferSize := cfg.MinBufferSize
@@ -48,14 +75,53 @@ func (cfg Config) Start(q JobQueue, m *model.Queue) Dispatcher {
 	}
 
 	kc := cfg.Kicker
-	if kc == nil {
+	if kc == nil && defaultKicker == KickerPolling {
 		kc = &kicker.PollingKicker{Interval: m.PollingInterval}
 	}
+	if kc == nil {
+		kc = &kicker.AdaptiveKicker{Interval: m.PollingInterval}
+	}
 	k := kc.NewKicker()
 
 	wc := cfg.Worker
+	if wc == nil && m.WorkerType == model.WorkerTypeCommand {
+		wc = &worker.CommandWorker{
+			QueueName:      q.Name(),
+			Command:        m.Command,
+			DefaultTimeout: m.DefaultTimeout,
+			Logger:         &logger,
+		}
+	}
+	if wc == nil && m.WorkerType == model.WorkerTypeProcess {
+		wc = &worker.ProcessWorker{
+			QueueName:      q.Name(),
+			Command:        m.Command,
+			Size:           m.MaxWorkers,
+			DefaultTimeout: m.DefaultTimeout,
+			Logger:         &logger,
+		}
+	}
 	if wc == nil {
-		wc = &worker.HTTPWorker{Logger: &logger}
+		wc = &worker.HTTPWorker{
+			QueueName:     q.Name(),
+			ResponseMode:  m.ResponseMode,
+			StatusMapping: m.StatusMapping,
+			Transport: worker.TransportConfig{
+				KeepAlive:           m.KeepAlive,
+				MaxConnsPerHost:     m.MaxConnsPerHost,
+				MaxIdleConnsPerHost: m.MaxIdleConnsPerHost,
+				IdleConnTimeout:     m.IdleConnTimeout,
+				ProxyURL:            m.ProxyURL,
+			},
+			TLS: worker.TLSConfig{
+				CertFile:   m.TLSCertFile,
+				KeyFile:    m.TLSKeyFile,
+				CAFile:     m.TLSCAFile,
+				MinVersion: m.TLSMinVersion,
+			},
+			DefaultTimeout: m.DefaultTimeout,
+			Logger:         &logger,
+		}
 	}
 	w := wc.NewWorker()
 
@@ -75,6 +141,8 @@ func (cfg Config) Start(q JobQueue, m *model.Queue) Dispatcher {
 		jobBuffer: make(chan jobqueue.Job, bufferSize),
 		sem:       make(chan struct{}, m.MaxWorkers),
 		limiter:   limiter,
+		keys:      newConcurrencyKeys(),
+		hosts:     make(map[string]struct{}),
 		logger:    logger,
 	}
 	go d.loop()
@@ -110,6 +178,9 @@ type dispatcher struct {
 	jobBuffer chan jobqueue.Job
 	sem       chan struct{}
 	limiter   *rate.Limiter
+	keys      *concurrencyKeys
+	hostsMu   sync.Mutex
+	hosts     map[string]struct{} // worker hosts seen by circuit breakers
 	logger    zerolog.Logger
 }
 
@@ -124,11 +195,16 @@ func (d *dispatcher) Ping() {
 func (d *dispatcher) Stats() *Stats {
 	runningWorkers := int64(len(d.sem))
 	totalWorkers := int64(cap(d.sem))
-	return &Stats{
-		OutstandingJobs: int64(len(d.jobBuffer)),
+	stats := &Stats{
+		OutstandingJobs: int64(len(d.jobBuffer) + d.keys.size()),
 		TotalWorkers:    totalWorkers,
 		IdleWorkers:     totalWorkers - runningWorkers,
+		CircuitBreakers: breakers.stats(d.seenHosts()),
+	}
+	if w, ok := d.worker.(worker.HasTransportStats); ok {
+		stats.Transport = w.TransportStats()
 	}
+	return stats
 }
 
 func (d *dispatcher) PollingInterval() uint {
@@ -171,17 +247,31 @@ Loop:
 		case <-d.stop:
 			cancel()
 			wg.Wait()
+			if w, ok := d.worker.(worker.Closer); ok {
+				if err := w.Close(); err != nil {
+					d.logger.Error().Msgf("Failed to close the worker: %s", err)
+				}
+			}
 			break Loop
 		case job := <-d.jobBuffer:
+			switch d.keys.admit(job) {
+			case keyHeld:
+				continue
+			case keyFull:
+				d.jobqueue.Postpone(job, uint64(fullKeyDelay/time.Millisecond))
+				continue
+			}
 			wg.Add(1)
 			d.sem <- struct{}{}
 			go func(job jobqueue.Job) {
 				defer wg.Done()
 				defer func() { <-d.sem }()
-				err := d.limiter.Wait(ctx)
-				if err == nil {
-					rslt := d.worker.Work(job)
-					d.jobqueue.Complete(job, rslt)
+				// A job held for its concurrency key takes over the
+				// worker of a finished job of the same key.
+				for ; job != nil; job = d.keys.done(job) {
+					if !d.dispatch(ctx, job) {
+						return
+					}
 				}
 			}(job)
 		}
@@ -189,7 +279,51 @@ Loop:
 	d.stopped <- struct{}{}
 }
 
+func (d *dispatcher) dispatch(ctx context.Context, job jobqueue.Job) bool {
+	cbs := breakers
+	host := hostOf(job.URL())
+	if cbs.enabled() {
+		d.seeHost(host)
+		if ok, delay := cbs.allow(host); !ok {
+			d.jobqueue.Postpone(job, uint64(delay/time.Millisecond))
+			return true
+		}
+	}
+
+	if err := d.limiter.Wait(ctx); err != nil {
+		return false
+	}
+	release, err := hostLimits.acquire(ctx, job.URL())
+	if err != nil {
+		return false
+	}
+	rslt := d.worker.Work(job)
+	release()
+	cbs.report(host, rslt)
+	d.jobqueue.Complete(job, rslt)
+	return true
+}
+
+func (d *dispatcher) seeHost(host string) {
+	d.hostsMu.Lock()
+	defer d.hostsMu.Unlock()
+	d.hosts[host] = struct{}{}
+}
+
+func (d *dispatcher) seenHosts() []string {
+	d.hostsMu.Lock()
+	defer d.hostsMu.Unlock()
+
+	hosts := make([]string, 0, len(d.hosts))
+	for host := range d.hosts {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
 func (d *dispatcher) popJobs() {
+	// Jobs held for their concurrency keys do not occupy the buffer
+	// since they are bounded by running jobs.
 	if len(d.jobBuffer) < cap(d.jobBuffer) {
 		reqn := cap(d.jobBuffer) - len(d.jobBuffer)
 		jobs, err := d.jobqueue.Pop(uint(reqn))
@@ -209,14 +343,35 @@ func (d *dispatcher) popJobs() {
 		for _, job := range jobs {
 			d.jobBuffer <- job
 		}
+		if fb, ok := d.kicker.(kicker.Feedback); ok {
+			// No need to wait for a deferred job if there may be
+			// more jobs already due.
+			var next time.Time
+			if len(jobs) < reqn {
+				next = d.nextTry()
+			}
+			fb.Kicked(uint(len(jobs)), uint(reqn), next)
+		}
 	}
 }
 
+// nextTry returns the time when the earliest deferred job becomes due
+// or the zero time if the queue does not know it.
+func (d *dispatcher) nextTry() time.Time {
+	if q, ok := d.jobqueue.(jobqueue.HasNextTry); ok {
+		if next, ok := q.NextTry(); ok {
+			return time.Unix(0, int64(next)*int64(time.Millisecond))
+		}
+	}
+	return time.Time{}
+}
+
 // JobQueue is an interface of a queue which can be watched by
 // dispatchers.
 type JobQueue interface {
 	Pop(limit uint) ([]jobqueue.Job, error)
 	Complete(job jobqueue.Job, res *jobqueue.Result)
+	Postpone(job jobqueue.Job, delay uint64)
 	Name() string
 }
 
@@ -225,4 +380,7 @@ type Stats struct {
 	OutstandingJobs int64 `json:"outstanding_jobs"`
 	TotalWorkers    int64 `json:"total_workers"`
 	IdleWorkers     int64 `json:"idle_workers"`
+
+	CircuitBreakers map[string]CircuitBreakerStats `json:"circuit_breakers,omitempty"`
+	Transport       *worker.TransportStats         `json:"transport,omitempty"`
 }
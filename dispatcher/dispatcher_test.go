@@ -357,6 +357,25 @@ func TestPing(t *testing.T) {
 	}
 }
 
+func TestPingAdaptive(t *testing.T) {
+	jq := &dummyJobQueue{}
+	cfg := Config{Worker: &dummyWorker{}}
+	d := cfg.Start(jq, &model.Queue{PollingInterval: 10000, MaxWorkers: 1})
+	defer func() { <-d.Stop() }()
+
+	jq.Lock()
+	jq.jobs = append(jq.jobs, &job{payload: "1"})
+	jq.Unlock()
+	d.Ping()
+
+	time.Sleep(200 * time.Millisecond)
+	jq.Lock()
+	defer jq.Unlock()
+	if len(jq.completed) != 1 {
+		t.Error("Queue must be popped on ping by default")
+	}
+}
+
 func TestStats(t *testing.T) {
 	worker := &dummyBlockingWorker{make(chan struct{}, 1)}
 
@@ -411,6 +430,7 @@ type dummyJobQueue struct {
 	sync.Mutex
 	jobs      []jobqueue.Job
 	completed []jobqueue.Result
+	postponed []jobqueue.Job
 }
 
 func (jq *dummyJobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
@@ -435,6 +455,13 @@ func (jq *dummyJobQueue) Complete(job jobqueue.Job, res *jobqueue.Result) {
 	jq.completed = append(jq.completed, *res)
 }
 
+func (jq *dummyJobQueue) Postpone(job jobqueue.Job, delay uint64) {
+	jq.Lock()
+	defer jq.Unlock()
+
+	jq.postponed = append(jq.postponed, job)
+}
+
 func (jq *dummyJobQueue) Name() string { return "dummy" }
 
 type errorJobQueue struct {
@@ -452,6 +479,8 @@ func (jq *errorJobQueue) Complete(job jobqueue.Job, res *jobqueue.Result) {
 	atomic.AddInt64(&jq.completed, 1)
 }
 
+func (jq *errorJobQueue) Postpone(job jobqueue.Job, delay uint64) {}
+
 type brokenJobQueue struct {
 	dummyJobQueue
 }
@@ -527,4 +556,7 @@ func (j *job) RetryCount() uint               { return 0 }
 func (j *job) RetryDelay() uint               { return 0 }
 func (j *job) FailCount() uint                { return 0 }
 func (j *job) Timeout() uint                  { return 0 }
+func (j *job) ConcurrencyKey() string         { return "" }
+func (j *job) ConcurrencyLimit() uint         { return 0 }
+func (j *job) GroupID() string                { return "" }
 func (j *job) ToLoggable() logger.LoggableJob { return nil }
@@ -0,0 +1,110 @@
+package dispatcher
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/fireworq/fireworq/model"
+
+	"golang.org/x/time/rate"
+)
+
+var hostLimits = &hostLimiter{m: make(map[string]*hostLimit)}
+
+// SetHostLimits replaces the limits of dispatching jobs to each
+// destination host.
+//
+// The limits are shared by all the dispatchers in the process.  Jobs
+// already being dispatched are accounted against the limits which
+// were in effect when they started.
+func SetHostLimits(limits []model.HostLimit) {
+	hostLimits.set(limits)
+}
+
+type hostLimiter struct {
+	sync.RWMutex
+	m map[string]*hostLimit
+}
+
+type hostLimit struct {
+	sem     chan struct{}
+	limiter *rate.Limiter
+}
+
+func newHostLimit(l *model.HostLimit) *hostLimit {
+	hl := &hostLimit{}
+	if l.MaxConcurrency > 0 {
+		hl.sem = make(chan struct{}, l.MaxConcurrency)
+	}
+	if l.MaxRequestsPerSecond > 0.0 {
+		burst := int(l.MaxBurstSize)
+		if burst <= 0 {
+			burst = 1
+		}
+		hl.limiter = rate.NewLimiter(rate.Limit(l.MaxRequestsPerSecond), burst)
+	}
+	return hl
+}
+
+func (hl *hostLimiter) set(limits []model.HostLimit) {
+	m := make(map[string]*hostLimit, len(limits))
+	for i := range limits {
+		m[limits[i].Host] = newHostLimit(&limits[i])
+	}
+
+	hl.Lock()
+	defer hl.Unlock()
+	hl.m = m
+}
+
+// find returns the limit of the host of rawurl.  A limit for a host
+// with a port number is preferred to that for the bare host name.
+func (hl *hostLimiter) find(rawurl string) *hostLimit {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil
+	}
+
+	hl.RLock()
+	defer hl.RUnlock()
+
+	if l, ok := hl.m[u.Host]; ok {
+		return l
+	}
+	if l, ok := hl.m[u.Hostname()]; ok {
+		return l
+	}
+	return nil
+}
+
+// acquire blocks until a request to the host of rawurl is allowed.
+// The returned function must be called after the request finishes.
+func (hl *hostLimiter) acquire(ctx context.Context, rawurl string) (func(), error) {
+	l := hl.find(rawurl)
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+
+	if l.limiter != nil {
+		if err := l.limiter.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
@@ -0,0 +1,176 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/dispatcher/worker"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+func TestHostLimiterFind(t *testing.T) {
+	hl := &hostLimiter{}
+	hl.set([]model.HostLimit{
+		{Host: "example.com", MaxConcurrency: 1},
+		{Host: "example.com:8080", MaxConcurrency: 2},
+	})
+
+	if l := hl.find("http://example.com/foo"); l == nil || cap(l.sem) != 1 {
+		t.Error("A limit should be found by the host name")
+	}
+	if l := hl.find("http://example.com:8080/foo"); l == nil || cap(l.sem) != 2 {
+		t.Error("A limit with a port number should be preferred")
+	}
+	if l := hl.find("http://example.com:8081/foo"); l == nil || cap(l.sem) != 1 {
+		t.Error("A limit should fall back to the one of the host name")
+	}
+	if l := hl.find("http://example.org/"); l != nil {
+		t.Error("No limit should be found for an unknown host")
+	}
+	if l := hl.find(":"); l != nil {
+		t.Error("No limit should be found for an invalid URL")
+	}
+}
+
+func TestHostLimiterAcquire(t *testing.T) {
+	hl := &hostLimiter{}
+	hl.set([]model.HostLimit{
+		{Host: "example.com", MaxConcurrency: 1},
+		{Host: "example.org", MaxRequestsPerSecond: 0.000001},
+	})
+
+	release, err := hl.acquire(context.Background(), "http://example.com/")
+	if err != nil {
+		t.Error(err)
+	}
+
+	func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, err := hl.acquire(ctx, "http://example.com/"); err == nil {
+			t.Error("Concurrent requests should be limited")
+		}
+	}()
+
+	release()
+
+	func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		release, err := hl.acquire(ctx, "http://example.com/")
+		if err != nil {
+			t.Error("A request should be allowed after a release")
+		}
+		release()
+	}()
+
+	func() {
+		release, err := hl.acquire(context.Background(), "http://example.org/")
+		if err != nil {
+			t.Error("A burst request should be allowed")
+		}
+		release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, err := hl.acquire(ctx, "http://example.org/"); err == nil {
+			t.Error("Requests should be throttled")
+		}
+	}()
+
+	func() {
+		release, err := hl.acquire(context.Background(), "http://example.net/")
+		if err != nil {
+			t.Error("Requests to an unlimited host should be allowed")
+		}
+		release()
+	}()
+}
+
+func TestHostLimitAcrossDispatchers(t *testing.T) {
+	SetHostLimits([]model.HostLimit{{Host: "worker.example.com", MaxConcurrency: 2}})
+	defer SetHostLimits(nil)
+
+	w := &concurrencyCountingWorker{}
+
+	ds := make([]Dispatcher, 0, 2)
+	jqs := make([]*dummyJobQueue, 0, 2)
+	for i := 0; i < 2; i++ {
+		jobs := make([]jobqueue.Job, 0)
+		for j := 0; j < 5; j++ {
+			jobs = append(jobs, &urlJob{job{fmt.Sprintf("%d", j)}, "http://worker.example.com/"})
+		}
+		jq := &dummyJobQueue{jobs: jobs}
+		jqs = append(jqs, jq)
+
+		cfg := Config{
+			Kicker: &dummyKickerConfig{instance: &dummyKicker{}},
+			Worker: w,
+		}
+		d := cfg.Start(jq, &model.Queue{MaxWorkers: 5})
+		ds = append(ds, d)
+		defer func() { <-d.Stop() }()
+	}
+
+	for _, d := range ds {
+		d.(*dispatcher).Kick()
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	for _, jq := range jqs {
+		jq.Lock()
+		if len(jq.completed) != 5 {
+			t.Error("All the jobs should be completed")
+		}
+		jq.Unlock()
+	}
+
+	if w.max() != 2 {
+		t.Errorf("Concurrent requests to a host should be limited: %d", w.max())
+	}
+}
+
+type urlJob struct {
+	job
+	url string
+}
+
+func (j *urlJob) URL() string { return j.url }
+
+type concurrencyCountingWorker struct {
+	sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (w *concurrencyCountingWorker) NewWorker() worker.Worker { return w }
+
+func (w *concurrencyCountingWorker) Work(job jobqueue.Job) *jobqueue.Result {
+	w.Lock()
+	w.running++
+	if w.running > w.maxRunning {
+		w.maxRunning = w.running
+	}
+	w.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	w.Lock()
+	w.running--
+	w.Unlock()
+
+	return &jobqueue.Result{
+		Status:  jobqueue.ResultStatusSuccess,
+		Message: job.Payload(),
+	}
+}
+
+func (w *concurrencyCountingWorker) max() int {
+	w.Lock()
+	defer w.Unlock()
+	return w.maxRunning
+}
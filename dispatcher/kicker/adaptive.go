@@ -0,0 +1,185 @@
+package kicker
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AdaptiveKicker is a builder of a Kicker which kicks a Kickable
+// immediately on Ping and otherwise on an interval adapted to the
+// results of kicks reported through Feedback.
+//
+// The interval starts from Interval.  It is doubled, up to
+// MaxInterval, every time a kick pops no job and halved, down to
+// MinInterval, every time a kick pops as many jobs as requested.  A
+// kick popping fewer jobs than requested restores the interval to
+// Interval.  The Kickable is also kicked when the earliest deferred
+// job becomes due.
+type AdaptiveKicker struct {
+	Interval    uint
+	MinInterval uint // defaults to a fifth of Interval
+	MaxInterval uint // defaults to five times Interval
+}
+
+// NewKicker creates a new adaptive kicker instance.
+func (cfg *AdaptiveKicker) NewKicker() Kicker {
+	interval := time.Duration(cfg.Interval) * time.Millisecond
+
+	min := time.Duration(cfg.MinInterval) * time.Millisecond
+	if min == 0 {
+		min = interval / 5
+	}
+	if min < time.Millisecond {
+		min = time.Millisecond
+	}
+	if interval < min {
+		interval = min
+	}
+
+	max := time.Duration(cfg.MaxInterval) * time.Millisecond
+	if max == 0 {
+		max = interval * 5
+	}
+	if max < interval {
+		max = interval
+	}
+
+	log.Debug().Msgf("Polling interval: %d (adaptive between %s and %s)", cfg.Interval, min, max)
+	return &adaptiveKicker{
+		pollingInterval: cfg.Interval,
+		interval:        interval,
+		min:             min,
+		max:             max,
+		current:         interval,
+		ping:            make(chan struct{}, 1),
+		feedback:        make(chan feedback, 1),
+		stop:            make(chan struct{}, 1),
+		stopped:         make(chan struct{}, 1),
+	}
+}
+
+// Feedback is an interface of a Kicker which adapts its frequency to
+// the results of kicks.
+type Feedback interface {
+	// Kicked reports that a kick popped n jobs out of limit
+	// requested ones.  next is the time when the earliest deferred
+	// job becomes due or the zero time if it is unknown.
+	Kicked(n uint, limit uint, next time.Time)
+}
+
+type feedback struct {
+	n     uint
+	limit uint
+	next  time.Time
+}
+
+type adaptiveKicker struct {
+	pollingInterval uint
+	interval        time.Duration
+	min             time.Duration
+	max             time.Duration
+	current         time.Duration // accessed only in loop()
+	ping            chan struct{}
+	feedback        chan feedback
+	stop            chan struct{}
+	stopped         chan struct{}
+}
+
+func (k *adaptiveKicker) Start(kickable Kickable) {
+	go k.loop(kickable)
+}
+
+func (k *adaptiveKicker) Stop() <-chan struct{} {
+	k.stop <- struct{}{}
+	return k.stopped
+}
+
+func (k *adaptiveKicker) Ping() {
+	select {
+	case k.ping <- struct{}{}:
+	default: // a kick is already pending
+	}
+}
+
+func (k *adaptiveKicker) PollingInterval() uint {
+	return k.pollingInterval
+}
+
+func (k *adaptiveKicker) Kicked(n uint, limit uint, next time.Time) {
+	f := feedback{n, limit, next}
+	for {
+		select {
+		case k.feedback <- f:
+			return
+		default:
+		}
+		// Replace stale feedback which is not consumed yet.
+		select {
+		case <-k.feedback:
+		default:
+		}
+	}
+}
+
+func (k *adaptiveKicker) loop(kickable Kickable) {
+	timer := time.NewTimer(k.current)
+	kickedAt := time.Now()
+	kick := func() {
+		kickable.Kick()
+		kickedAt = time.Now()
+		resetTimer(timer, k.current)
+	}
+Loop:
+	for {
+		select {
+		case <-timer.C:
+			kick()
+		case <-k.ping:
+			kick()
+		case f := <-k.feedback:
+			k.adapt(f)
+			wait := k.current - time.Since(kickedAt)
+			if !f.next.IsZero() {
+				if due := time.Until(f.next); due > 0 && due < wait {
+					wait = due
+				}
+			}
+			resetTimer(timer, wait)
+		case <-k.stop:
+			timer.Stop()
+			break Loop
+		}
+	}
+	k.stopped <- struct{}{}
+}
+
+func (k *adaptiveKicker) adapt(f feedback) {
+	switch {
+	case f.n == 0:
+		k.current *= 2
+		if k.current > k.max {
+			k.current = k.max
+		}
+	case f.n >= f.limit:
+		k.current /= 2
+		if k.current < k.min {
+			k.current = k.min
+		}
+	default:
+		k.current = k.interval
+	}
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	if d < 0 {
+		d = 0
+	}
+	timer.Reset(d)
+}
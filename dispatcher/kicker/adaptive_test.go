@@ -0,0 +1,110 @@
+package kicker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveStartStop(t *testing.T) {
+	cfg := AdaptiveKicker{Interval: uint(100)}
+	k := cfg.NewKicker()
+
+	k.Start(&dummyKickable{})
+
+	select {
+	case <-k.Stop():
+	case <-time.After(3 * time.Second):
+		t.Error("An adaptive kicker should be able to stop")
+	}
+}
+
+func TestAdaptivePollingInterval(t *testing.T) {
+	interval := uint(123)
+	cfg := AdaptiveKicker{Interval: interval}
+	k := cfg.NewKicker()
+
+	if k.PollingInterval() != interval {
+		t.Error("An adaptive kicker should return its base interval")
+	}
+}
+
+func TestAdaptivePing(t *testing.T) {
+	cfg := AdaptiveKicker{Interval: uint(10000)}
+	k := cfg.NewKicker()
+	kickable := &dummyKickable{}
+	k.Start(kickable)
+	defer func() { <-k.Stop() }()
+
+	k.Ping()
+	<-time.After(100 * time.Millisecond)
+	if atomic.LoadInt64(&kickable.kicked) != 1 {
+		t.Error("An adaptive kicker should kick immediately on ping")
+	}
+}
+
+func TestAdaptiveLoop(t *testing.T) {
+	cfg := AdaptiveKicker{Interval: uint(100)}
+	k := cfg.NewKicker()
+	kickable := &dummyKickable{}
+	k.Start(kickable)
+
+	<-time.After(1 * time.Second)
+	if atomic.LoadInt64(&kickable.kicked) < 1 {
+		t.Error("An adaptive kicker should kick")
+	}
+	<-k.Stop()
+}
+
+func TestAdaptiveNextTry(t *testing.T) {
+	cfg := AdaptiveKicker{Interval: uint(10000)}
+	k := cfg.NewKicker()
+	kickable := &dummyKickable{}
+	k.Start(kickable)
+	defer func() { <-k.Stop() }()
+
+	k.(Feedback).Kicked(0, 10, time.Now().Add(200*time.Millisecond))
+
+	<-time.After(100 * time.Millisecond)
+	if atomic.LoadInt64(&kickable.kicked) != 0 {
+		t.Error("An adaptive kicker should not kick before a deferred job becomes due")
+	}
+	<-time.After(200 * time.Millisecond)
+	if atomic.LoadInt64(&kickable.kicked) != 1 {
+		t.Error("An adaptive kicker should kick when a deferred job becomes due")
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	cfg := AdaptiveKicker{Interval: uint(100), MinInterval: uint(20), MaxInterval: uint(300)}
+	k := cfg.NewKicker().(*adaptiveKicker)
+
+	for _, c := range []struct {
+		n        uint
+		limit    uint
+		interval time.Duration
+	}{
+		{0, 10, 200 * time.Millisecond},
+		{0, 10, 300 * time.Millisecond},
+		{0, 10, 300 * time.Millisecond},
+		{5, 10, 100 * time.Millisecond},
+		{10, 10, 50 * time.Millisecond},
+		{10, 10, 25 * time.Millisecond},
+		{10, 10, 20 * time.Millisecond},
+		{0, 10, 40 * time.Millisecond},
+	} {
+		k.adapt(feedback{n: c.n, limit: c.limit})
+		if k.current != c.interval {
+			t.Errorf("Wrong interval after popping %d/%d jobs: %s (expected: %s)", c.n, c.limit, k.current, c.interval)
+		}
+	}
+}
+
+func TestAdaptiveDefaultInterval(t *testing.T) {
+	cfg := AdaptiveKicker{Interval: uint(200)}
+	k := cfg.NewKicker().(*adaptiveKicker)
+
+	if k.min != 40*time.Millisecond || k.max != time.Second {
+		t.Errorf("Wrong default bounds: %s, %s", k.min, k.max)
+	}
+}
@@ -0,0 +1,295 @@
+package dispatcher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultVisibilityTimeout is the duration, in seconds, of a lease
+// whose visibility timeout is not specified.
+const DefaultVisibilityTimeout = 30
+
+// How often expired leases are checked.
+const leaseCheckInterval = time.Second
+
+// Puller is an interface of a dispatcher which leases jobs to workers
+// pulling them instead of pushing jobs to workers.
+type Puller interface {
+	// Reserve leases at most max jobs for visibility seconds.  A
+	// leased job which is neither acknowledged nor negatively
+	// acknowledged in time is returned to the queue.
+	Reserve(max uint, visibility uint) ([]*Lease, error)
+	// Ack completes a leased job successfully.  token must be the
+	// one of the current lease of the job.
+	Ack(id uint64, token string) error
+	// Nack fails a leased job, which will be retried after delay
+	// seconds or its own retry delay if delay is zero.  token must
+	// be the one of the current lease of the job.
+	Nack(id uint64, token string, delay uint, message string) error
+}
+
+// Lease describes a job leased to a worker.
+type Lease struct {
+	Job      jobqueue.Job
+	Deadline time.Time
+	// Token is an unguessable value identifying the lease so that a
+	// worker whose lease has expired cannot acknowledge the job
+	// leased to another worker.
+	Token string
+}
+
+// NoSuchLeaseError is an error returned when a job to be acknowledged
+// is not leased or leased with another token.
+type NoSuchLeaseError struct {
+	ID uint64
+}
+
+func (e *NoSuchLeaseError) Error() string {
+	return fmt.Sprintf("No lease of job %d", e.ID)
+}
+
+type pullDispatcher struct {
+	jobqueue        JobQueue
+	pollingInterval uint
+	maxWorkers      uint
+	mu              sync.Mutex
+	leases          map[uint64]*Lease
+	ready           []jobqueue.Job // released by concurrency keys
+	keys            *concurrencyKeys
+	closed          bool
+	stop            chan struct{}
+	stopped         chan struct{}
+	logger          zerolog.Logger
+}
+
+func startPull(q JobQueue, m *model.Queue) Dispatcher {
+	d := &pullDispatcher{
+		jobqueue:        q,
+		pollingInterval: m.PollingInterval,
+		maxWorkers:      m.MaxWorkers,
+		leases:          make(map[uint64]*Lease),
+		keys:            newConcurrencyKeys(),
+		stop:            make(chan struct{}),
+		stopped:         make(chan struct{}),
+		logger:          log.With().Str("package", "dispatcher").Str("queue", q.Name()).Logger(),
+	}
+	go d.loop()
+	return d
+}
+
+func (d *pullDispatcher) Reserve(max uint, visibility uint) ([]*Lease, error) {
+	if visibility == 0 {
+		visibility = DefaultVisibilityTimeout
+	}
+	deadline := time.Now().Add(time.Duration(visibility) * time.Second)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return nil, &jobqueue.InactiveError{}
+	}
+
+	leases := make([]*Lease, 0)
+	lease := func(job jobqueue.Job) {
+		l := &Lease{Job: job, Deadline: deadline, Token: newLeaseToken()}
+		d.leases[job.ToLoggable().ID()] = l
+		leases = append(leases, l)
+	}
+
+	for len(d.ready) > 0 && uint(len(leases)) < max && uint(len(d.leases)) < d.maxWorkers {
+		lease(d.ready[0])
+		d.ready = d.ready[1:]
+	}
+
+	// Jobs held for their concurrency keys do not occupy the slots
+	// since they are bounded by leased jobs.
+	occupied := uint(len(d.leases) + len(d.ready))
+	if uint(len(leases)) >= max || occupied >= d.maxWorkers {
+		return leases, nil
+	}
+	reqn := max - uint(len(leases))
+	if reqn > d.maxWorkers-occupied {
+		reqn = d.maxWorkers - occupied
+	}
+
+	jobs, err := d.jobqueue.Pop(reqn)
+	if err != nil {
+		if len(leases) > 0 {
+			return leases, nil
+		}
+		return nil, err
+	}
+	for _, job := range jobs {
+		switch d.keys.admit(job) {
+		case keyAdmitted:
+			lease(job)
+		case keyFull:
+			d.jobqueue.Postpone(job, uint64(fullKeyDelay/time.Millisecond))
+		}
+	}
+	return leases, nil
+}
+
+// newLeaseToken returns a random token of a lease.
+func newLeaseToken() string {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+func (d *pullDispatcher) Ack(id uint64, token string) error {
+	job, err := d.release(id, token)
+	if err != nil {
+		return err
+	}
+	d.jobqueue.Complete(job, &jobqueue.Result{
+		Status:  jobqueue.ResultStatusSuccess,
+		Message: "Acknowledged",
+	})
+	return nil
+}
+
+func (d *pullDispatcher) Nack(id uint64, token string, delay uint, message string) error {
+	job, err := d.release(id, token)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		message = "Negatively acknowledged"
+	}
+	d.jobqueue.Complete(job, &jobqueue.Result{
+		Status:     jobqueue.ResultStatusFailure,
+		Message:    message,
+		RetryAfter: delay,
+	})
+	return nil
+}
+
+// release removes the lease of id with token and returns its job.
+func (d *pullDispatcher) release(id uint64, token string) (jobqueue.Job, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l, ok := d.leases[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(l.Token), []byte(token)) != 1 {
+		return nil, &NoSuchLeaseError{ID: id}
+	}
+	delete(d.leases, id)
+	if next := d.keys.done(l.Job); next != nil {
+		d.ready = append(d.ready, next)
+	}
+	return l.Job, nil
+}
+
+func (d *pullDispatcher) loop() {
+	ticker := time.NewTicker(leaseCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.expire(time.Now())
+		case <-d.stop:
+			d.returnAll()
+			d.stopped <- struct{}{}
+			return
+		}
+	}
+}
+
+// expire returns the jobs of expired leases to the queue.
+func (d *pullDispatcher) expire(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, l := range d.leases {
+		if now.Before(l.Deadline) {
+			continue
+		}
+		d.logger.Info().Msgf("The lease of job %d has expired", id)
+		delete(d.leases, id)
+		d.jobqueue.Postpone(l.Job, 0)
+		if next := d.keys.done(l.Job); next != nil {
+			d.ready = append(d.ready, next)
+		}
+	}
+}
+
+// returnAll returns all the leased and held jobs to the queue.
+func (d *pullDispatcher) returnAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, l := range d.leases {
+		delete(d.leases, id)
+		d.jobqueue.Postpone(l.Job, 0)
+		for next := d.keys.done(l.Job); next != nil; next = d.keys.done(next) {
+			d.jobqueue.Postpone(next, 0)
+		}
+	}
+	for _, job := range d.ready {
+		d.jobqueue.Postpone(job, 0)
+		for next := d.keys.done(job); next != nil; next = d.keys.done(next) {
+			d.jobqueue.Postpone(next, 0)
+		}
+	}
+	d.ready = nil
+	d.closed = true
+}
+
+func (d *pullDispatcher) Stats() *Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	totalWorkers := int64(d.maxWorkers)
+	return &Stats{
+		OutstandingJobs: int64(len(d.ready) + d.keys.size()),
+		TotalWorkers:    totalWorkers,
+		IdleWorkers:     totalWorkers - int64(len(d.leases)),
+	}
+}
+
+func (d *pullDispatcher) PollingInterval() uint {
+	return d.pollingInterval
+}
+
+func (d *pullDispatcher) MaxWorkers() uint {
+	return d.maxWorkers
+}
+
+func (d *pullDispatcher) MaxDispatchesPerSecond() float64 {
+	return float64(rate.Inf)
+}
+
+func (d *pullDispatcher) MaxBurstSize() int {
+	return 0
+}
+
+// Ping does nothing since jobs are popped when workers reserve them.
+func (d *pullDispatcher) Ping() {
+}
+
+func (d *pullDispatcher) Stop() <-chan struct{} {
+	stopped := make(chan struct{})
+
+	go func() {
+		d.stop <- struct{}{}
+		<-d.stopped
+		stopped <- struct{}{}
+	}()
+
+	return stopped
+}
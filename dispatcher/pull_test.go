@@ -0,0 +1,171 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/logger"
+	"github.com/fireworq/fireworq/model"
+)
+
+func TestPull(t *testing.T) {
+	q := &dummyJobQueue{}
+	for i := uint64(1); i <= 5; i++ {
+		q.jobs = append(q.jobs, &pulledJob{job{""}, i, ""})
+	}
+
+	d := Start(q, &model.Queue{
+		PollingInterval: 100,
+		MaxWorkers:      3,
+		WorkerType:      model.WorkerTypePull,
+	})
+	p, ok := d.(Puller)
+	if !ok {
+		t.Fatal("A dispatcher of a pull queue should be a Puller")
+	}
+
+	first, err := p.Reserve(2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 || first[0].Job.ToLoggable().ID() != 1 || first[1].Job.ToLoggable().ID() != 2 {
+		t.Fatalf("Wrong leases: %v", first)
+	}
+	if time.Until(first[0].Deadline) > 10*time.Second || time.Until(first[0].Deadline) < 9*time.Second {
+		t.Errorf("Wrong deadline: %v", first[0].Deadline)
+	}
+
+	leases, err := p.Reserve(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 1 || leases[0].Job.ToLoggable().ID() != 3 {
+		t.Errorf("Leases should be limited by MaxWorkers: %v", leases)
+	}
+	if stats := d.Stats(); stats.TotalWorkers != 3 || stats.IdleWorkers != 0 {
+		t.Errorf("Wrong stats: %v", stats)
+	}
+
+	tokens := map[uint64]string{}
+	for _, l := range append(first, leases...) {
+		if len(l.Token) != 32 {
+			t.Errorf("Wrong token: %s", l.Token)
+		}
+		tokens[l.Job.ToLoggable().ID()] = l.Token
+	}
+	if tokens[1] == tokens[2] {
+		t.Error("Each lease should have its own token")
+	}
+
+	if _, ok := p.Ack(1, tokens[2]).(*NoSuchLeaseError); !ok {
+		t.Error("A job should not be acknowledged with a token of another lease")
+	}
+	if err := p.Ack(1, tokens[1]); err != nil {
+		t.Error(err)
+	}
+	if err := p.Nack(2, tokens[2], 60, "oops"); err != nil {
+		t.Error(err)
+	}
+	if err := p.Ack(1, tokens[1]); err == nil {
+		t.Error("A job should not be acknowledged twice")
+	}
+	if _, ok := p.Ack(100, "").(*NoSuchLeaseError); !ok {
+		t.Error("An unknown job should not be acknowledged")
+	}
+
+	q.Lock()
+	if len(q.completed) != 2 ||
+		q.completed[0].Status != jobqueue.ResultStatusSuccess ||
+		q.completed[1].Status != jobqueue.ResultStatusFailure ||
+		q.completed[1].RetryAfter != 60 ||
+		q.completed[1].Message != "oops" {
+		t.Errorf("Wrong results: %v", q.completed)
+	}
+	q.Unlock()
+
+	time.Sleep(2500 * time.Millisecond)
+	q.Lock()
+	if len(q.postponed) != 1 || q.postponed[0].ToLoggable().ID() != 3 {
+		t.Errorf("An expired lease should be returned to the queue: %v", q.postponed)
+	}
+	q.Unlock()
+	if err := p.Ack(3, tokens[3]); err == nil {
+		t.Error("An expired lease should not be acknowledged")
+	}
+
+	// The expired job is reserved again by another worker.
+	q.Lock()
+	q.jobs = append([]jobqueue.Job{q.postponed[0]}, q.jobs...)
+	q.Unlock()
+	leases, err = p.Reserve(5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 3 || leases[0].Job.ToLoggable().ID() != 3 {
+		t.Fatalf("Wrong leases: %v", leases)
+	}
+	if err := p.Ack(3, tokens[3]); err == nil {
+		t.Error("A job should not be acknowledged with the token of an expired lease")
+	}
+	if err := p.Ack(3, leases[0].Token); err != nil {
+		t.Error(err)
+	}
+
+	<-d.Stop()
+	q.Lock()
+	if len(q.postponed) != 3 {
+		t.Errorf("Leased jobs should be returned to the queue on stop: %v", q.postponed)
+	}
+	q.Unlock()
+	if _, err := p.Reserve(1, 0); err == nil {
+		t.Error("A stopped dispatcher should not lease jobs")
+	}
+}
+
+func TestPullConcurrencyKey(t *testing.T) {
+	q := &dummyJobQueue{}
+	q.jobs = append(q.jobs,
+		&pulledJob{job{""}, 1, "a"},
+		&pulledJob{job{""}, 2, "a"},
+		&pulledJob{job{""}, 3, "b"},
+	)
+
+	d := Start(q, &model.Queue{
+		MaxWorkers: 10,
+		WorkerType: model.WorkerTypePull,
+	})
+	defer func() { <-d.Stop() }()
+	p := d.(Puller)
+
+	leases, _ := p.Reserve(10, 0)
+	if len(leases) != 2 || leases[0].Job.ToLoggable().ID() != 1 || leases[1].Job.ToLoggable().ID() != 3 {
+		t.Errorf("A job should be held for its concurrency key: %v", leases)
+	}
+
+	if leases, _ := p.Reserve(10, 0); len(leases) != 0 {
+		t.Errorf("A held job should not be leased: %v", leases)
+	}
+
+	p.Ack(1, leases[0].Token)
+	leases, _ = p.Reserve(10, 0)
+	if len(leases) != 1 || leases[0].Job.ToLoggable().ID() != 2 {
+		t.Errorf("A held job should be leased after the running job of the same key: %v", leases)
+	}
+}
+
+type pulledJob struct {
+	job
+	id  uint64
+	key string
+}
+
+func (j *pulledJob) ConcurrencyKey() string         { return j.key }
+func (j *pulledJob) ToLoggable() logger.LoggableJob { return &loggablePulledJob{id: j.id} }
+
+type loggablePulledJob struct {
+	logger.LoggableJob
+	id uint64
+}
+
+func (j *loggablePulledJob) ID() uint64 { return j.id }
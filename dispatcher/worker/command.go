@@ -0,0 +1,193 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+
+	"github.com/rs/zerolog"
+)
+
+var commandEnabled bool
+
+// CommandInit initializes global parameters of command workers by
+// configuration values.
+func CommandInit() {
+	b, err := strconv.ParseBool(config.Get("dispatch_command_enabled"))
+	if err != nil {
+		b, _ = strconv.ParseBool(config.GetDefault("dispatch_command_enabled"))
+	}
+	commandEnabled = b
+}
+
+// CommandEnabled tells whether command workers are allowed.
+func CommandEnabled() bool {
+	return commandEnabled
+}
+
+// ValidateCommand checks if a command can be configured for a queue.
+func ValidateCommand(command []string) error {
+	if !commandEnabled {
+		return errors.New("Command workers are disabled")
+	}
+	if len(command) == 0 || command[0] == "" {
+		return errors.New("Command should not be empty")
+	}
+	return nil
+}
+
+// CommandWorker is a worker which handles a job by running Command on
+// the local host.
+//
+// The payload of the job is given to the standard input of the
+// process and the metadata of the job are given by environment
+// variables prefixed by FIREWORQ_.  If the standard output of the
+// process is a JSON result, it is used as the result of the job.
+// Otherwise, the job succeeds if the exit code is zero and fails
+// otherwise.  The process and its children are killed when the job
+// times out.
+type CommandWorker struct {
+	QueueName      string
+	Command        []string
+	DefaultTimeout uint
+	Logger         *zerolog.Logger
+}
+
+// NewWorker creates a new command worker instance which inherits the
+// configurations of the current one.
+func (worker *CommandWorker) NewWorker() Worker {
+	w := *worker
+
+	if w.Logger == nil {
+		logger := zerolog.Nop()
+		w.Logger = &logger
+	}
+
+	return &w
+}
+
+// Work runs Command for the job and returns the result.
+func (worker *CommandWorker) Work(job jobqueue.Job) *jobqueue.Result {
+	if !commandEnabled {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: "Command workers are disabled",
+		}
+	}
+	if len(worker.Command) == 0 {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: "No command is configured",
+		}
+	}
+
+	timeout := job.Timeout()
+	if timeout == 0 {
+		timeout = worker.DefaultTimeout
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(worker.Command[0], worker.Command[1:]...)
+	cmd.Stdin = strings.NewReader(job.Payload())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), worker.env(job)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: fmt.Sprintf("Cannot run command: %v", err),
+		}
+	}
+
+	worker.Logger.Debug().
+		Str("action", "dispatch").
+		Str("worker", "CommandWorker").
+		Str("command", worker.Command[0]).
+		Str("payload", job.Payload()).
+		Msg("Dispatched via command")
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(time.Duration(timeout) * time.Second)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case err = <-done:
+	case <-expired:
+		// Kill the whole process group so that no child keeps the
+		// output open.
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: fmt.Sprintf("Command timed out after %d seconds", timeout),
+		}
+	}
+
+	code := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return &jobqueue.Result{
+				Status:  jobqueue.ResultStatusInternalFailure,
+				Message: fmt.Sprintf("Command failed: %v", err),
+			}
+		}
+		code = exitErr.ExitCode()
+	}
+
+	var rslt jobqueue.Result
+	if err := json.Unmarshal(stdout.Bytes(), &rslt); err == nil && rslt.IsValid() {
+		rslt.Code = code
+		return &rslt
+	}
+
+	if code == 0 {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusSuccess,
+			Code:    code,
+			Message: stdout.String(),
+		}
+	}
+
+	message := stderr.String()
+	if message == "" {
+		message = stdout.String()
+	}
+	return &jobqueue.Result{
+		Status:  jobqueue.ResultStatusFailure,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (worker *CommandWorker) env(job jobqueue.Job) []string {
+	loggable := job.ToLoggable()
+	return []string{
+		"FIREWORQ_QUEUE_NAME=" + worker.QueueName,
+		"FIREWORQ_JOB_ID=" + strconv.FormatUint(loggable.ID(), 10),
+		"FIREWORQ_JOB_CATEGORY=" + loggable.Category(),
+		"FIREWORQ_JOB_URL=" + job.URL(),
+		"FIREWORQ_JOB_TIMEOUT=" + strconv.FormatUint(uint64(job.Timeout()), 10),
+		"FIREWORQ_JOB_RETRY_COUNT=" + strconv.FormatUint(uint64(job.RetryCount()), 10),
+		"FIREWORQ_JOB_FAIL_COUNT=" + strconv.FormatUint(uint64(job.FailCount()), 10),
+	}
+}
@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/logger"
+)
+
+func TestValidateCommand(t *testing.T) {
+	CommandInit()
+	if err := ValidateCommand([]string{"true"}); err == nil {
+		t.Error("A command should be rejected unless command workers are enabled")
+	}
+
+	config.Locally("dispatch_command_enabled", "true", func() {
+		CommandInit()
+
+		if err := ValidateCommand([]string{"true"}); err != nil {
+			t.Error(err)
+		}
+		if err := ValidateCommand(nil); err == nil {
+			t.Error("An empty command should be rejected")
+		}
+		if err := ValidateCommand([]string{""}); err == nil {
+			t.Error("An empty command should be rejected")
+		}
+	})
+	CommandInit()
+}
+
+func TestCommandWork(t *testing.T) {
+	config.Locally("dispatch_command_enabled", "true", func() {
+		CommandInit()
+
+		sh := func(script string) Worker {
+			return (&CommandWorker{
+				QueueName: "command_queue",
+				Command:   []string{"sh", "-c", script},
+			}).NewWorker()
+		}
+
+		func() {
+			w := sh(`cat; echo " $FIREWORQ_QUEUE_NAME $FIREWORQ_JOB_ID $FIREWORQ_JOB_CATEGORY $FIREWORQ_JOB_URL"`)
+			rslt := w.Work(&commandJob{id: 3, category: "cat", url: "file:///", payload: `{"foo":1}`})
+			if rslt.Status != jobqueue.ResultStatusSuccess {
+				t.Errorf("Wrong status: %v", rslt)
+			}
+			if rslt.Message != `{"foo":1} command_queue 3 cat file:///`+"\n" {
+				t.Errorf("The payload and the metadata should be passed: %q", rslt.Message)
+			}
+		}()
+
+		func() {
+			w := sh(`echo oops >&2; exit 3`)
+			rslt := w.Work(&commandJob{})
+			if rslt.Status != jobqueue.ResultStatusFailure || rslt.Code != 3 {
+				t.Errorf("A non-zero exit code should be a failure: %v", rslt)
+			}
+			if rslt.Message != "oops\n" {
+				t.Errorf("The standard error should be the message: %q", rslt.Message)
+			}
+		}()
+
+		func() {
+			w := sh(`echo '{"status":"permanent-failure","message":"bad","retry_after":5}'; exit 1`)
+			rslt := w.Work(&commandJob{})
+			if rslt.Status != jobqueue.ResultStatusPermanentFailure || rslt.Message != "bad" || rslt.RetryAfter != 5 || rslt.Code != 1 {
+				t.Errorf("A JSON result should be respected: %v", rslt)
+			}
+		}()
+
+		func() {
+			w := sh(`echo '{"status":"unknown"}'`)
+			rslt := w.Work(&commandJob{})
+			if rslt.Status != jobqueue.ResultStatusSuccess {
+				t.Errorf("An invalid JSON result should be ignored: %v", rslt)
+			}
+		}()
+
+		func() {
+			w := (&CommandWorker{Command: []string{"/non/existent/command"}}).NewWorker()
+			rslt := w.Work(&commandJob{})
+			if rslt.Status != jobqueue.ResultStatusInternalFailure {
+				t.Errorf("A command which cannot run should be an internal failure: %v", rslt)
+			}
+		}()
+
+		func() {
+			w := sh(`sleep 10 & sleep 10`)
+			start := time.Now()
+			rslt := w.Work(&commandJob{timeout: 1})
+			if rslt.Status != jobqueue.ResultStatusInternalFailure || !strings.Contains(rslt.Message, "timed out") {
+				t.Errorf("A command should time out: %v", rslt)
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Error("The command and its children should be killed on timeout")
+			}
+		}()
+
+		func() {
+			w := (&CommandWorker{Command: []string{"sleep", "10"}, DefaultTimeout: 1}).NewWorker()
+			rslt := w.Work(&commandJob{})
+			if rslt.Status != jobqueue.ResultStatusInternalFailure {
+				t.Errorf("A job without its own timeout should time out by default: %v", rslt)
+			}
+		}()
+	})
+
+	CommandInit()
+	w := (&CommandWorker{Command: []string{"true"}}).NewWorker()
+	if rslt := w.Work(&commandJob{}); rslt.Status != jobqueue.ResultStatusInternalFailure {
+		t.Errorf("A command should not run unless command workers are enabled: %v", rslt)
+	}
+}
+
+type commandJob struct {
+	job
+	id       uint64
+	category string
+	url      string
+	payload  string
+	timeout  uint
+}
+
+func (j *commandJob) URL() string     { return j.url }
+func (j *commandJob) Payload() string { return j.payload }
+func (j *commandJob) Timeout() uint   { return j.timeout }
+func (j *commandJob) ToLoggable() logger.LoggableJob {
+	return &loggableCommandJob{id: j.id, category: j.category}
+}
+
+type loggableCommandJob struct {
+	logger.LoggableJob
+	id       uint64
+	category string
+}
+
+func (j *loggableCommandJob) ID() uint64       { return j.id }
+func (j *loggableCommandJob) Category() string { return j.category }
@@ -11,6 +11,8 @@ import (
 
 	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/signature"
 
 	"github.com/rs/zerolog"
 )
@@ -22,35 +24,65 @@ var defaultUserAgent string
 //
 // Configuration keys prefixed by "dispatch_" are considered.
 func HTTPInit() {
-	transport := http.DefaultTransport.(*http.Transport)
-	transport.MaxIdleConns = 0
-
 	b, err := strconv.ParseBool(config.Get("dispatch_keep_alive"))
 	if err != nil {
 		b, _ = strconv.ParseBool(config.GetDefault("dispatch_keep_alive"))
 	}
-	transport.DisableKeepAlives = !b
 
-	v, err := strconv.ParseInt(config.Get("dispatch_max_conns_per_host"), 10, 32)
+	v, err := strconv.ParseUint(config.Get("dispatch_max_conns_per_host"), 10, 32)
 	if err != nil {
-		v, _ = strconv.ParseInt(config.GetDefault("dispatch_max_conns_per_host"), 10, 32)
+		v, _ = strconv.ParseUint(config.GetDefault("dispatch_max_conns_per_host"), 10, 32)
 	}
-	transport.MaxIdleConnsPerHost = int(v)
+	maxIdleConnsPerHost := uint(v)
 
-	v, err = strconv.ParseInt(config.Get("dispatch_idle_conn_timeout"), 10, 32)
+	v, err = strconv.ParseUint(config.Get("dispatch_idle_conn_timeout"), 10, 32)
 	if err != nil {
-		v, _ = strconv.ParseInt(config.GetDefault("dispatch_idle_conn_timeout"), 10, 32)
+		v, _ = strconv.ParseUint(config.GetDefault("dispatch_idle_conn_timeout"), 10, 32)
+	}
+	idleConnTimeout := uint(v)
+
+	defaultTransport = TransportConfig{
+		KeepAlive:           &b,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
 	}
-	transport.IdleConnTimeout = time.Duration(v) * time.Second
 
 	defaultUserAgent = config.Get("dispatch_user_agent")
+
+	defaultTLS = TLSConfig{
+		CertFile:   config.Get("dispatch_tls_cert_file"),
+		KeyFile:    config.Get("dispatch_tls_key_file"),
+		CAFile:     config.Get("dispatch_tls_ca_file"),
+		MinVersion: config.Get("dispatch_tls_min_version"),
+	}
 }
 
 // HTTPWorker is a worker which handles a job as an HTTP POST request
 // to the URL specified by the job.
+//
+// The result is read from the JSON body of the response unless
+// ResponseMode is model.ResponseModeStatus, in which case it is
+// determined by the status code of the response through
+// StatusMapping or DefaultStatusMapping.
+//
+// If the queue of QueueName has secrets, requests are signed with
+// them as described in package signature.
+//
+// Each instance created by NewWorker() has its own connection pool
+// configured by Transport and TLS, whose fields left empty are taken
+// from the global configuration.  DefaultTimeout, in seconds, is
+// applied to a job without its own timeout.
 type HTTPWorker struct {
-	UserAgent string
-	Logger    *zerolog.Logger
+	QueueName      string
+	UserAgent      string
+	ResponseMode   string
+	StatusMapping  StatusMapping
+	Transport      TransportConfig
+	TLS            TLSConfig
+	DefaultTimeout uint
+	Logger         *zerolog.Logger
+
+	transport *transport
 }
 
 // NewWorker creates a new HTTP worker instance which inherits the
@@ -67,17 +99,56 @@ func (worker *HTTPWorker) NewWorker() Worker {
 		w.Logger = &logger
 	}
 
+	w.transport = newTransport(
+		w.Transport.merge(defaultTransport),
+		w.TLS.merge(defaultTLS),
+	)
+
 	return &w
 }
 
-// Work makes a POST request to job.URL and returns the result.
+// TransportStats returns the connection statistics of the worker.
+func (worker *HTTPWorker) TransportStats() *TransportStats {
+	if worker.transport == nil {
+		return &TransportStats{}
+	}
+	return worker.transport.stats()
+}
+
+// Work makes a POST request to job.URL and returns the result.  If
+// job.URL is of the form unix:///path/to.sock:/http/path, the request
+// is sent over the Unix domain socket.
 func (worker *HTTPWorker) Work(job jobqueue.Job) *jobqueue.Result {
+	timeout := job.Timeout()
+	if timeout == 0 {
+		timeout = worker.DefaultTimeout
+	}
 	client := &http.Client{
-		Timeout: time.Duration(job.Timeout()) * time.Second,
+		Timeout: time.Duration(timeout) * time.Second,
+	}
+
+	reqURL := job.URL()
+	if socket, u, ok := ParseUnixURL(reqURL); ok {
+		t := worker.transport
+		if t == nil {
+			t = newTransport(defaultTransport, TLSConfig{})
+		}
+		client.Transport = t.unix(socket)
+		reqURL = u
+	} else if worker.transport != nil {
+		transport, err := worker.transport.get()
+		if err != nil {
+			return &jobqueue.Result{
+				Status:  jobqueue.ResultStatusInternalFailure,
+				Message: fmt.Sprintf("Cannot load TLS configuration: %v", err),
+			}
+		}
+		client.Transport = transport
 	}
+
 	req, err := http.NewRequest(
 		"POST",
-		job.URL(),
+		reqURL,
 		strings.NewReader(job.Payload()),
 	)
 	if err != nil {
@@ -94,6 +165,15 @@ func (worker *HTTPWorker) Work(job jobqueue.Job) *jobqueue.Result {
 	}
 	req.Header.Add("User-Agent", userAgent)
 
+	if secrets := queueSecrets.get(worker.QueueName); len(secrets) > 0 {
+		jobID := job.ToLoggable().ID()
+		signature.Sign(req.Header, secrets, time.Now(), jobID, []byte(job.Payload()))
+	}
+
+	if worker.transport != nil {
+		req = worker.transport.trace(req)
+	}
+
 	resp, err := client.Do(req)
 
 	worker.Logger.Debug().
@@ -111,13 +191,32 @@ func (worker *HTTPWorker) Work(job jobqueue.Job) *jobqueue.Result {
 	}
 	defer resp.Body.Close()
 
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return &jobqueue.Result{
-			Status:  jobqueue.ResultStatusFailure,
+			Status:     jobqueue.ResultStatusFailure,
+			Code:       resp.StatusCode,
+			Message:    fmt.Sprintf("Cannot read body: %v", err),
+			RetryAfter: retryAfter,
+		}
+	}
+
+	if worker.ResponseMode == model.ResponseModeStatus {
+		mapping := worker.StatusMapping
+		if len(mapping) == 0 {
+			mapping = DefaultStatusMapping
+		}
+		rslt := &jobqueue.Result{
+			Status:  mapping.Status(resp.StatusCode),
 			Code:    resp.StatusCode,
-			Message: fmt.Sprintf("Cannot read body: %v", err),
+			Message: string(body),
 		}
+		if !rslt.IsFinished() {
+			rslt.RetryAfter = retryAfter
+		}
+		return rslt
 	}
 
 	var rslt jobqueue.Result
@@ -131,17 +230,43 @@ func (worker *HTTPWorker) Work(job jobqueue.Job) *jobqueue.Result {
 				err,
 				string(body),
 			),
+			RetryAfter: retryAfter,
 		}
 	}
 
 	if !rslt.IsValid() {
 		return &jobqueue.Result{
-			Status:  jobqueue.ResultStatusFailure,
-			Code:    resp.StatusCode,
-			Message: fmt.Sprintf("Invalid result status: %s\nOriginal response body:\n%s", rslt.Status, string(body)),
+			Status:     jobqueue.ResultStatusFailure,
+			Code:       resp.StatusCode,
+			Message:    fmt.Sprintf("Invalid result status: %s\nOriginal response body:\n%s", rslt.Status, string(body)),
+			RetryAfter: retryAfter,
 		}
 	}
 
 	rslt.Code = resp.StatusCode
+	if rslt.RetryAfter == 0 && !rslt.IsFinished() {
+		rslt.RetryAfter = retryAfter
+	}
 	return &rslt
 }
+
+// parseRetryAfter returns the delay, in seconds, specified by a
+// Retry-After header value, which is either a number of seconds or an
+// HTTP date.
+func parseRetryAfter(v string) uint {
+	if v == "" {
+		return 0
+	}
+
+	if secs, err := strconv.ParseUint(v, 10, 32); err == nil {
+		return uint(secs)
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return uint((d + time.Second - 1) / time.Second)
+		}
+	}
+
+	return 0
+}
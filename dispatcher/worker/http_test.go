@@ -11,6 +11,8 @@ import (
 	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/jobqueue"
 	"github.com/fireworq/fireworq/jobqueue/logger"
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/signature"
 )
 
 func TestMain(m *testing.M) {
@@ -22,8 +24,7 @@ func TestHTTPInit(t *testing.T) {
 	config.Locally("dispatch_max_conns_per_host", "foo", func() {
 		HTTPInit()
 
-		transport := http.DefaultTransport.(*http.Transport)
-		if transport.MaxIdleConnsPerHost != 10 {
+		if defaultTransport.MaxIdleConnsPerHost != 10 {
 			t.Error("Not set a default value to MaxIdleConnsPerHost")
 		}
 	})
@@ -31,8 +32,7 @@ func TestHTTPInit(t *testing.T) {
 	config.Locally("dispatch_max_conns_per_host", "1000", func() {
 		HTTPInit()
 
-		transport := http.DefaultTransport.(*http.Transport)
-		if transport.MaxIdleConnsPerHost != 1000 {
+		if defaultTransport.MaxIdleConnsPerHost != 1000 {
 			t.Error("Not set to MaxIdleConnsPerHost")
 		}
 	})
@@ -40,8 +40,7 @@ func TestHTTPInit(t *testing.T) {
 	config.Locally("dispatch_idle_conn_timeout", "foo", func() {
 		HTTPInit()
 
-		transport := http.DefaultTransport.(*http.Transport)
-		if transport.IdleConnTimeout != 0 {
+		if defaultTransport.IdleConnTimeout != 0 {
 			t.Error("Not set a default value to IdleConnTimeout")
 		}
 	})
@@ -49,10 +48,21 @@ func TestHTTPInit(t *testing.T) {
 	config.Locally("dispatch_idle_conn_timeout", "10", func() {
 		HTTPInit()
 
-		transport := http.DefaultTransport.(*http.Transport)
-		if transport.IdleConnTimeout != 10*time.Second {
+		if defaultTransport.IdleConnTimeout != 10 {
 			t.Error("Not set to IdleConnTimeout")
 		}
+
+		w := (&HTTPWorker{}).NewWorker().(*HTTPWorker)
+		tr, err := w.transport.get()
+		if err != nil {
+			t.Error(err)
+		}
+		if tr.IdleConnTimeout != 10*time.Second || tr.MaxIdleConnsPerHost != 10 {
+			t.Error("A worker should inherit the global configuration")
+		}
+		if tr == http.DefaultTransport {
+			t.Error("A worker should have its own transport")
+		}
 	})
 }
 
@@ -275,6 +285,143 @@ func TestWork(t *testing.T) {
 	}()
 }
 
+func TestWorkRetryAfter(t *testing.T) {
+	var retryAfter, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", retryAfter)
+		w.WriteHeader(503)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	w := (&HTTPWorker{}).NewWorker()
+
+	func() {
+		retryAfter, body = "120", "Service Unavailable"
+		rslt := w.Work(&job{url: server.URL})
+		if rslt.Status != jobqueue.ResultStatusFailure || rslt.RetryAfter != 120 {
+			t.Errorf("Retry-After in seconds should be honored: %v", rslt)
+		}
+	}()
+
+	func() {
+		retryAfter, body = time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), "Service Unavailable"
+		rslt := w.Work(&job{url: server.URL})
+		if rslt.RetryAfter < 3590 || rslt.RetryAfter > 3600 {
+			t.Errorf("Retry-After in an HTTP date should be honored: %v", rslt)
+		}
+	}()
+
+	func() {
+		retryAfter, body = "120", `{"status":"failure","retry_after":600,"keep_retry_count":true}`
+		rslt := w.Work(&job{url: server.URL})
+		if rslt.RetryAfter != 600 || !rslt.KeepRetryCount {
+			t.Errorf("A retry delay in the result should take precedence: %v", rslt)
+		}
+	}()
+
+	func() {
+		retryAfter, body = "120", `{"status":"permanent-failure"}`
+		rslt := w.Work(&job{url: server.URL})
+		if rslt.RetryAfter != 0 {
+			t.Errorf("Retry-After should be ignored for a finished job: %v", rslt)
+		}
+	}()
+
+	func() {
+		retryAfter, body = "soon", "Service Unavailable"
+		rslt := w.Work(&job{url: server.URL})
+		if rslt.RetryAfter != 0 {
+			t.Errorf("A malformed Retry-After should be ignored: %v", rslt)
+		}
+	}()
+}
+
+func TestWorkResponseModeStatus(t *testing.T) {
+	var code int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if code == 503 {
+			w.Header().Set("Retry-After", "60")
+		}
+		w.WriteHeader(code)
+		w.Write([]byte("plain text"))
+	}))
+	defer server.Close()
+
+	func() {
+		w := (&HTTPWorker{ResponseMode: model.ResponseModeStatus}).NewWorker()
+
+		expected := map[int]string{
+			200: jobqueue.ResultStatusSuccess,
+			201: jobqueue.ResultStatusSuccess,
+			404: jobqueue.ResultStatusPermanentFailure,
+			429: jobqueue.ResultStatusFailure,
+			500: jobqueue.ResultStatusFailure,
+		}
+		for c, status := range expected {
+			code = c
+			rslt := w.Work(&job{url: server.URL})
+			if rslt.Status != status || rslt.Code != c || rslt.Message != "plain text" {
+				t.Errorf("Wrong result for %d: %v", c, rslt)
+			}
+		}
+
+		code = 503
+		rslt := w.Work(&job{url: server.URL})
+		if rslt.Status != jobqueue.ResultStatusFailure || rslt.RetryAfter != 60 {
+			t.Errorf("Retry-After should be honored: %v", rslt)
+		}
+	}()
+
+	func() {
+		w := (&HTTPWorker{
+			ResponseMode:  model.ResponseModeStatus,
+			StatusMapping: StatusMapping{"404": jobqueue.ResultStatusSuccess},
+		}).NewWorker()
+
+		code = 404
+		if rslt := w.Work(&job{url: server.URL}); rslt.Status != jobqueue.ResultStatusSuccess {
+			t.Errorf("A custom mapping should be used: %v", rslt)
+		}
+
+		code = 200
+		if rslt := w.Work(&job{url: server.URL}); rslt.Status != jobqueue.ResultStatusFailure {
+			t.Errorf("An unmapped status should be a failure: %v", rslt)
+		}
+	}()
+}
+
+func TestWorkSigned(t *testing.T) {
+	verifier := &signature.Verifier{Secrets: []string{"old"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := verifier.VerifyRequest(req); err != nil {
+			w.Write([]byte(`{"status":"permanent-failure","message":"` + err.Error() + `"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	SetQueueSecrets([]model.QueueSecret{{QueueName: "signed", Secrets: []string{"new", "old"}}})
+	defer SetQueueSecrets(nil)
+
+	j := &identifiedJob{job{url: server.URL, payload: `{"foo":"bar"}`}, 12345}
+
+	func() {
+		w := (&HTTPWorker{QueueName: "signed"}).NewWorker()
+		if rslt := w.Work(j); rslt.Status != jobqueue.ResultStatusSuccess {
+			t.Errorf("A request should be signed with each secret: %v", rslt)
+		}
+	}()
+
+	func() {
+		w := (&HTTPWorker{QueueName: "unsigned"}).NewWorker()
+		if rslt := w.Work(j); rslt.Status != jobqueue.ResultStatusPermanentFailure {
+			t.Errorf("A request should not be signed without secrets: %v", rslt)
+		}
+	}()
+}
+
 type testServer struct {
 	worker *testWorker
 	server *httptest.Server
@@ -347,4 +494,21 @@ func (j *job) RetryCount() uint               { return 0 }
 func (j *job) RetryDelay() uint               { return 0 }
 func (j *job) FailCount() uint                { return 0 }
 func (j *job) Timeout() uint                  { return 0 }
+func (j *job) ConcurrencyKey() string         { return "" }
+func (j *job) ConcurrencyLimit() uint         { return 0 }
+func (j *job) GroupID() string                { return "" }
 func (j *job) ToLoggable() logger.LoggableJob { return nil }
+
+type identifiedJob struct {
+	job
+	id uint64
+}
+
+func (j *identifiedJob) ToLoggable() logger.LoggableJob { return &loggableJob{id: j.id} }
+
+type loggableJob struct {
+	logger.LoggableJob
+	id uint64
+}
+
+func (j *loggableJob) ID() uint64 { return j.id }
@@ -0,0 +1,356 @@
+package worker
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+
+	"github.com/rs/zerolog"
+)
+
+// ProcessWorker is a worker which handles jobs by long-lived
+// processes of Command running on the local host.
+//
+// Each instance created by NewWorker() keeps at most Size processes,
+// which are spawned on demand and reused for subsequent jobs.  A job
+// is written to the standard input of an idle process as a line of
+// JSON object and the process should write its result to the standard
+// output as a line of JSON object in the same format as the response
+// of an HTTP worker.  A process which exits, breaks the protocol or
+// exceeds the timeout of a job is killed together with its children
+// and replaced by a new one on the next job.
+//
+// Work() must not be called concurrently more than Size times; the
+// dispatcher guarantees it by making Size equal to its MaxWorkers.
+type ProcessWorker struct {
+	QueueName      string
+	Command        []string
+	Size           uint
+	DefaultTimeout uint
+	Logger         *zerolog.Logger
+
+	idle   chan *process
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewWorker creates a new process worker instance which inherits the
+// configurations of the current one.  The new instance has its own
+// pool of processes.
+func (worker *ProcessWorker) NewWorker() Worker {
+	w := &ProcessWorker{
+		QueueName:      worker.QueueName,
+		Command:        worker.Command,
+		Size:           worker.Size,
+		DefaultTimeout: worker.DefaultTimeout,
+		Logger:         worker.Logger,
+	}
+
+	if w.Logger == nil {
+		logger := zerolog.Nop()
+		w.Logger = &logger
+	}
+	if w.Size == 0 {
+		w.Size = 1
+	}
+	w.idle = make(chan *process, w.Size)
+
+	return w
+}
+
+// processJob is a line written to the standard input of a process.
+type processJob struct {
+	ID         uint64 `json:"id"`
+	Category   string `json:"category"`
+	URL        string `json:"url"`
+	Payload    string `json:"payload"`
+	Timeout    uint   `json:"timeout"`
+	RetryCount uint   `json:"retry_count"`
+	FailCount  uint   `json:"fail_count"`
+}
+
+// Work passes the job to an idle process and returns the result.
+func (worker *ProcessWorker) Work(job jobqueue.Job) *jobqueue.Result {
+	if !commandEnabled {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: "Command workers are disabled",
+		}
+	}
+	if len(worker.Command) == 0 {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: "No command is configured",
+		}
+	}
+
+	timeout := job.Timeout()
+	if timeout == 0 {
+		timeout = worker.DefaultTimeout
+	}
+
+	loggable := job.ToLoggable()
+	line, err := json.Marshal(&processJob{
+		ID:         loggable.ID(),
+		Category:   loggable.Category(),
+		URL:        job.URL(),
+		Payload:    job.Payload(),
+		Timeout:    timeout,
+		RetryCount: job.RetryCount(),
+		FailCount:  job.FailCount(),
+	})
+	if err != nil {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: fmt.Sprintf("Cannot encode job: %v", err),
+		}
+	}
+
+	p, err := worker.acquire()
+	if err != nil {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: fmt.Sprintf("Cannot run command: %v", err),
+		}
+	}
+
+	worker.Logger.Debug().
+		Str("action", "dispatch").
+		Str("worker", "ProcessWorker").
+		Int("pid", p.cmd.Process.Pid).
+		Str("payload", job.Payload()).
+		Msg("Dispatched via process")
+
+	failed := make(chan error, 1)
+	go func() {
+		if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+			failed <- err
+		}
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(time.Duration(timeout) * time.Second)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var resp []byte
+	select {
+	case resp = <-p.lines:
+	case <-p.exited:
+		// The process may have written its result just before it
+		// exited.
+		select {
+		case resp = <-p.lines:
+		default:
+			p.kill()
+			return &jobqueue.Result{
+				Status:  jobqueue.ResultStatusInternalFailure,
+				Message: fmt.Sprintf("Process exited: %v", p.err),
+			}
+		}
+	case err := <-failed:
+		p.kill()
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: fmt.Sprintf("Process exited: %v", err),
+		}
+	case <-expired:
+		p.kill()
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusInternalFailure,
+			Message: fmt.Sprintf("Process timed out after %d seconds", timeout),
+		}
+	}
+
+	var rslt jobqueue.Result
+	if err := json.Unmarshal(resp, &rslt); err != nil {
+		p.kill()
+		return &jobqueue.Result{
+			Status: jobqueue.ResultStatusFailure,
+			Message: fmt.Sprintf(
+				"Cannot parse output as JSON: %v\nOriginal output:\n%s",
+				err,
+				string(resp),
+			),
+		}
+	}
+	worker.release(p)
+
+	if !rslt.IsValid() {
+		return &jobqueue.Result{
+			Status:  jobqueue.ResultStatusFailure,
+			Message: fmt.Sprintf("Invalid result status: %s\nOriginal output:\n%s", rslt.Status, string(resp)),
+		}
+	}
+	return &rslt
+}
+
+// Close kills all the idle processes.  Processes in use are killed
+// when they are released.
+func (worker *ProcessWorker) Close() error {
+	worker.mu.Lock()
+	worker.closed = true
+	worker.mu.Unlock()
+
+	for {
+		select {
+		case p := <-worker.idle:
+			p.stop()
+		default:
+			return nil
+		}
+	}
+}
+
+// acquire returns an idle process or spawns a new one.
+func (worker *ProcessWorker) acquire() (*process, error) {
+	for {
+		select {
+		case p := <-worker.idle:
+			select {
+			case <-p.exited:
+				// It has crashed while idle.
+				p.kill()
+				continue
+			default:
+				return p, nil
+			}
+		default:
+			return worker.spawn()
+		}
+	}
+}
+
+func (worker *ProcessWorker) release(p *process) {
+	worker.mu.Lock()
+	defer worker.mu.Unlock()
+
+	if worker.closed {
+		p.stop()
+		return
+	}
+	select {
+	case worker.idle <- p:
+	default:
+		// The pool is full since Work() is called more than Size
+		// times concurrently.
+		p.stop()
+	}
+}
+
+func (worker *ProcessWorker) spawn() (*process, error) {
+	worker.mu.Lock()
+	closed := worker.closed
+	worker.mu.Unlock()
+	if closed {
+		return nil, errors.New("The worker is closed")
+	}
+
+	cmd := exec.Command(worker.Command[0], worker.Command[1:]...)
+	cmd.Env = append(os.Environ(), "FIREWORQ_QUEUE_NAME="+worker.QueueName)
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	worker.Logger.Info().
+		Str("action", "spawn").
+		Str("worker", "ProcessWorker").
+		Int("pid", cmd.Process.Pid).
+		Msg("Spawned a process")
+
+	p := &process{
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan []byte, 1),
+		quit:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go p.read(stdout)
+	return p, nil
+}
+
+// process is a long-lived process owned by a ProcessWorker.
+type process struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan []byte // lines written to the standard output
+	quit     chan struct{}
+	quitOnce sync.Once
+	exited   chan struct{}
+	err      error // why the output ended; set before exited is closed
+}
+
+// read passes lines of the output of the process to lines until the
+// output ends and then waits for the process to exit.  Since Wait()
+// closes the output, it must not be called before the output is read
+// to the end.
+func (p *process) read(stdout io.Reader) {
+	r := bufio.NewReader(stdout)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			p.err = err
+			break
+		}
+		select {
+		case p.lines <- line:
+		case <-p.quit:
+			// Nobody receives the output of a process being stopped.
+		}
+	}
+	p.cmd.Wait()
+	close(p.exited)
+}
+
+func (p *process) close() {
+	p.quitOnce.Do(func() {
+		p.stdin.Close()
+		close(p.quit)
+	})
+}
+
+// processStopTimeout is how long a process is given to exit by itself
+// after its standard input is closed.
+const processStopTimeout = 5 * time.Second
+
+// stop closes the standard input of the process to let it exit and
+// kills it if it does not.
+func (p *process) stop() {
+	p.close()
+	go func() {
+		select {
+		case <-p.exited:
+		case <-time.After(processStopTimeout):
+			p.kill()
+		}
+	}()
+}
+
+// kill kills the process and its children.
+func (p *process) kill() {
+	p.close()
+	syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+	<-p.exited
+}
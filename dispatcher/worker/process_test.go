@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+// An echo server of the line protocol which replies the payload as
+// the message with its PID.
+const processEchoScript = `
+while read -r line; do
+  case "$line" in
+    *'"payload":"crash"'*) exit 1 ;;
+    *'"payload":"sleep"'*) sleep 10 ;;
+    *'"payload":"garbage"'*) echo garbage ;;
+    *'"payload":"permanent"'*) echo '{"status":"permanent-failure","message":"bad"}' ;;
+    *'"payload":"once"'*) echo '{"status":"success","message":"once"}'; exit 0 ;;
+    *'"payload":"timeout"'*) echo "{\"status\":\"success\",\"message\":\"$(echo "$line" | sed 's/.*"timeout":\([0-9]*\).*/\1/')\"}" ;;
+    *) echo "{\"status\":\"success\",\"message\":\"$FIREWORQ_QUEUE_NAME $$\"}" ;;
+  esac
+done
+`
+
+func TestProcessWork(t *testing.T) {
+	config.Locally("dispatch_command_enabled", "true", func() {
+		CommandInit()
+
+		w := (&ProcessWorker{
+			QueueName: "process_queue",
+			Command:   []string{"sh", "-c", processEchoScript},
+			Size:      2,
+		}).NewWorker().(*ProcessWorker)
+		defer w.Close()
+
+		rslt := w.Work(&commandJob{id: 1, payload: "hello"})
+		if rslt.Status != jobqueue.ResultStatusSuccess || !strings.HasPrefix(rslt.Message, "process_queue ") {
+			t.Fatalf("Wrong result: %v", rslt)
+		}
+		pid := rslt.Message
+
+		if rslt := w.Work(&commandJob{payload: "hello"}); rslt.Message != pid {
+			t.Errorf("A process should be reused: %v", rslt)
+		}
+
+		if rslt := w.Work(&commandJob{payload: "permanent"}); rslt.Status != jobqueue.ResultStatusPermanentFailure || rslt.Message != "bad" {
+			t.Errorf("A result should be respected: %v", rslt)
+		}
+
+		if rslt := w.Work(&commandJob{payload: "garbage"}); rslt.Status != jobqueue.ResultStatusFailure {
+			t.Errorf("An invalid output should be a failure: %v", rslt)
+		}
+		rslt = w.Work(&commandJob{payload: "hello"})
+		if rslt.Status != jobqueue.ResultStatusSuccess || rslt.Message == pid {
+			t.Errorf("A process breaking the protocol should be replaced: %v", rslt)
+		}
+		pid = rslt.Message
+
+		if rslt := w.Work(&commandJob{payload: "crash"}); rslt.Status != jobqueue.ResultStatusInternalFailure {
+			t.Errorf("A crash should be an internal failure: %v", rslt)
+		}
+		rslt = w.Work(&commandJob{payload: "hello"})
+		if rslt.Status != jobqueue.ResultStatusSuccess || rslt.Message == pid {
+			t.Errorf("A crashed process should be restarted: %v", rslt)
+		}
+		pid = rslt.Message
+
+		start := time.Now()
+		rslt = w.Work(&commandJob{payload: "sleep", timeout: 1})
+		if rslt.Status != jobqueue.ResultStatusInternalFailure || !strings.Contains(rslt.Message, "timed out") {
+			t.Errorf("A job should time out: %v", rslt)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Error("A process should be killed on timeout")
+		}
+		rslt = w.Work(&commandJob{payload: "hello"})
+		if rslt.Status != jobqueue.ResultStatusSuccess || rslt.Message == pid {
+			t.Errorf("A timed out process should be replaced: %v", rslt)
+		}
+
+		// A result written just before the process exits is not lost.
+		for i := 0; i < 20; i++ {
+			if rslt := w.Work(&commandJob{payload: "once"}); rslt.Status != jobqueue.ResultStatusSuccess || rslt.Message != "once" {
+				t.Fatalf("A result of an exiting process should be received: %v", rslt)
+			}
+			time.Sleep(10 * time.Millisecond) // wait for the exit
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		pids := make(map[string]struct{})
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					rslt := w.Work(&commandJob{payload: "hello"})
+					if rslt.Status != jobqueue.ResultStatusSuccess {
+						t.Errorf("Wrong result: %v", rslt)
+					}
+					mu.Lock()
+					pids[rslt.Message] = struct{}{}
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if len(pids) > 2 {
+			t.Errorf("The number of processes should not exceed the size: %d", len(pids))
+		}
+
+		w.Close()
+		if rslt := w.Work(&commandJob{payload: "hello"}); rslt.Status != jobqueue.ResultStatusInternalFailure {
+			t.Errorf("A closed worker should not spawn a process: %v", rslt)
+		}
+	})
+
+	config.Locally("dispatch_command_enabled", "true", func() {
+		CommandInit()
+
+		w := (&ProcessWorker{
+			Command:        []string{"sh", "-c", processEchoScript},
+			DefaultTimeout: 7,
+		}).NewWorker().(*ProcessWorker)
+		defer w.Close()
+
+		if rslt := w.Work(&commandJob{payload: "timeout"}); rslt.Message != "7" {
+			t.Errorf("The effective timeout should be passed: %v", rslt)
+		}
+		if rslt := w.Work(&commandJob{payload: "timeout", timeout: 3}); rslt.Message != "3" {
+			t.Errorf("The timeout of a job should be passed: %v", rslt)
+		}
+	})
+
+	CommandInit()
+	w := (&ProcessWorker{Command: []string{"cat"}}).NewWorker()
+	if rslt := w.Work(&commandJob{}); rslt.Status != jobqueue.ResultStatusInternalFailure {
+		t.Errorf("A process should not run unless command workers are enabled: %v", rslt)
+	}
+}
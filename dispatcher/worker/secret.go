@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/fireworq/fireworq/model"
+)
+
+var queueSecrets = &secretStore{m: make(map[string][]string)}
+
+// SetQueueSecrets replaces the secrets with which HTTP workers sign
+// requests of jobs in each queue.
+func SetQueueSecrets(secrets []model.QueueSecret) {
+	queueSecrets.set(secrets)
+}
+
+type secretStore struct {
+	sync.RWMutex
+	m map[string][]string
+}
+
+func (s *secretStore) set(secrets []model.QueueSecret) {
+	m := make(map[string][]string, len(secrets))
+	for _, qs := range secrets {
+		if len(qs.Secrets) > 0 {
+			m[qs.QueueName] = qs.Secrets
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.m = m
+}
+
+func (s *secretStore) get(queueName string) []string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.m[queueName]
+}
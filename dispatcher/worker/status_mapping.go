@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+// StatusMapping maps HTTP status codes of responses to result
+// statuses of jobs.  A key is either a status code such as "404" or a
+// class of status codes such as "4xx".  A status code takes
+// precedence over its class.
+type StatusMapping map[string]string
+
+// DefaultStatusMapping is used when a worker determines results by
+// status codes without its own mapping.
+var DefaultStatusMapping = StatusMapping{
+	"2xx": jobqueue.ResultStatusSuccess,
+	"4xx": jobqueue.ResultStatusPermanentFailure,
+	"408": jobqueue.ResultStatusFailure,
+	"429": jobqueue.ResultStatusFailure,
+	"5xx": jobqueue.ResultStatusFailure,
+}
+
+var statusMappingKey = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+// Validate returns an error if the mapping has an invalid key or
+// value.
+func (m StatusMapping) Validate() error {
+	for k, v := range m {
+		if !statusMappingKey.MatchString(k) {
+			return fmt.Errorf("Invalid status code: %s", k)
+		}
+		rslt := &jobqueue.Result{Status: v}
+		if !rslt.IsValid() {
+			return fmt.Errorf("Invalid result status: %s", v)
+		}
+	}
+	return nil
+}
+
+// Status returns the result status for a status code.  A status code
+// not in the mapping is regarded as a failure.
+func (m StatusMapping) Status(code int) string {
+	if s, ok := m[strconv.Itoa(code)]; ok {
+		return s
+	}
+	if s, ok := m[fmt.Sprintf("%dxx", code/100)]; ok {
+		return s
+	}
+	return jobqueue.ResultStatusFailure
+}
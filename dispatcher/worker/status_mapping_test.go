@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+func TestStatusMapping(t *testing.T) {
+	m := StatusMapping{
+		"2xx": jobqueue.ResultStatusSuccess,
+		"404": jobqueue.ResultStatusSuccess,
+		"4xx": jobqueue.ResultStatusPermanentFailure,
+	}
+	if err := m.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	expected := map[int]string{
+		200: jobqueue.ResultStatusSuccess,
+		204: jobqueue.ResultStatusSuccess,
+		404: jobqueue.ResultStatusSuccess,
+		400: jobqueue.ResultStatusPermanentFailure,
+		302: jobqueue.ResultStatusFailure,
+		500: jobqueue.ResultStatusFailure,
+	}
+	for code, status := range expected {
+		if s := m.Status(code); s != status {
+			t.Errorf("Wrong status for %d: %s", code, s)
+		}
+	}
+
+	for _, invalid := range []StatusMapping{
+		{"2XX": jobqueue.ResultStatusSuccess},
+		{"600": jobqueue.ResultStatusSuccess},
+		{"40": jobqueue.ResultStatusSuccess},
+		{"2xx": "ok"},
+		{"5xx": jobqueue.ResultStatusInternalFailure},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Invalid mapping should be rejected: %v", invalid)
+		}
+	}
+
+	if err := DefaultStatusMapping.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// TLSConfig describes TLS settings of requests to workers.  Files
+// are reloaded by ReloadTLS() or when they are modified.
+type TLSConfig struct {
+	CertFile   string // client certificate
+	KeyFile    string // private key of the client certificate
+	CAFile     string // CA bundle to verify workers
+	MinVersion string // one of "1.0", "1.1", "1.2" and "1.3"
+}
+
+var defaultTLS TLSConfig
+
+// Files of a TLS configuration are checked for modification at most
+// once in this interval.
+var tlsCheckInterval = 10 * time.Second
+
+// tlsGeneration is incremented to make every transport reload its
+// TLS configuration.
+var tlsGeneration uint64
+
+// ReloadTLS makes all the HTTP workers reload their certificates
+// before their next requests.  It returns an error if the global
+// configuration cannot be loaded, in which case workers keep the
+// previous certificates.
+func ReloadTLS() error {
+	atomic.AddUint64(&tlsGeneration, 1)
+	if defaultTLS == (TLSConfig{}) {
+		return nil
+	}
+	_, err := defaultTLS.load()
+	return err
+}
+
+// ParseTLSVersion parses a TLS version such as "1.2".  An empty
+// string results in zero, which means the default version.
+func ParseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("Unknown TLS version: %s", v)
+	}
+}
+
+// Validate returns an error if the configuration is malformed.  It
+// does not load the files.
+func (c TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("A client certificate and its key should be specified together")
+	}
+	_, err := ParseTLSVersion(c.MinVersion)
+	return err
+}
+
+// merge returns the configuration with fields left empty taken from
+// base.
+func (c TLSConfig) merge(base TLSConfig) TLSConfig {
+	if c.CertFile == "" {
+		c.CertFile = base.CertFile
+		c.KeyFile = base.KeyFile
+	}
+	if c.CAFile == "" {
+		c.CAFile = base.CAFile
+	}
+	if c.MinVersion == "" {
+		c.MinVersion = base.MinVersion
+	}
+	return c
+}
+
+func (c TLSConfig) files() []string {
+	return []string{c.CertFile, c.KeyFile, c.CAFile}
+}
+
+// load returns nil for an empty configuration, which means the
+// default TLS settings.
+func (c TLSConfig) load() (*tls.Config, error) {
+	if c == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{}
+	cfg.MinVersion, _ = ParseTLSVersion(c.MinVersion)
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificate found in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+func modTimes(files []string) []time.Time {
+	times := make([]time.Time, len(files))
+	for i, f := range files {
+		if f == "" {
+			continue
+		}
+		if fi, err := os.Stat(f); err == nil {
+			times[i] = fi.ModTime()
+		}
+	}
+	return times
+}
+
+func sameTimes(a, b []time.Time) bool {
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+package worker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+func TestTLSConfig(t *testing.T) {
+	if err := (TLSConfig{CertFile: "cert.pem"}).Validate(); err == nil {
+		t.Error("A certificate without a key should be rejected")
+	}
+	if err := (TLSConfig{MinVersion: "1.4"}).Validate(); err == nil {
+		t.Error("An unknown TLS version should be rejected")
+	}
+	if err := (TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", MinVersion: "1.2"}).Validate(); err != nil {
+		t.Error(err)
+	}
+
+	base := TLSConfig{CertFile: "a.pem", KeyFile: "a.key", CAFile: "ca.pem", MinVersion: "1.2"}
+	c := TLSConfig{CertFile: "b.pem", KeyFile: "b.key"}.merge(base)
+	if c != (TLSConfig{CertFile: "b.pem", KeyFile: "b.key", CAFile: "ca.pem", MinVersion: "1.2"}) {
+		t.Errorf("Wrong merged configuration: %v", c)
+	}
+}
+
+func TestWorkMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fireworq-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	serverCert := ca.issue(t, false)
+	clientCert := ca.issue(t, true)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	writeFile(t, caFile, ca.certPEM)
+	writeFile(t, certFile, clientCert.certPEM)
+	writeFile(t, keyFile, clientCert.keyPEM)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca.certPEM)
+	cert, err := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	origInterval := tlsCheckInterval
+	tlsCheckInterval = 0
+	defer func() { tlsCheckInterval = origInterval }()
+
+	j := &job{url: server.URL}
+
+	func() {
+		w := (&HTTPWorker{}).NewWorker()
+		if rslt := w.Work(j); rslt.Status != jobqueue.ResultStatusInternalFailure {
+			t.Errorf("A request without TLS configuration should fail: %v", rslt)
+		}
+	}()
+
+	func() {
+		w := (&HTTPWorker{TLS: TLSConfig{CAFile: caFile}}).NewWorker()
+		if rslt := w.Work(j); rslt.Status != jobqueue.ResultStatusInternalFailure {
+			t.Errorf("A request without a client certificate should fail: %v", rslt)
+		}
+	}()
+
+	w := (&HTTPWorker{TLS: TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: caFile, MinVersion: "1.2"}}).NewWorker()
+	if rslt := w.Work(j); rslt.Status != jobqueue.ResultStatusSuccess {
+		t.Errorf("A request with a client certificate should succeed: %v", rslt)
+	}
+
+	// Replace the client certificate with one the server does not trust.
+	other := newTestCA(t).issue(t, true)
+	writeFile(t, certFile, other.certPEM)
+	writeFile(t, keyFile, other.keyPEM)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	os.Chtimes(keyFile, future, future)
+
+	if rslt := w.Work(j); rslt.Status != jobqueue.ResultStatusInternalFailure {
+		t.Errorf("A modified certificate should be reloaded: %v", rslt)
+	}
+
+	writeFile(t, certFile, clientCert.certPEM)
+	writeFile(t, keyFile, clientCert.keyPEM)
+	if err := ReloadTLS(); err != nil {
+		t.Error(err)
+	}
+	if rslt := w.Work(j); rslt.Status != jobqueue.ResultStatusSuccess {
+		t.Errorf("A certificate should be reloaded on demand: %v", rslt)
+	}
+}
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCA(t *testing.T) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+}
+
+func (ca *testCert) issue(t *testing.T, client bool) *testCert {
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:    []string{"localhost"},
+	}
+	if client {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	return newTestCert(t, tmpl, ca)
+}
+
+func newTestCert(t *testing.T, tmpl *x509.Certificate, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	if err := ioutil.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,216 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransportConfig describes connection settings of an HTTP worker.
+// Fields left zero are taken from the global configuration.
+type TransportConfig struct {
+	KeepAlive           *bool
+	MaxConnsPerHost     uint // connections per host; zero means no limit
+	MaxIdleConnsPerHost uint // idle connections kept per host
+	IdleConnTimeout     uint // seconds
+	ProxyURL            string
+}
+
+var defaultTransport = TransportConfig{MaxIdleConnsPerHost: 10}
+
+// Validate returns an error if the configuration is malformed.
+func (c TransportConfig) Validate() error {
+	if c.ProxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	default:
+		return fmt.Errorf("Unsupported proxy URL: %s", c.ProxyURL)
+	}
+}
+
+func (c TransportConfig) merge(base TransportConfig) TransportConfig {
+	if c.KeepAlive == nil {
+		c.KeepAlive = base.KeepAlive
+	}
+	if c.MaxConnsPerHost == 0 {
+		c.MaxConnsPerHost = base.MaxConnsPerHost
+	}
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = base.MaxIdleConnsPerHost
+	}
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = base.IdleConnTimeout
+	}
+	if c.ProxyURL == "" {
+		c.ProxyURL = base.ProxyURL
+	}
+	return c
+}
+
+// TransportStats contains connection statistics of an HTTP worker.
+type TransportStats struct {
+	OpenConnections   int64  `json:"open_connections"`
+	TotalConnections  uint64 `json:"total_connections"`
+	TotalRequests     uint64 `json:"total_requests"`
+	ReusedConnections uint64 `json:"reused_connections"`
+}
+
+// HasTransportStats is an interface of a worker which reports its
+// connection statistics.
+type HasTransportStats interface {
+	TransportStats() *TransportStats
+}
+
+// transport is an http.Transport owned by a worker, which is rebuilt
+// when its TLS configuration is reloaded, together with those for Unix
+// domain sockets.
+type transport struct {
+	// accessed atomically; kept first for 64-bit alignment
+	open   int64
+	dials  uint64
+	reqs   uint64
+	reused uint64
+
+	config TransportConfig
+	tls    TLSConfig
+
+	mu         sync.Mutex
+	current    *http.Transport
+	modTimes   []time.Time
+	checkedAt  time.Time
+	generation uint64
+
+	unixTransports map[string]*http.Transport
+}
+
+func newTransport(config TransportConfig, tls TLSConfig) *transport {
+	return &transport{config: config, tls: tls}
+}
+
+// get returns the current http.Transport, reloading the TLS
+// configuration if the files have been modified or ReloadTLS() has
+// been called.  If the reload fails, the previous one is returned.
+func (t *transport) get() (*http.Transport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	generation := atomic.LoadUint64(&tlsGeneration)
+	if t.current != nil && t.generation == generation {
+		if t.tls == (TLSConfig{}) || time.Since(t.checkedAt) < tlsCheckInterval {
+			return t.current, nil
+		}
+		t.checkedAt = time.Now()
+		if sameTimes(t.modTimes, modTimes(t.tls.files())) {
+			return t.current, nil
+		}
+	}
+
+	mt := modTimes(t.tls.files())
+	tr, err := t.build()
+	if err != nil {
+		if t.current != nil {
+			// The files may be being replaced; keep the previous ones.
+			t.generation = generation
+			return t.current, nil
+		}
+		return nil, err
+	}
+
+	if t.current != nil {
+		t.current.CloseIdleConnections()
+	}
+	t.current = tr
+	t.modTimes = mt
+	t.checkedAt = time.Now()
+	t.generation = generation
+	return tr, nil
+}
+
+func (t *transport) build() (*http.Transport, error) {
+	tlsConfig, err := t.tls.load()
+	if err != nil {
+		return nil, err
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.MaxIdleConns = 0
+	tr.MaxConnsPerHost = int(t.config.MaxConnsPerHost)
+	tr.MaxIdleConnsPerHost = int(t.config.MaxIdleConnsPerHost)
+	tr.IdleConnTimeout = time.Duration(t.config.IdleConnTimeout) * time.Second
+	tr.DisableKeepAlives = t.config.KeepAlive != nil && !*t.config.KeepAlive
+	tr.TLSClientConfig = tlsConfig
+	tr.DialContext = t.dialContext
+
+	if t.config.ProxyURL != "" {
+		u, err := url.Parse(t.config.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	return tr, nil
+}
+
+var dialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: 30 * time.Second,
+}
+
+func (t *transport) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&t.dials, 1)
+	atomic.AddInt64(&t.open, 1)
+	return &countedConn{Conn: conn, open: &t.open}, nil
+}
+
+// trace counts a request and whether it reuses a connection.
+func (t *transport) trace(req *http.Request) *http.Request {
+	atomic.AddUint64(&t.reqs, 1)
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				atomic.AddUint64(&t.reused, 1)
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+func (t *transport) stats() *TransportStats {
+	return &TransportStats{
+		OpenConnections:   atomic.LoadInt64(&t.open),
+		TotalConnections:  atomic.LoadUint64(&t.dials),
+		TotalRequests:     atomic.LoadUint64(&t.reqs),
+		ReusedConnections: atomic.LoadUint64(&t.reused),
+	}
+}
+
+type countedConn struct {
+	net.Conn
+	open   *int64
+	closed int32
+}
+
+func (c *countedConn) Close() error {
+	if atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		atomic.AddInt64(c.open, -1)
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+func TestTransportConfig(t *testing.T) {
+	if err := (TransportConfig{ProxyURL: "ftp://proxy.example.com"}).Validate(); err == nil {
+		t.Error("An unsupported proxy URL should be rejected")
+	}
+	if err := (TransportConfig{ProxyURL: "http://proxy.example.com:3128"}).Validate(); err != nil {
+		t.Error(err)
+	}
+
+	keepAlive := false
+	base := TransportConfig{KeepAlive: &keepAlive, MaxIdleConnsPerHost: 10, IdleConnTimeout: 30}
+	c := TransportConfig{MaxConnsPerHost: 100}.merge(base)
+	if c.KeepAlive != &keepAlive || c.MaxConnsPerHost != 100 || c.MaxIdleConnsPerHost != 10 || c.IdleConnTimeout != 30 {
+		t.Errorf("Wrong merged configuration: %v", c)
+	}
+
+	tr, err := newTransport(c, TLSConfig{}).build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.MaxConnsPerHost != 100 || tr.MaxIdleConnsPerHost != 10 {
+		t.Errorf("Wrong connection limits: %d, %d", tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	}
+}
+
+func TestWorkerTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	keepAlive := true
+	w1 := (&HTTPWorker{Transport: TransportConfig{KeepAlive: &keepAlive}}).NewWorker().(*HTTPWorker)
+	w2 := (&HTTPWorker{Transport: TransportConfig{KeepAlive: &keepAlive}}).NewWorker().(*HTTPWorker)
+
+	for i := 0; i < 3; i++ {
+		if rslt := w1.Work(&job{url: server.URL}); rslt.Status != jobqueue.ResultStatusSuccess {
+			t.Errorf("Worker request should succeed: %v", rslt)
+		}
+	}
+
+	stats := w1.TransportStats()
+	if stats.TotalRequests != 3 || stats.TotalConnections != 1 || stats.ReusedConnections != 2 {
+		t.Errorf("Wrong stats: %+v", stats)
+	}
+	if stats.OpenConnections != 1 {
+		t.Errorf("A kept-alive connection should be open: %+v", stats)
+	}
+
+	if stats := w2.TransportStats(); stats.TotalRequests != 0 || stats.TotalConnections != 0 {
+		t.Errorf("Workers should not share a transport: %+v", stats)
+	}
+
+	tr, _ := w1.transport.get()
+	tr.CloseIdleConnections()
+	time.Sleep(10 * time.Millisecond)
+	if stats := w1.TransportStats(); stats.OpenConnections != 0 {
+		t.Errorf("A closed connection should not be counted: %+v", stats)
+	}
+}
+
+func TestWorkerDefaultTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	w := (&HTTPWorker{DefaultTimeout: 1}).NewWorker()
+	if rslt := w.Work(&job{url: server.URL}); rslt.Status != jobqueue.ResultStatusInternalFailure {
+		t.Errorf("A job without its own timeout should time out by default: %v", rslt)
+	}
+}
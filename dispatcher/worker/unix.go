@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const unixURLPrefix = "unix://"
+
+// ParseUnixURL splits a URL of the form unix:///path/to.sock:/http/path
+// into the path of the Unix domain socket and the URL of an HTTP
+// request sent over the socket.  ok is false if rawurl is not such a
+// URL.
+//
+// The HTTP path defaults to / if it is omitted.
+func ParseUnixURL(rawurl string) (socket string, httpURL string, ok bool) {
+	if !strings.HasPrefix(rawurl, unixURLPrefix) {
+		return "", "", false
+	}
+	rest := rawurl[len(unixURLPrefix):]
+
+	path := "/"
+	if i := strings.Index(rest, ":"); i >= 0 {
+		rest, path = rest[:i], rest[i+1:]
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	if rest == "" {
+		return "", "", false
+	}
+
+	// The host part is ignored by the server but required by an
+	// HTTP request.
+	return rest, "http://localhost" + path, true
+}
+
+// unix returns an http.Transport which sends requests over the Unix
+// domain socket of path.  Connections are pooled per socket path.
+func (t *transport) unix(path string) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tr, ok := t.unixTransports[path]; ok {
+		return tr
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.MaxIdleConns = 0
+	tr.MaxConnsPerHost = int(t.config.MaxConnsPerHost)
+	tr.MaxIdleConnsPerHost = int(t.config.MaxIdleConnsPerHost)
+	tr.IdleConnTimeout = time.Duration(t.config.IdleConnTimeout) * time.Second
+	tr.DisableKeepAlives = t.config.KeepAlive != nil && !*t.config.KeepAlive
+	tr.Proxy = nil
+	tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return t.dialContext(ctx, "unix", path)
+	}
+
+	if t.unixTransports == nil {
+		t.unixTransports = make(map[string]*http.Transport)
+	}
+	t.unixTransports[path] = tr
+	return tr
+}
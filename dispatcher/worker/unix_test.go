@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+func TestParseUnixURL(t *testing.T) {
+	for _, c := range []struct {
+		rawurl  string
+		socket  string
+		httpURL string
+		ok      bool
+	}{
+		{"unix:///var/run/worker.sock:/jobs?foo=1", "/var/run/worker.sock", "http://localhost/jobs?foo=1", true},
+		{"unix:///var/run/worker.sock", "/var/run/worker.sock", "http://localhost/", true},
+		{"unix:///var/run/worker.sock:jobs", "/var/run/worker.sock", "http://localhost/jobs", true},
+		{"unix://", "", "", false},
+		{"http://localhost/jobs", "", "", false},
+	} {
+		socket, httpURL, ok := ParseUnixURL(c.rawurl)
+		if socket != c.socket || httpURL != c.httpURL || ok != c.ok {
+			t.Errorf("Wrong result for %s: %s %s %v", c.rawurl, socket, httpURL, ok)
+		}
+	}
+}
+
+func TestWorkUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fireworq-unix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "worker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var path string
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.RequestURI()
+		buf, _ := ioutil.ReadAll(req.Body)
+		w.Write(buf)
+	})}
+	go server.Serve(l)
+	defer server.Close()
+
+	keepAlive := true
+	w := (&HTTPWorker{Transport: TransportConfig{KeepAlive: &keepAlive}}).NewWorker().(*HTTPWorker)
+
+	for i := 0; i < 2; i++ {
+		rslt := w.Work(&job{
+			url:     "unix://" + socket + ":/jobs?id=1",
+			payload: `{"status":"success","message":"done"}`,
+		})
+		if rslt.Status != jobqueue.ResultStatusSuccess || rslt.Code != 200 || rslt.Message != "done" {
+			t.Errorf("A job should be dispatched over a Unix domain socket: %v", rslt)
+		}
+		if path != "/jobs?id=1" {
+			t.Errorf("Wrong path: %s", path)
+		}
+	}
+
+	stats := w.TransportStats()
+	if stats.TotalConnections != 1 || stats.ReusedConnections != 1 {
+		t.Errorf("A connection to the socket should be reused: %v", stats)
+	}
+
+	rslt := w.Work(&job{url: "unix://" + filepath.Join(dir, "none.sock") + ":/jobs"})
+	if rslt.Status != jobqueue.ResultStatusInternalFailure {
+		t.Errorf("A missing socket should be an internal failure: %v", rslt)
+	}
+}
@@ -13,3 +13,9 @@ type Worker interface {
 type Config interface {
 	NewWorker() Worker
 }
+
+// Closer is an interface of a worker which holds resources to be
+// released when its dispatcher stops.
+type Closer interface {
+	Close() error
+}
@@ -0,0 +1,66 @@
+package jobqueue
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadLetterHandler is a function called with a job which failed
+// permanently and its last result.  It returns an error if it cannot
+// deliver the dead letter, and then the job is kept in the queue to
+// be tried again later.
+type DeadLetterHandler func(job Job, res *Result) error
+
+// A job whose dead letter cannot be delivered is tried again after
+// this delay in milliseconds.
+var deadLetterRetryDelay = uint64(10 * time.Second / time.Millisecond)
+
+// HasDeadLetterFlag is an interface of a job which may be a dead
+// letter of another job.
+type HasDeadLetterFlag interface {
+	IsDeadLetter() bool
+}
+
+// IsDeadLetter returns true if job is a dead letter of another job.
+// A job which does not implement HasDeadLetterFlag is not.
+func IsDeadLetter(job interface{}) bool {
+	if f, ok := job.(HasDeadLetterFlag); ok {
+		return f.IsDeadLetter()
+	}
+	return false
+}
+
+// DeadLetter describes a permanently failed job delivered to a
+// dead-letter queue as a payload of a new job.
+type DeadLetter struct {
+	QueueName string          `json:"queue_name"`
+	JobID     uint64          `json:"job_id"`
+	Category  string          `json:"category"`
+	URL       string          `json:"url"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Result    *Result         `json:"result"`
+	FailCount uint            `json:"fail_count"`
+	FailedAt  time.Time       `json:"failed_at"`
+}
+
+// NewDeadLetter creates a DeadLetter of a job failed in a queue of
+// queueName.
+func NewDeadLetter(queueName string, job Job, res *Result) *DeadLetter {
+	loggable := job.ToLoggable()
+
+	payload := json.RawMessage(job.Payload())
+	if len(payload) > 0 && !json.Valid(payload) {
+		payload, _ = json.Marshal(job.Payload())
+	}
+
+	return &DeadLetter{
+		QueueName: queueName,
+		JobID:     loggable.ID(),
+		Category:  loggable.Category(),
+		URL:       job.URL(),
+		Payload:   payload,
+		Result:    res,
+		FailCount: loggable.FailCount(),
+		FailedAt:  time.Now(),
+	}
+}
@@ -1,10 +1,15 @@
 package factory
 
 import (
+	"errors"
+
 	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/jobqueue"
 	"github.com/fireworq/fireworq/jobqueue/inmemory"
 	"github.com/fireworq/fireworq/jobqueue/mysql"
+	"github.com/fireworq/fireworq/jobqueue/postgres"
+	"github.com/fireworq/fireworq/jobqueue/redis"
+	"github.com/fireworq/fireworq/jobqueue/sqlite"
 	"github.com/fireworq/fireworq/model"
 
 	"github.com/rs/zerolog/log"
@@ -24,21 +29,71 @@ type IncomingJob = jobqueue.IncomingJob
 // such as mysql.
 type JobQueue = jobqueue.JobQueue
 
+// Driver returns the name of the driver for job queues, which is the
+// value of "queue_driver" configuration or "driver" configuration if
+// the former is empty.
+func Driver() string {
+	driver := config.Get("queue_driver")
+	if driver == "" {
+		driver = config.Get("driver")
+	}
+	return driver
+}
+
+// ValidateShards returns an error if a job queue cannot be sharded
+// across the shards of names.
+func ValidateShards(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if Driver() != "mysql" {
+		return errors.New("Cannot configure Shards without the queue driver of mysql")
+	}
+	_, err := mysql.ShardDsns(names)
+	return err
+}
+
 // NewImpl creates a new jobqueue.Impl instance according to the value
-// of "driver" configuration.
+// of "queue_driver" configuration or "driver" configuration if the
+// former is empty.
 func NewImpl(q *model.Queue) jobqueue.Impl {
 	log := log.With().Str("queue", q.Name).Logger()
 
 	var impl jobqueue.Impl
 
-	driver := config.Get("driver")
+	driver := Driver()
 	if driver == "mysql" {
-		log.Info().Msg("Select mysql as a driver for a job queue")
-		impl = mysql.NewPrimaryBackup(q, mysql.Dsn())
+		switch {
+		case len(q.Shards) > 0:
+			dsns, err := mysql.ShardDsns(q.Shards)
+			if err != nil {
+				log.Panic().Msgf("Cannot shard a job queue: %s", err)
+			}
+			log.Info().Msgf("Select mysql as a driver for a job queue sharded across %d databases", len(dsns))
+			impl = mysql.NewSharded(q, dsns)
+		case q.ConsumptionMode == model.ConsumptionModeActiveActive:
+			log.Info().Msg("Select mysql as a driver for an active-active job queue")
+			impl = mysql.NewActiveActive(q, mysql.Dsn())
+		default:
+			log.Info().Msg("Select mysql as a driver for a job queue")
+			impl = mysql.NewPrimaryBackup(q, mysql.Dsn())
+		}
+	}
+	if driver == "postgres" {
+		log.Info().Msg("Select postgres as a driver for a job queue")
+		impl = postgres.NewPrimaryBackup(q, postgres.Dsn())
+	}
+	if driver == "sqlite" {
+		log.Info().Msg("Select sqlite as a driver for a job queue")
+		impl = sqlite.New(q, sqlite.Dir())
+	}
+	if driver == "redis" {
+		log.Info().Msg("Select redis as a driver for a job queue")
+		impl = redis.NewPrimaryBackup(q, redis.URL())
 	}
 	if driver == "in-memory" {
 		log.Info().Msg("Select in-memory as a driver for a job queue")
-		impl = inmemory.New()
+		impl = inmemory.New(q)
 	}
 
 	if impl == nil {
@@ -48,8 +103,18 @@ func NewImpl(q *model.Queue) jobqueue.Impl {
 	return impl
 }
 
+// Purge discards the jobs of a deleted queue of name if they are
+// kept by the driver in the process, which would otherwise be taken
+// over by a new queue of the same name.
+func Purge(name string) {
+	if Driver() == "in-memory" {
+		inmemory.Delete(name)
+	}
+}
+
 // Start creates and starts a new JobQueue instance whose
-// implementation is decided by the value of "driver" configuration.
+// implementation is decided by the value of "queue_driver" or
+// "driver" configuration.
 func Start(q *model.Queue) JobQueue {
 	impl := NewImpl(q)
 	return jobqueue.Start(q, impl)
@@ -18,3 +18,17 @@ func TestInvalidDriver(t *testing.T) {
 		NewImpl(&model.Queue{})
 	})
 }
+
+func TestQueueDriver(t *testing.T) {
+	config.Locally("driver", "nothing", func() {
+		config.Locally("queue_driver", "in-memory", func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Error("It should prefer the queue driver")
+				}
+			}()
+
+			NewImpl(&model.Queue{})
+		})
+	})
+}
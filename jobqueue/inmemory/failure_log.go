@@ -0,0 +1,162 @@
+package inmemory
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+type failureLog struct {
+	sync.Mutex
+	lastID   uint64
+	failures []jobqueue.FailedJob // in ID order
+}
+
+func (l *failureLog) Add(failed jobqueue.Job, result *jobqueue.Result) error {
+	j, ok := failed.(*job)
+	if !ok {
+		return fmt.Errorf("Invalid job structure: %v", failed)
+	}
+
+	payload := json.RawMessage(j.Payload())
+	if _, err := json.Marshal(payload); err != nil {
+		payload, _ = json.Marshal(j.Payload())
+	}
+	res := *result
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.lastID++
+	l.failures = append(l.failures, jobqueue.FailedJob{
+		ID:        l.lastID,
+		JobID:     j.id,
+		Category:  j.Category(),
+		URL:       j.URL(),
+		Payload:   payload,
+		Result:    &res,
+		FailCount: failed.FailCount() + 1,
+		FailedAt:  time.Now(),
+		CreatedAt: fromMillisecond(j.createdAt),
+	})
+
+	return nil
+}
+
+func (l *failureLog) Delete(failureID uint64) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if i, ok := l.index(failureID); ok {
+		l.failures = append(l.failures[:i], l.failures[i+1:]...)
+	}
+	return nil
+}
+
+func (l *failureLog) Prune(failedBefore uint64, maxRows uint, limit uint) (uint, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	excess := 0
+	if maxRows > 0 {
+		excess = len(l.failures) - int(maxRows)
+	}
+	before := fromMillisecond(failedBefore)
+
+	var n uint
+	for n < limit && int(n) < len(l.failures) {
+		if int(n) >= excess && (failedBefore <= 0 || !l.failures[n].FailedAt.Before(before)) {
+			break
+		}
+		n++
+	}
+	l.failures = l.failures[n:]
+
+	return n, nil
+}
+
+func (l *failureLog) Find(failureID uint64) (*jobqueue.FailedJob, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	i, ok := l.index(failureID)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	j := l.failures[i]
+	return &j, nil
+}
+
+func (l *failureLog) FindAll(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	failures := l.snapshot()
+
+	// Sort by (created_at, failure_id) in descending order.
+	less := func(a, b *jobqueue.FailedJob) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+	sort.Slice(failures, func(x, y int) bool { return less(&failures[x], &failures[y]) })
+
+	if t, id, ok := decodeCursor(cursor); ok {
+		from := &jobqueue.FailedJob{ID: id, CreatedAt: fromMillisecond(t)}
+		start := sort.Search(len(failures), func(x int) bool { return !less(&failures[x], from) })
+		failures = failures[start:]
+	}
+
+	return page(failures, limit), nil
+}
+
+func (l *failureLog) FindAllRecentFailures(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	failures := l.snapshot()
+
+	// Sort by failure_id in descending order.
+	for x, y := 0, len(failures)-1; x < y; x, y = x+1, y-1 {
+		failures[x], failures[y] = failures[y], failures[x]
+	}
+
+	if _, id, ok := decodeCursor(cursor); ok {
+		start := sort.Search(len(failures), func(x int) bool { return failures[x].ID <= id })
+		failures = failures[start:]
+	}
+
+	return page(failures, limit), nil
+}
+
+func (l *failureLog) snapshot() []jobqueue.FailedJob {
+	l.Lock()
+	defer l.Unlock()
+
+	failures := make([]jobqueue.FailedJob, len(l.failures))
+	copy(failures, l.failures)
+	return failures
+}
+
+func (l *failureLog) index(failureID uint64) (int, bool) {
+	i := sort.Search(len(l.failures), func(i int) bool { return l.failures[i].ID >= failureID })
+	if i < len(l.failures) && l.failures[i].ID == failureID {
+		return i, true
+	}
+	return 0, false
+}
+
+func page(failures []jobqueue.FailedJob, limit uint) *jobqueue.FailedJobs {
+	nextCursor := ""
+	if uint(len(failures)) > limit {
+		nextCursor = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(
+			"%d,%d",
+			failures[limit].CreatedAt.UnixNano()/int64(time.Millisecond),
+			failures[limit].ID,
+		)))
+		failures = failures[:limit]
+	}
+
+	return &jobqueue.FailedJobs{FailedJobs: failures, NextCursor: nextCursor}
+}
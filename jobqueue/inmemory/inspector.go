@@ -0,0 +1,160 @@
+package inmemory
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+type inspector struct {
+	q *jobQueue
+}
+
+func (i *inspector) Delete(jobID uint64) error {
+	i.q.Lock()
+	defer i.q.Unlock()
+
+	i.q.remove(jobID)
+	return nil
+}
+
+func (i *inspector) Find(jobID uint64) (*jobqueue.InspectedJob, error) {
+	i.q.Lock()
+	defer i.q.Unlock()
+
+	j, ok := i.q.find(jobID)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return inspect(j), nil
+}
+
+func (i *inspector) FindAllGrabbed(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	return i.findAll(limit, cursor, order, func(j *job) bool {
+		return j.status == "grabbed" && j.nextTry <= now
+	})
+}
+
+func (i *inspector) FindAllWaiting(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	return i.findAll(limit, cursor, order, func(j *job) bool {
+		return j.status == "claimed" && j.nextTry <= now
+	})
+}
+
+func (i *inspector) FindAllDeferred(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	return i.findAll(limit, cursor, order, func(j *job) bool {
+		return j.status == "claimed" && j.nextTry > now
+	})
+}
+
+func (i *inspector) findAll(limit uint, cursor string, order jobqueue.SortOrder, match func(j *job) bool) (*jobqueue.InspectedJobs, error) {
+	jobs := i.snapshot(match)
+
+	// Sort by (next_try, job_id) in the specified order.
+	less := func(a, b *jobqueue.InspectedJob) bool {
+		if !a.NextTry.Equal(b.NextTry) {
+			return a.NextTry.Before(b.NextTry)
+		}
+		return a.ID < b.ID
+	}
+	if order == jobqueue.Desc {
+		asc := less
+		less = func(a, b *jobqueue.InspectedJob) bool { return asc(b, a) }
+	}
+	sort.Slice(jobs, func(x, y int) bool { return less(&jobs[x], &jobs[y]) })
+
+	if t, id, ok := decodeCursor(cursor); ok {
+		from := &jobqueue.InspectedJob{ID: id, NextTry: fromMillisecond(t)}
+		start := sort.Search(len(jobs), func(x int) bool { return !less(&jobs[x], from) })
+		jobs = jobs[start:]
+	}
+
+	results := jobs
+	nextCursor := ""
+	if uint(len(results)) > limit {
+		nextCursor = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(
+			"%d,%d",
+			results[limit].NextTry.UnixNano()/int64(time.Millisecond),
+			results[limit].ID,
+		)))
+		results = results[:limit]
+	}
+
+	return &jobqueue.InspectedJobs{Jobs: results, NextCursor: nextCursor}, nil
+}
+
+func (i *inspector) snapshot(match func(j *job) bool) []jobqueue.InspectedJob {
+	i.q.Lock()
+	defer i.q.Unlock()
+
+	jobs := make([]jobqueue.InspectedJob, 0)
+	for _, j := range i.q.grabbed {
+		if match(j) {
+			jobs = append(jobs, *inspect(j))
+		}
+	}
+	for _, j := range *i.q.queue {
+		if match(j) {
+			jobs = append(jobs, *inspect(j))
+		}
+	}
+	return jobs
+}
+
+func inspect(j *job) *jobqueue.InspectedJob {
+	payload := json.RawMessage(j.Payload())
+	if _, err := json.Marshal(payload); err != nil {
+		payload, _ = json.Marshal(j.Payload())
+	}
+
+	return &jobqueue.InspectedJob{
+		ID:         j.id,
+		Category:   j.Category(),
+		URL:        j.URL(),
+		Payload:    payload,
+		Status:     j.status,
+		CreatedAt:  fromMillisecond(j.createdAt),
+		NextTry:    fromMillisecond(j.nextTry),
+		Timeout:    j.Timeout(),
+		FailCount:  j.failCount,
+		MaxRetries: j.failCount + j.retryCount,
+		RetryDelay: j.RetryDelay(),
+
+		ConcurrencyKey:   j.ConcurrencyKey(),
+		ConcurrencyLimit: j.ConcurrencyLimit(),
+		GroupID:          j.GroupID(),
+	}
+}
+
+func fromMillisecond(t uint64) time.Time {
+	secInMillisec := uint64(time.Second / time.Millisecond)
+	return time.Unix(int64(t/secInMillisec), int64(t%secInMillisec)*int64(time.Millisecond))
+}
+
+// decodeCursor decodes a cursor in a form of "<time>,<ID>".
+func decodeCursor(cursor string) (uint64, uint64, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, false
+	}
+	pair := strings.SplitN(string(decoded), ",", 2)
+	if len(pair) != 2 {
+		return 0, 0, false
+	}
+	t, err1 := strconv.ParseUint(pair[0], 10, 64)
+	id, err2 := strconv.ParseUint(pair[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return t, id, true
+}
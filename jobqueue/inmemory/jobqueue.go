@@ -8,19 +8,59 @@ import (
 
 	"github.com/fireworq/fireworq/jobqueue"
 	"github.com/fireworq/fireworq/jobqueue/logger"
+	"github.com/fireworq/fireworq/model"
 
 	"github.com/rs/zerolog/log"
 )
 
 type jobQueue struct {
 	sync.Mutex
-	queue *queue
+	queue      *queue
+	grabbed    map[uint64]*job     // jobs popped but not completed yet
+	groups     map[string][]uint64 // IDs of jobs in each group in push order
+	failureLog *failureLog
 }
 
-// New creates a jobqueue.Impl which uses in-memory data store.
-func New() jobqueue.Impl {
+type storage struct {
+	sync.Mutex
+	m map[string]*jobQueue
+}
+
+// Job queues are kept by their names so that jobs survive reloading
+// queue definitions.
+var qs = &storage{m: make(map[string]*jobQueue)}
+
+// New creates a jobqueue.Impl which uses in-memory data store.  Job
+// queues of the same name share the data store.
+func New(definition *model.Queue) jobqueue.Impl {
+	qs.Lock()
+	defer qs.Unlock()
+
+	q, ok := qs.m[definition.Name]
+	if !ok {
+		q = newJobQueue()
+		qs.m[definition.Name] = q
+	}
+	return q
+}
+
+// Delete discards the data store of a job queue of name.  It should
+// be called after the job queue is stopped.
+func Delete(name string) {
+	qs.Lock()
+	defer qs.Unlock()
+
+	delete(qs.m, name)
+}
+
+func newJobQueue() *jobQueue {
 	q := make(queue, 0)
-	return &jobQueue{queue: &q}
+	return &jobQueue{
+		queue:      &q,
+		grabbed:    make(map[uint64]*job),
+		groups:     make(map[string][]uint64),
+		failureLog: &failureLog{},
+	}
 }
 
 func (q *jobQueue) Start() {
@@ -38,6 +78,9 @@ func (q *jobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
 
 	job := newJob(j)
 	heap.Push(q.queue, job)
+	if g := job.GroupID(); g != "" {
+		q.groups[g] = append(q.groups[g], job.id)
+	}
 	return job, nil
 }
 
@@ -47,7 +90,8 @@ func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
 
 	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
 	popped := make([]jobqueue.Job, 0, limit)
-	for i := uint(0); i < limit; i++ {
+	skipped := make([]*job, 0)
+	for uint(len(popped)) < limit {
 		if q.queue.Len() <= 0 {
 			break
 		}
@@ -55,13 +99,109 @@ func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
 			break
 		}
 
-		popped = append(popped, heap.Pop(q.queue).(*job))
+		j := heap.Pop(q.queue).(*job)
+		if !q.isGroupHead(j) {
+			skipped = append(skipped, j)
+			continue
+		}
+		j.status = "grabbed"
+		q.grabbed[j.id] = j
+		popped = append(popped, j)
+	}
+	for _, j := range skipped {
+		heap.Push(q.queue, j)
 	}
 	return popped, nil
 }
 
-func (q *jobQueue) Delete(job jobqueue.Job) {
-	// Do nothing; the job is deleted from the queue on Pop().
+// NextTry returns the time when the head of the queue becomes due if
+// it is not due yet.
+func (q *jobQueue) NextTry() (uint64, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.queue.Len() <= 0 {
+		return 0, false
+	}
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	next := (*q.queue)[0].NextTry()
+	if next <= now {
+		return 0, false
+	}
+	return next, true
+}
+
+func (q *jobQueue) isGroupHead(j *job) bool {
+	ids := q.groups[j.GroupID()]
+	return len(ids) == 0 || ids[0] == j.id
+}
+
+func (q *jobQueue) Delete(completedJob jobqueue.Job) {
+	// The job itself is deleted from the queue on Pop().  Just forget
+	// it and remove it from its group to proceed to the next job in
+	// the group.
+	j, ok := completedJob.(*job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.grabbed, j.id)
+	q.leaveGroup(j)
+}
+
+// remove removes a job either grabbed or in the queue.
+func (q *jobQueue) remove(jobID uint64) {
+	if j, ok := q.grabbed[jobID]; ok {
+		delete(q.grabbed, jobID)
+		q.leaveGroup(j)
+		return
+	}
+
+	for i, j := range *q.queue {
+		if j.id == jobID {
+			heap.Remove(q.queue, i)
+			q.leaveGroup(j)
+			return
+		}
+	}
+}
+
+// find finds a job either grabbed or in the queue.
+func (q *jobQueue) find(jobID uint64) (*job, bool) {
+	if j, ok := q.grabbed[jobID]; ok {
+		return j, true
+	}
+
+	for _, j := range *q.queue {
+		if j.id == jobID {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+func (q *jobQueue) leaveGroup(j *job) {
+	g := j.GroupID()
+	if g == "" {
+		return
+	}
+
+	ids := q.groups[g]
+	for i, id := range ids {
+		if id == j.id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) > 0 {
+		q.groups[g] = ids
+	} else {
+		delete(q.groups, g)
+	}
 }
 
 func (q *jobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
@@ -74,6 +214,12 @@ func (q *jobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
 		return
 	}
 
+	if _, ok := q.grabbed[j.id]; !ok {
+		return // deleted while running
+	}
+	delete(q.grabbed, j.id)
+
+	j.status = "claimed"
 	j.nextTry = uint64(time.Now().UnixNano()/int64(time.Millisecond)) + next.NextDelay()
 	j.retryCount = next.RetryCount()
 	j.failCount = next.FailCount()
@@ -85,19 +231,57 @@ func (q *jobQueue) IsActive() bool {
 	return true
 }
 
+func (q *jobQueue) Inspector() jobqueue.Inspector {
+	return &inspector{q}
+}
+
+func (q *jobQueue) FailureLog() jobqueue.FailureLog {
+	return q.failureLog
+}
+
+// job holds copies of the values of an incoming job since the
+// incoming job may be modified by the caller after being pushed.
 type job struct {
-	jobqueue.IncomingJob
 	id         uint64
+	status     string
 	createdAt  uint64
 	nextTry    uint64
 	retryCount uint
 	failCount  uint
+
+	category   string
+	url        string
+	payload    string
+	timeout    uint
+	retryDelay uint
+
+	concurrencyKey   string
+	concurrencyLimit uint
+	groupID          string
+	deadLetter       bool
 }
 
 func newJob(j jobqueue.IncomingJob) *job {
 	id := atomic.AddUint64(&lastID, 1)
 	createdAt := uint64(time.Now().UnixNano() / int64(time.Millisecond))
-	return &job{j, id, createdAt, createdAt + j.NextDelay(), j.RetryCount(), 0}
+	return &job{
+		id:         id,
+		status:     "claimed",
+		createdAt:  createdAt,
+		nextTry:    createdAt + j.NextDelay(),
+		retryCount: j.RetryCount(),
+
+		category:   j.Category(),
+		url:        j.URL(),
+		payload:    j.Payload(),
+		timeout:    j.Timeout(),
+		retryDelay: j.RetryDelay(),
+
+		concurrencyKey:   j.ConcurrencyKey(),
+		concurrencyLimit: j.ConcurrencyLimit(),
+		groupID:          j.GroupID(),
+		deadLetter:       jobqueue.IsDeadLetter(j),
+	}
 }
 
 func (j *job) ID() uint64 {
@@ -109,7 +293,7 @@ func (j *job) CreatedAt() uint64 {
 }
 
 func (j *job) Status() string {
-	return "claimed"
+	return j.status
 }
 
 func (j *job) NextTry() uint64 {
@@ -124,6 +308,42 @@ func (j *job) FailCount() uint {
 	return j.failCount
 }
 
+func (j *job) Category() string {
+	return j.category
+}
+
+func (j *job) URL() string {
+	return j.url
+}
+
+func (j *job) Payload() string {
+	return j.payload
+}
+
+func (j *job) Timeout() uint {
+	return j.timeout
+}
+
+func (j *job) RetryDelay() uint {
+	return j.retryDelay
+}
+
+func (j *job) ConcurrencyKey() string {
+	return j.concurrencyKey
+}
+
+func (j *job) ConcurrencyLimit() uint {
+	return j.concurrencyLimit
+}
+
+func (j *job) GroupID() string {
+	return j.groupID
+}
+
+func (j *job) IsDeadLetter() bool {
+	return j.deadLetter
+}
+
 func (j *job) ToLoggable() logger.LoggableJob {
 	return j
 }
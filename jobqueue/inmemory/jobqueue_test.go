@@ -1,10 +1,16 @@
 package inmemory
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
 	"github.com/fireworq/fireworq/test"
 	"github.com/fireworq/fireworq/test/jobqueue"
 )
@@ -22,7 +28,7 @@ func TestMain(m *testing.M) {
 // Common tests
 
 func TestNew(t *testing.T) {
-	_ = New()
+	_ = New(&model.Queue{Name: "jobqueue_inmemory_new_test"})
 }
 
 func TestSubtests(t *testing.T) {
@@ -31,11 +37,334 @@ func TestSubtests(t *testing.T) {
 
 // in-memory specific tests
 
+func TestInspectGrabbed(t *testing.T) {
+	jq := newJobQueue()
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for i := 0; i < 3; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: fmt.Sprintf("%d", i)}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	jobs, err := jq.Pop(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := jq.Inspector()
+	r, err := i.FindAllGrabbed(10, "", jobqueue.Asc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Jobs) != 2 {
+		t.Errorf("Wrong number of grabbed jobs: %d", len(r.Jobs))
+	}
+
+	// A grabbed job deleted while running is not returned to the
+	// queue.
+	if err := i.Delete(jobs[0].(*job).id); err != nil {
+		t.Error(err)
+	}
+	if _, err := i.Find(jobs[0].(*job).id); err != sql.ErrNoRows {
+		t.Error("Deleted job should not be found")
+	}
+	jq.Update(jobs[0], &nextTestInfo{})
+	if _, err := i.Find(jobs[0].(*job).id); err != sql.ErrNoRows {
+		t.Error("Deleted job should not be updated")
+	}
+
+	jq.Update(jobs[1], &nextTestInfo{})
+	r, err = i.FindAllWaiting(10, "", jobqueue.Asc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Jobs) != 2 {
+		t.Errorf("Wrong number of waiting jobs: %d", len(r.Jobs))
+	}
+}
+
+func TestFailureLog(t *testing.T) {
+	jq := newJobQueue()
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for i := 0; i < 5; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: fmt.Sprintf("%d", i)}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Error(err)
+	}
+	l := jq.FailureLog()
+	for _, j := range jobs {
+		if err := l.Add(j, &jobqueue.Result{
+			Status:  jobqueue.ResultStatusPermanentFailure,
+			Message: j.Payload(),
+		}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, findAll := range []func(uint, string) (*jobqueue.FailedJobs, error){l.FindAll, l.FindAllRecentFailures} {
+		var failed []jobqueue.FailedJob
+		cursor := ""
+		for {
+			r, err := findAll(2, cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed = append(failed, r.FailedJobs...)
+			cursor = r.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+
+		if len(failed) != 5 {
+			t.Errorf("Wrong number of failed jobs: %d", len(failed))
+			continue
+		}
+		// Failures are listed from the latest one.
+		for i, f := range failed {
+			if f.Result.Message != jobs[len(jobs)-1-i].Payload() {
+				t.Errorf("Wrong failed job: %v", f)
+			}
+		}
+	}
+
+	r, err := l.FindAll(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := r.FailedJobs[0].ID
+	if f, err := l.Find(id); err != nil || f.ID != id {
+		t.Errorf("Failed job must be found: %v", err)
+	}
+	if err := l.Delete(id); err != nil {
+		t.Error(err)
+	}
+	if _, err := l.Find(id); err != sql.ErrNoRows {
+		t.Error("Deleted failed job should not be found")
+	}
+}
+
+func TestFailureLogPrune(t *testing.T) {
+	jq := newJobQueue()
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for i := 0; i < 5; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: fmt.Sprintf("%d", i)}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Error(err)
+	}
+	l := jq.FailureLog()
+	for _, j := range jobs {
+		if err := l.Add(j, &jobqueue.Result{
+			Status:  jobqueue.ResultStatusPermanentFailure,
+			Message: j.Payload(),
+		}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	pruner := l.(jobqueue.FailurePruner)
+
+	if n, err := pruner.Prune(0, 0, 10); err != nil || n != 0 {
+		t.Errorf("Nothing should be pruned without retention: %d, %v", n, err)
+	}
+
+	if n, err := pruner.Prune(0, 3, 1); err != nil || n != 1 {
+		t.Errorf("Pruning should be limited: %d, %v", n, err)
+	}
+	if n, err := pruner.Prune(0, 3, 10); err != nil || n != 1 {
+		t.Errorf("Wrong number of pruned failed jobs: %d, %v", n, err)
+	}
+
+	r, err := l.FindAll(10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.FailedJobs) != 3 {
+		t.Fatalf("Wrong number of failed jobs: %d", len(r.FailedJobs))
+	}
+	if r.FailedJobs[2].Result.Message != jobs[2].Payload() {
+		t.Errorf("Older failed jobs should be pruned first: %v", r.FailedJobs[2])
+	}
+
+	now := uint64(time.Now().Add(time.Second).UnixNano() / int64(time.Millisecond))
+	if n, err := pruner.Prune(now, 0, 10); err != nil || n != 3 {
+		t.Errorf("Expired failed jobs should be pruned: %d, %v", n, err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	name := "jobqueue_inmemory_snapshot_test"
+	jq := New(&model.Queue{Name: name}).(*jobQueue)
+
+	for i := 0; i < 3; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: fmt.Sprintf("%d", i)}); err != nil {
+			t.Error(err)
+		}
+	}
+	jobs, err := jq.Pop(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jq.FailureLog().Add(jobs[0], &jobqueue.Result{
+		Status:  jobqueue.ResultStatusPermanentFailure,
+		Message: "failed",
+	}); err != nil {
+		t.Error(err)
+	}
+
+	data, err := Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(data); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New(&model.Queue{Name: name}).(*jobQueue)
+	if restored == jq {
+		t.Error("The queue must be replaced")
+	}
+
+	// The grabbed job is restored as a waiting one.
+	r, err := restored.Inspector().FindAllWaiting(10, "", jobqueue.Asc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Jobs) != 3 {
+		t.Errorf("Wrong number of waiting jobs: %d", len(r.Jobs))
+	}
+	if _, err := restored.Inspector().Find(jobs[0].(*job).id); err != nil {
+		t.Errorf("Grabbed job must be restored: %v", err)
+	}
+
+	failures, err := restored.FailureLog().FindAll(10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures.FailedJobs) != 1 || failures.FailedJobs[0].Result.Message != "failed" {
+		t.Errorf("Wrong failed jobs: %v", failures.FailedJobs)
+	}
+
+	pushed, err := restored.Push(&incomingTestJob{payload: "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range r.Jobs {
+		if j.ID >= pushed.(*job).id {
+			t.Errorf("Job ID must not be reused: %d", j.ID)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	name := "jobqueue_inmemory_delete_test"
+	jq := New(&model.Queue{Name: name})
+	if _, err := jq.Push(&incomingTestJob{payload: "1"}); err != nil {
+		t.Error(err)
+	}
+
+	Delete(name)
+
+	data, err := Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Queues[name]; ok {
+		t.Error("A deleted queue should not be saved")
+	}
+
+	jobs, err := New(&model.Queue{Name: name}).Pop(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("A new queue should not take over jobs of a deleted one: %v", jobs)
+	}
+}
+
 func runSubtests(t *testing.T, db, q string, tests []jqtest.Subtest) {
 	for _, test := range tests {
-		jq := New()
+		jq := newJobQueue()
 		jq.Start()
 		test(t, jq)
 		jq.Stop()
 	}
 }
+
+type incomingTestJob struct {
+	payload string
+}
+
+func (j *incomingTestJob) Category() string {
+	return "test_job"
+}
+
+func (j *incomingTestJob) URL() string {
+	return "http://localhost/"
+}
+
+func (j *incomingTestJob) Payload() string {
+	return j.payload
+}
+
+func (j *incomingTestJob) NextDelay() uint64 {
+	return 0
+}
+
+func (j *incomingTestJob) RetryCount() uint {
+	return 0
+}
+
+func (j *incomingTestJob) RetryDelay() uint {
+	return 0
+}
+
+func (j *incomingTestJob) Timeout() uint {
+	return 0
+}
+
+func (j *incomingTestJob) ConcurrencyKey() string {
+	return ""
+}
+
+func (j *incomingTestJob) ConcurrencyLimit() uint {
+	return 0
+}
+
+func (j *incomingTestJob) GroupID() string {
+	return ""
+}
+
+type nextTestInfo struct{}
+
+func (n *nextTestInfo) NextDelay() uint64 {
+	return 0
+}
+
+func (n *nextTestInfo) RetryCount() uint {
+	return 0
+}
+
+func (n *nextTestInfo) FailCount() uint {
+	return 1
+}
@@ -0,0 +1,171 @@
+package inmemory
+
+import (
+	"container/heap"
+	"encoding/json"
+	"sort"
+	"sync/atomic"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+type snapshot struct {
+	LastID uint64                    `json:"last_id"`
+	Queues map[string]*queueSnapshot `json:"queues"`
+}
+
+type queueSnapshot struct {
+	Jobs          []jobSnapshot        `json:"jobs"`
+	Failures      []jobqueue.FailedJob `json:"failures"`
+	LastFailureID uint64               `json:"last_failure_id"`
+}
+
+type jobSnapshot struct {
+	ID         uint64 `json:"id"`
+	CreatedAt  uint64 `json:"created_at"`
+	NextTry    uint64 `json:"next_try"`
+	RetryCount uint   `json:"retry_count"`
+	FailCount  uint   `json:"fail_count"`
+
+	Category   string `json:"category"`
+	URL        string `json:"url"`
+	Payload    string `json:"payload"`
+	Timeout    uint   `json:"timeout"`
+	RetryDelay uint   `json:"retry_delay"`
+
+	ConcurrencyKey   string `json:"concurrency_key,omitempty"`
+	ConcurrencyLimit uint   `json:"concurrency_limit,omitempty"`
+	GroupID          string `json:"group_id,omitempty"`
+	DeadLetter       bool   `json:"dead_letter,omitempty"`
+}
+
+// Snapshot serializes all the job queues.  Grabbed jobs are saved as
+// claimed ones so that they are tried again after restoring.
+func Snapshot() ([]byte, error) {
+	qs.Lock()
+	defer qs.Unlock()
+
+	s := &snapshot{
+		LastID: atomic.LoadUint64(&lastID),
+		Queues: make(map[string]*queueSnapshot, len(qs.m)),
+	}
+	for name, q := range qs.m {
+		s.Queues[name] = q.snapshot()
+	}
+
+	return json.Marshal(s)
+}
+
+// Restore replaces job queues with those in the data serialized by
+// Snapshot.  It should be called before any job queue is created.
+func Restore(data []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	qs.Lock()
+	defer qs.Unlock()
+
+	for name, saved := range s.Queues {
+		qs.m[name] = saved.restore()
+	}
+
+	// Never reuse IDs of the restored jobs.
+	for {
+		current := atomic.LoadUint64(&lastID)
+		if current >= s.LastID || atomic.CompareAndSwapUint64(&lastID, current, s.LastID) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (q *jobQueue) snapshot() *queueSnapshot {
+	q.Lock()
+	jobs := make([]jobSnapshot, 0, q.queue.Len()+len(q.grabbed))
+	for _, j := range *q.queue {
+		jobs = append(jobs, j.snapshot())
+	}
+	for _, j := range q.grabbed {
+		jobs = append(jobs, j.snapshot())
+	}
+	q.Unlock()
+
+	sort.Slice(jobs, func(x, y int) bool { return jobs[x].ID < jobs[y].ID })
+
+	l := q.failureLog
+	l.Lock()
+	defer l.Unlock()
+
+	failures := make([]jobqueue.FailedJob, len(l.failures))
+	copy(failures, l.failures)
+
+	return &queueSnapshot{
+		Jobs:          jobs,
+		Failures:      failures,
+		LastFailureID: l.lastID,
+	}
+}
+
+func (s *queueSnapshot) restore() *jobQueue {
+	q := newJobQueue()
+
+	// Jobs are in ID order, which is the push order in a group.
+	for _, js := range s.Jobs {
+		j := js.restore()
+		heap.Push(q.queue, j)
+		if g := j.GroupID(); g != "" {
+			q.groups[g] = append(q.groups[g], j.id)
+		}
+	}
+
+	q.failureLog.lastID = s.LastFailureID
+	q.failureLog.failures = s.Failures
+
+	return q
+}
+
+func (j *job) snapshot() jobSnapshot {
+	return jobSnapshot{
+		ID:         j.id,
+		CreatedAt:  j.createdAt,
+		NextTry:    j.nextTry,
+		RetryCount: j.retryCount,
+		FailCount:  j.failCount,
+
+		Category:   j.category,
+		URL:        j.url,
+		Payload:    j.payload,
+		Timeout:    j.timeout,
+		RetryDelay: j.retryDelay,
+
+		ConcurrencyKey:   j.concurrencyKey,
+		ConcurrencyLimit: j.concurrencyLimit,
+		GroupID:          j.groupID,
+		DeadLetter:       j.deadLetter,
+	}
+}
+
+func (js *jobSnapshot) restore() *job {
+	return &job{
+		id:         js.ID,
+		status:     "claimed",
+		createdAt:  js.CreatedAt,
+		nextTry:    js.NextTry,
+		retryCount: js.RetryCount,
+		failCount:  js.FailCount,
+
+		category:   js.Category,
+		url:        js.URL,
+		payload:    js.Payload,
+		timeout:    js.Timeout,
+		retryDelay: js.RetryDelay,
+
+		concurrencyKey:   js.ConcurrencyKey,
+		concurrencyLimit: js.ConcurrencyLimit,
+		groupID:          js.GroupID,
+		deadLetter:       js.DeadLetter,
+	}
+}
@@ -18,6 +18,10 @@ type InspectedJob struct {
 	FailCount  uint            `json:"fail_count"`
 	MaxRetries uint            `json:"max_retries"`
 	RetryDelay uint            `json:"retry_delay"`
+
+	ConcurrencyKey   string `json:"concurrency_key,omitempty"`
+	ConcurrencyLimit uint   `json:"concurrency_limit,omitempty"`
+	GroupID          string `json:"group_id,omitempty"`
 }
 
 // InspectedJobs describes a (page of) job list in a queue.
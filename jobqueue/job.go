@@ -16,6 +16,11 @@ type IncomingJob interface {
 	Timeout() uint     // seconds
 	RetryDelay() uint  // seconds
 	RetryCount() uint
+
+	ConcurrencyKey() string
+	ConcurrencyLimit() uint
+
+	GroupID() string
 }
 
 // Job is an interface of jobs.
@@ -28,6 +33,11 @@ type Job interface {
 	RetryDelay() uint
 	FailCount() uint
 
+	ConcurrencyKey() string
+	ConcurrencyLimit() uint
+
+	GroupID() string
+
 	ToLoggable() logger.LoggableJob
 }
 
@@ -82,14 +92,27 @@ func (j *loggableCompletedJob) FailCount() uint {
 // nextJob : implements the following interfaces
 // - NextInfo
 type nextJob struct {
-	job Job
+	job            Job
+	res            *Result
+	keepRetryCount bool
+	maxRetryAfter  uint // seconds; zero means no limit
 }
 
 func (j *nextJob) NextDelay() uint64 {
-	return uint64(time.Duration(j.job.RetryDelay()) * time.Second / time.Millisecond)
+	delay := j.job.RetryDelay()
+	if j.res.RetryAfter > 0 {
+		delay = j.res.RetryAfter
+		if j.maxRetryAfter > 0 && delay > j.maxRetryAfter {
+			delay = j.maxRetryAfter
+		}
+	}
+	return uint64(time.Duration(delay) * time.Second / time.Millisecond)
 }
 
 func (j *nextJob) RetryCount() uint {
+	if j.keepRetryCount {
+		return j.job.RetryCount()
+	}
 	return j.job.RetryCount() - 1
 }
 
@@ -97,6 +120,47 @@ func (j *nextJob) FailCount() uint {
 	return j.job.FailCount()
 }
 
+// postponedJob : implements the following interfaces
+// - NextInfo
+type postponedJob struct {
+	job   Job
+	delay uint64
+}
+
+func (j *postponedJob) NextDelay() uint64 {
+	return j.delay
+}
+
+func (j *postponedJob) RetryCount() uint {
+	return j.job.RetryCount()
+}
+
+func (j *postponedJob) FailCount() uint {
+	return j.job.FailCount()
+}
+
+// blockedJob : implements the following interfaces
+// - NextInfo
+type blockedJob struct {
+	job Job
+}
+
+// A blocked job is deferred virtually forever so that it stays at the
+// head of its group until it is deleted.
+var blockedJobDelay = uint64(100 * 365 * 24 * time.Hour / time.Millisecond)
+
+func (j *blockedJob) NextDelay() uint64 {
+	return blockedJobDelay
+}
+
+func (j *blockedJob) RetryCount() uint {
+	return 0
+}
+
+func (j *blockedJob) FailCount() uint {
+	return j.job.FailCount()
+}
+
 // NextInfo describes information of a retry.
 type NextInfo interface {
 	NextDelay() uint64
@@ -1,6 +1,10 @@
 package jobqueue
 
 import (
+	"strconv"
+	"time"
+
+	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/jobqueue/logger"
 	"github.com/fireworq/fireworq/model"
 
@@ -18,14 +22,25 @@ type Impl interface {
 	IsActive() bool
 }
 
+// HasNextTry is an interface of a job queue implementation which
+// knows when its earliest deferred job becomes due.
+type HasNextTry interface {
+	// NextTry returns the time, in milliseconds since the epoch,
+	// when the earliest job which is not due yet becomes due.  It
+	// returns false if there is no such job or the time is unknown.
+	NextTry() (uint64, bool)
+}
+
 // JobQueue is an interface of a job queue.
 type JobQueue interface {
 	Stop() <-chan struct{}
 	Push(job IncomingJob) (uint64, error)
 	Pop(limit uint) ([]Job, error)
 	Complete(job Job, res *Result)
+	Postpone(job Job, delay uint64)
 
 	Name() string
+	SetDeadLetterHandler(h DeadLetterHandler)
 
 	IsActive() bool
 	Node() (*Node, error)
@@ -38,28 +53,81 @@ type JobQueue interface {
 // Start returns a job queue.
 func Start(definition *model.Queue, q Impl) JobQueue {
 	jq := &jobQueue{
-		name:       definition.Name,
-		maxWorkers: definition.MaxWorkers,
-		impl:       q,
-		stats:      newStats(),
+		name:               definition.Name,
+		maxWorkers:         definition.MaxWorkers,
+		groupFailurePolicy: definition.GroupFailurePolicy,
+		maxKeptRetries:     maxKeptRetries(),
+		maxRetryAfter:      maxRetryAfter(),
+		impl:               q,
+		stats:              newStats(),
 	}
 	q.Start()
+
+	if definition.FailureRetentionAge > 0 || definition.FailureRetentionRows > 0 {
+		jq.pruner = startPruner(
+			jq,
+			time.Duration(definition.FailureRetentionAge)*time.Second,
+			definition.FailureRetentionRows,
+		)
+	}
+
 	return jq
 }
 
+// maxKeptRetries returns the maximum number of failures of a job up
+// to which a worker can keep the retry count of the job.
+func maxKeptRetries() uint {
+	n, err := strconv.ParseUint(config.Get("queue_max_kept_retries"), 10, 32)
+	if err != nil {
+		n, _ = strconv.ParseUint(config.GetDefault("queue_max_kept_retries"), 10, 32)
+	}
+	return uint(n)
+}
+
+// maxRetryAfter returns the maximum delay, in seconds, before
+// retrying a job which a worker can request.  Zero means no limit.
+func maxRetryAfter() uint {
+	n, err := strconv.ParseUint(config.Get("dispatch_max_retry_after"), 10, 32)
+	if err != nil {
+		n, _ = strconv.ParseUint(config.GetDefault("dispatch_max_retry_after"), 10, 32)
+	}
+	return uint(n)
+}
+
 type jobQueue struct {
-	name       string
-	maxWorkers uint
-	impl       Impl
-	stats      *stats
+	name               string
+	maxWorkers         uint
+	groupFailurePolicy string
+	maxKeptRetries     uint
+	maxRetryAfter      uint
+	impl               Impl
+	stats              *stats
+	deadLetter         DeadLetterHandler
+	pruner             *pruner
 }
 
 func (q *jobQueue) Name() string {
 	return q.name
 }
 
+// SetDeadLetterHandler sets a handler of permanently failed jobs.  It
+// must be called before the queue is dispatched.
+func (q *jobQueue) SetDeadLetterHandler(h DeadLetterHandler) {
+	q.deadLetter = h
+}
+
 func (q *jobQueue) Stop() <-chan struct{} {
-	return q.impl.Stop()
+	if q.pruner == nil {
+		return q.impl.Stop()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		<-q.pruner.stop()
+		<-q.impl.Stop()
+		stopped <- struct{}{}
+	}()
+	return stopped
 }
 
 func (q *jobQueue) Push(j IncomingJob) (uint64, error) {
@@ -107,6 +175,17 @@ func (q *jobQueue) Complete(job Job, res *Result) {
 		q.stats.elapsed(logger.Elapsed(loggable))
 		q.impl.Delete(job)
 	} else if res.IsPermanentFailure() || !j.canRetry() {
+		// The dead letter is delivered before the job is deleted so
+		// that it is never lost.  A dead letter is never delivered
+		// again not to make a loop of dead letters.
+		if q.deadLetter != nil && !IsDeadLetter(job) {
+			if err := q.deadLetter(j, res); err != nil {
+				logger.Info(q.name, "postpone", loggable, "Cannot deliver the dead letter: "+err.Error())
+				q.impl.Update(job, &postponedJob{job, deadLetterRetryDelay})
+				return
+			}
+		}
+
 		logger.Info(q.name, "complete", loggable, res.Message)
 		q.stats.fail(1)
 		q.stats.permanentlyFail(1)
@@ -118,14 +197,27 @@ func (q *jobQueue) Complete(job Job, res *Result) {
 				log.Warn().Msg(err.Error())
 			}
 		}
-		q.impl.Delete(job)
+		if job.GroupID() != "" && q.groupFailurePolicy == model.GroupFailurePolicyBlock {
+			logger.Info(q.name, "block", loggable, "The job group is blocked")
+			q.impl.Update(job, &blockedJob{j})
+		} else {
+			q.impl.Delete(job)
+		}
 	} else {
 		logger.Info(q.name, "retry", loggable, res.Message)
 		q.stats.fail(1)
-		q.impl.Update(job, &nextJob{j})
+		keep := res.KeepRetryCount && j.FailCount() <= q.maxKeptRetries
+		q.impl.Update(job, &nextJob{j, res, keep, q.maxRetryAfter})
 	}
 }
 
+// Postpone returns a job to the queue to be tried again after delay
+// milliseconds.  Unlike a failure, it consumes no retry.
+func (q *jobQueue) Postpone(job Job, delay uint64) {
+	logger.Info(q.name, "postpone", job.ToLoggable(), "The job is postponed")
+	q.impl.Update(job, &postponedJob{job, delay})
+}
+
 func (q *jobQueue) IsActive() bool {
 	return q.impl.IsActive()
 }
@@ -137,6 +229,15 @@ func (q *jobQueue) Node() (*Node, error) {
 	return nil, nil
 }
 
+// NextTry returns the time when the earliest deferred job becomes due
+// if the implementation knows it.
+func (q *jobQueue) NextTry() (uint64, bool) {
+	if hasNextTry, ok := q.impl.(HasNextTry); ok {
+		return hasNextTry.NextTry()
+	}
+	return 0, false
+}
+
 func (q *jobQueue) Stats() *Stats {
 	return q.stats.export()
 }
@@ -163,6 +264,16 @@ func (e *InactiveError) Error() string {
 	return "queue is not active"
 }
 
+// UnsupportedJobError is an error returned when Push() is called with
+// a job which the queue cannot process as specified.
+type UnsupportedJobError struct {
+	Reason string
+}
+
+func (e *UnsupportedJobError) Error() string {
+	return "job is not supported: " + e.Reason
+}
+
 // ConnectionClosedError is an error returned when Pop() is called but
 // connection to a remote store has been lost.
 type ConnectionClosedError struct{}
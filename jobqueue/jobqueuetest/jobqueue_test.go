@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/jobqueue"
 	"github.com/fireworq/fireworq/jobqueue/factory"
 	"github.com/fireworq/fireworq/model"
@@ -26,7 +27,12 @@ func TestRecovering(t *testing.T) {
 
 	jq1 := start(&model.Queue{Name: name, MaxWorkers: 10})
 
-	jq2 := start(&model.Queue{Name: name, MaxWorkers: 10})
+	// A SQLite queue is not shared with a backup but recovers jobs
+	// when it restarts.
+	var jq2 jobqueue.JobQueue
+	if !test.If("driver", "sqlite") {
+		jq2 = start(&model.Queue{Name: name, MaxWorkers: 10})
+	}
 
 	if !jq1.IsActive() {
 		t.Error("The first job queue should be active")
@@ -43,6 +49,9 @@ func TestRecovering(t *testing.T) {
 	// jobs are not completed
 
 	<-jq1.Stop()
+	if test.If("driver", "sqlite") {
+		jq2 = start(&model.Queue{Name: name, MaxWorkers: 10})
+	}
 	defer func() { <-jq2.Stop() }()
 
 	ch := make(chan struct{})
@@ -108,12 +117,6 @@ func TestInspecting(t *testing.T) {
 	jq.Pop(4)
 
 	ins, ok := jq.Inspector()
-	if test.If("driver", "in-memory") {
-		if ins != nil || ok {
-			t.Error("Implemented?")
-		}
-		return
-	}
 	if !ok {
 		t.Error("Cannot get the inspector")
 	}
@@ -564,6 +567,291 @@ func TestStats(t *testing.T) {
 	}
 }
 
+func TestConcurrencyKey(t *testing.T) {
+	queueName := "jobqueue_concurrency_key_test_queue"
+
+	jq := start(&model.Queue{Name: queueName, MaxWorkers: 10})
+	defer func() { <-jq.Stop() }()
+
+	jobs := make([]incomingJob, 3)
+	jobs[0].concurrencyKey = "user1"
+	jobs[0].concurrencyLimit = 1
+	jobs[1].concurrencyKey = "user2"
+	jobs[1].concurrencyLimit = 3
+	for i := range jobs {
+		j := jobs[i]
+		j.url = fmt.Sprintf("job%d", i)
+		jq.Push(&j)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	launched, err := jq.Pop(3)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 3 {
+		t.Errorf("The number of jobs is incorrect: %d", len(launched))
+		return
+	}
+
+	for i, j := range launched {
+		if j.ConcurrencyKey() != jobs[i].concurrencyKey || j.ConcurrencyLimit() != jobs[i].concurrencyLimit {
+			t.Errorf("A concurrency key should be preserved: %s (%d)", j.ConcurrencyKey(), j.ConcurrencyLimit())
+		}
+		jq.Complete(j, &jobqueue.Result{
+			Status: jobqueue.ResultStatusSuccess,
+		})
+	}
+}
+
+func TestGroupFailurePolicy(t *testing.T) {
+	for _, policy := range []string{"", model.GroupFailurePolicySkip, model.GroupFailurePolicyBlock} {
+		func() {
+			queueName := "jobqueue_group_failure_policy_test_queue_" + policy
+
+			jq := start(&model.Queue{Name: queueName, MaxWorkers: 10, GroupFailurePolicy: policy})
+			defer func() { <-jq.Stop() }()
+
+			jobs := make([]incomingJob, 2)
+			for i := range jobs {
+				j := jobs[i]
+				j.url = fmt.Sprintf("job%d", i)
+				j.groupID = "group1"
+				jq.Push(&j)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			launched, err := jq.Pop(2)
+			if err != nil {
+				t.Error(err)
+			}
+			if len(launched) != 1 {
+				t.Errorf("Only the head of a group should be popped: %d", len(launched))
+				return
+			}
+			jq.Complete(launched[0], &jobqueue.Result{
+				Status: jobqueue.ResultStatusPermanentFailure,
+			})
+
+			launched, err = jq.Pop(2)
+			if err != nil {
+				t.Error(err)
+			}
+			if policy == model.GroupFailurePolicyBlock {
+				if len(launched) != 0 {
+					t.Error("A group should be blocked by a failed job")
+				}
+			} else {
+				if len(launched) != 1 || launched[0].URL() != "job1" {
+					t.Error("A failed job should be skipped")
+				}
+			}
+		}()
+	}
+}
+
+func TestPostpone(t *testing.T) {
+	queueName := "jobqueue_postpone_test_queue"
+
+	jq := start(&model.Queue{Name: queueName, MaxWorkers: 10})
+	defer func() { <-jq.Stop() }()
+
+	jq.Push(&incomingJob{url: "job0", retryCount: 2})
+	time.Sleep(10 * time.Millisecond)
+
+	launched, err := jq.Pop(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 1 {
+		t.Errorf("The number of jobs is incorrect: %d", len(launched))
+		return
+	}
+	jq.Postpone(launched[0], 300)
+
+	launched, err = jq.Pop(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 0 {
+		t.Error("A postponed job should be deferred")
+	}
+
+	time.Sleep(400 * time.Millisecond)
+
+	launched, err = jq.Pop(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 1 {
+		t.Errorf("A postponed job should be popped again: %d", len(launched))
+		return
+	}
+	if launched[0].RetryCount() != 2 || launched[0].FailCount() != 0 {
+		t.Errorf("A postponed job should consume no retry: %d, %d", launched[0].RetryCount(), launched[0].FailCount())
+	}
+
+	qStats := jq.Stats()
+	if qStats.TotalFailures != 0 {
+		t.Error("A postponed job should not be counted as a failure")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	queueName := "jobqueue_retry_after_test_queue"
+
+	jq := start(&model.Queue{Name: queueName, MaxWorkers: 10})
+	defer func() { <-jq.Stop() }()
+
+	jq.Push(&incomingJob{url: "job0", retryCount: 1})
+	time.Sleep(10 * time.Millisecond)
+
+	launched, err := jq.Pop(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 1 {
+		t.Errorf("The number of jobs is incorrect: %d", len(launched))
+		return
+	}
+	jq.Complete(launched[0], &jobqueue.Result{
+		Status:         jobqueue.ResultStatusFailure,
+		RetryAfter:     1,
+		KeepRetryCount: true,
+	})
+
+	launched, err = jq.Pop(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 0 {
+		t.Error("A job should be retried after the delay specified by the result")
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	launched, err = jq.Pop(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 1 {
+		t.Errorf("A job should be retried: %d", len(launched))
+		return
+	}
+	if launched[0].RetryCount() != 1 || launched[0].FailCount() != 1 {
+		t.Errorf("A retry should not consume the retry count: %d, %d", launched[0].RetryCount(), launched[0].FailCount())
+	}
+
+	jq.Complete(launched[0], &jobqueue.Result{Status: jobqueue.ResultStatusFailure})
+	time.Sleep(10 * time.Millisecond)
+
+	launched, err = jq.Pop(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(launched) != 1 {
+		t.Errorf("A job should be retried: %d", len(launched))
+		return
+	}
+	if launched[0].RetryCount() != 0 || launched[0].FailCount() != 2 {
+		t.Errorf("A retry should consume the retry count: %d, %d", launched[0].RetryCount(), launched[0].FailCount())
+	}
+}
+
+func TestMaxRetryAfter(t *testing.T) {
+	queueName := "jobqueue_max_retry_after_test_queue"
+
+	config.Locally("dispatch_max_retry_after", "1", func() {
+		jq := start(&model.Queue{Name: queueName, MaxWorkers: 10})
+		defer func() { <-jq.Stop() }()
+
+		jq.Push(&incomingJob{url: "job0", retryCount: 1})
+		time.Sleep(10 * time.Millisecond)
+
+		launched, err := jq.Pop(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(launched) != 1 {
+			t.Fatalf("The number of jobs is incorrect: %d", len(launched))
+		}
+		jq.Complete(launched[0], &jobqueue.Result{
+			Status:     jobqueue.ResultStatusFailure,
+			RetryAfter: 365 * 24 * 60 * 60,
+		})
+
+		if launched, _ := jq.Pop(1); len(launched) != 0 {
+			t.Error("A job should be retried after the delay")
+		}
+
+		time.Sleep(1100 * time.Millisecond)
+
+		launched, err = jq.Pop(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(launched) != 1 {
+			t.Errorf("The delay should be shortened to the maximum: %d", len(launched))
+		}
+	})
+}
+
+func TestMaxKeptRetries(t *testing.T) {
+	queueName := "jobqueue_max_kept_retries_test_queue"
+
+	config.Locally("queue_max_kept_retries", "2", func() {
+		jq := start(&model.Queue{Name: queueName, MaxWorkers: 10})
+		defer func() { <-jq.Stop() }()
+
+		jq.Push(&incomingJob{url: "job0", retryCount: 1})
+		jq.Push(&incomingJob{url: "job1", retryCount: 0})
+		time.Sleep(10 * time.Millisecond)
+
+		keep := &jobqueue.Result{Status: jobqueue.ResultStatusFailure, KeepRetryCount: true}
+
+		// Retries keep the retry count up to the maximum number of
+		// failures.
+		for i := uint(1); i <= 3; i++ {
+			launched, err := jq.Pop(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 1 && len(launched) != 2 {
+				t.Fatalf("The number of jobs is incorrect: %d", len(launched))
+			}
+			if i > 1 && (len(launched) != 1 || launched[0].URL() != "job0") {
+				t.Fatalf("Only job0 should be retried: %v", launched)
+			}
+			for _, j := range launched {
+				if j.URL() == "job0" && j.FailCount() != i-1 {
+					t.Errorf("Wrong fail count: %d", j.FailCount())
+				}
+				jq.Complete(j, keep)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// Once the job failed more than the maximum, its retry count
+		// is consumed.
+		launched, err := jq.Pop(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(launched) != 1 || launched[0].RetryCount() != 0 || launched[0].FailCount() != 3 {
+			t.Fatalf("A retry should consume the retry count beyond the maximum: %v", launched)
+		}
+		jq.Complete(launched[0], keep)
+		time.Sleep(10 * time.Millisecond)
+
+		if launched, _ := jq.Pop(2); len(launched) != 0 {
+			t.Errorf("A job should fail permanently after the retry count is exhausted: %v", launched)
+		}
+		if stats := jq.Stats(); stats.TotalPermanentFailures != 2 {
+			t.Errorf("Both jobs should fail permanently: %d", stats.TotalPermanentFailures)
+		}
+	})
+}
+
 func start(q *model.Queue) jobqueue.JobQueue {
 	impl := factory.NewImpl(q)
 	jq := jobqueue.Start(q, impl)
@@ -578,6 +866,10 @@ type incomingJob struct {
 	nextDelay  uint64
 	retryDelay uint
 	retryCount uint
+
+	concurrencyKey   string
+	concurrencyLimit uint
+	groupID          string
 }
 
 func (job *incomingJob) Category() string {
@@ -611,3 +903,15 @@ func (job *incomingJob) RetryDelay() uint {
 func (job *incomingJob) Timeout() uint {
 	return uint(0)
 }
+
+func (job *incomingJob) ConcurrencyKey() string {
+	return job.concurrencyKey
+}
+
+func (job *incomingJob) ConcurrencyLimit() uint {
+	return job.concurrencyLimit
+}
+
+func (job *incomingJob) GroupID() string {
+	return job.groupID
+}
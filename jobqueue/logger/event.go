@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Event describes an action on a job, which has the same fields as a
+// log entry written by Info() or Debug().
+type Event struct {
+	Time       int64  `json:"time"`
+	Action     string `json:"action"`
+	Queue      string `json:"queue"`
+	Category   string `json:"category"`
+	ID         uint64 `json:"id"`
+	Status     string `json:"status"`
+	CreatedAt  int64  `json:"created_at"`
+	Elapsed    int64  `json:"elapsed"`
+	URL        string `json:"url"`
+	Payload    string `json:"payload"`
+	NextTry    uint64 `json:"next_try"`
+	RetryCount uint   `json:"retry_count"`
+	RetryDelay uint   `json:"retry_delay"`
+	FailCount  uint   `json:"fail_count"`
+	Timeout    uint   `json:"timeout"`
+	Message    string `json:"message"`
+}
+
+// Subscription receives events of jobs matching its filter.
+//
+// Events are delivered through a buffered channel without blocking
+// the actions on jobs.  If the buffer is full, events are dropped and
+// counted by Dropped().
+type Subscription struct {
+	// accessed atomically; kept first for 64-bit alignment
+	dropped uint64
+
+	queue    string
+	category string
+	ch       chan *Event
+}
+
+// C returns the channel of events.  It is never closed.
+func (s *Subscription) C() <-chan *Event {
+	return s.ch
+}
+
+// Dropped returns the number of events dropped so far.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close stops receiving events.
+func (s *Subscription) Close() {
+	subscribers.remove(s)
+}
+
+func (s *Subscription) matches(queue string, category string) bool {
+	return (s.queue == "" || s.queue == queue) &&
+		(s.category == "" || s.category == category)
+}
+
+func (s *Subscription) send(e *Event) {
+	select {
+	case s.ch <- e:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Subscribe starts receiving events of jobs in queue and of category
+// through a buffer of size.  An empty queue or category matches any.
+func Subscribe(queue string, category string, size int) *Subscription {
+	s := &Subscription{
+		queue:    queue,
+		category: category,
+		ch:       make(chan *Event, size),
+	}
+	subscribers.add(s)
+	return s
+}
+
+type subscriptionSet struct {
+	// accessed atomically to skip building events without
+	// subscribers
+	n int32
+
+	sync.RWMutex
+	m map[*Subscription]struct{}
+}
+
+var subscribers = &subscriptionSet{m: make(map[*Subscription]struct{})}
+
+func (ss *subscriptionSet) add(s *Subscription) {
+	ss.Lock()
+	defer ss.Unlock()
+	ss.m[s] = struct{}{}
+	atomic.StoreInt32(&ss.n, int32(len(ss.m)))
+}
+
+func (ss *subscriptionSet) remove(s *Subscription) {
+	ss.Lock()
+	defer ss.Unlock()
+	delete(ss.m, s)
+	atomic.StoreInt32(&ss.n, int32(len(ss.m)))
+}
+
+func (ss *subscriptionSet) empty() bool {
+	return atomic.LoadInt32(&ss.n) == 0
+}
+
+func (ss *subscriptionSet) publish(queue string, action string, j LoggableJob, msg string) {
+	if ss.empty() {
+		return
+	}
+
+	ss.RLock()
+	defer ss.RUnlock()
+
+	var e *Event
+	category := j.Category()
+	for s := range ss.m {
+		if !s.matches(queue, category) {
+			continue
+		}
+		if e == nil {
+			created := int64(j.CreatedAt())
+			elapsed := Elapsed(j)
+			e = &Event{
+				Time:       created + elapsed,
+				Action:     action,
+				Queue:      queue,
+				Category:   category,
+				ID:         j.ID(),
+				Status:     j.Status(),
+				CreatedAt:  created,
+				Elapsed:    elapsed,
+				URL:        j.URL(),
+				Payload:    j.Payload(),
+				NextTry:    j.NextTry(),
+				RetryCount: j.RetryCount(),
+				RetryDelay: j.RetryDelay(),
+				FailCount:  j.FailCount(),
+				Timeout:    j.Timeout(),
+				Message:    msg,
+			}
+		}
+		s.send(e)
+	}
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	if !subscribers.empty() {
+		t.Fatal("There should be no subscription")
+	}
+	Info("queue1", "push", &loggableJob{id: 1, category: "cat1"}, "")
+
+	all := Subscribe("", "", 10)
+	defer all.Close()
+	byQueue := Subscribe("queue1", "", 10)
+	defer byQueue.Close()
+	byCategory := Subscribe("", "cat2", 10)
+	defer byCategory.Close()
+
+	Info("queue1", "push", &loggableJob{id: 2, category: "cat1"}, "New job accepted")
+	Debug("queue2", "pop", &loggableJob{id: 3, category: "cat2"}, "A job grabbed")
+
+	if e := <-all.C(); e.ID != 2 || e.Action != "push" || e.Queue != "queue1" || e.Category != "cat1" || e.Message != "New job accepted" {
+		t.Errorf("Wrong event: %v", e)
+	}
+	if e := <-all.C(); e.ID != 3 || e.Action != "pop" {
+		t.Errorf("A debug event should be published: %v", e)
+	}
+	if e := <-byQueue.C(); e.ID != 2 {
+		t.Errorf("Wrong event: %v", e)
+	}
+	if e := <-byCategory.C(); e.ID != 3 {
+		t.Errorf("Wrong event: %v", e)
+	}
+	if len(byQueue.C()) != 0 || len(byCategory.C()) != 0 {
+		t.Error("Events should be filtered")
+	}
+
+	small := Subscribe("", "", 1)
+	for i := uint64(0); i < 3; i++ {
+		Info("queue1", "complete", &loggableJob{id: i, category: "cat1"}, "")
+	}
+	if small.Dropped() != 2 || len(small.C()) != 1 {
+		t.Errorf("Events exceeding the buffer should be dropped: %d", small.Dropped())
+	}
+	small.Close()
+	Info("queue1", "complete", &loggableJob{id: 4, category: "cat1"}, "")
+	if len(small.C()) != 1 {
+		t.Error("A closed subscription should not receive events")
+	}
+}
+
+type loggableJob struct {
+	id       uint64
+	category string
+}
+
+func (j *loggableJob) Category() string  { return j.category }
+func (j *loggableJob) URL() string       { return "" }
+func (j *loggableJob) Payload() string   { return "" }
+func (j *loggableJob) ID() uint64        { return j.id }
+func (j *loggableJob) Status() string    { return "claimed" }
+func (j *loggableJob) NextTry() uint64   { return 0 }
+func (j *loggableJob) RetryCount() uint  { return 0 }
+func (j *loggableJob) RetryDelay() uint  { return 0 }
+func (j *loggableJob) FailCount() uint   { return 0 }
+func (j *loggableJob) Timeout() uint     { return 0 }
+func (j *loggableJob) CreatedAt() uint64 { return 0 }
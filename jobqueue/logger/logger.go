@@ -68,14 +68,18 @@ func put(event *zerolog.Event, queue string, action string, j LoggableJob, msg s
 		Msg(msg)
 }
 
-// Info writes an INFO level log entry of a job action.
+// Info writes an INFO level log entry of a job action and publishes
+// it to subscriptions.
 func Info(queue string, action string, j LoggableJob, msg string) {
 	put(logger.Info(), queue, action, j, msg)
+	subscribers.publish(queue, action, j, msg)
 }
 
-// Debug writes a DEBUG level log entry of a job action.
+// Debug writes a DEBUG level log entry of a job action and publishes
+// it to subscriptions regardless of the log level.
 func Debug(queue string, action string, j LoggableJob, msg string) {
 	put(logger.Debug(), queue, action, j, msg)
+	subscribers.publish(queue, action, j, msg)
 }
 
 // LoggableJob defines fields of a job to be written into the log.
@@ -17,25 +17,29 @@ var (
 )
 
 type activator struct {
-	queueName string
-	cancel    atomic.Value
-	stoppedC  chan struct{}
-	stopped   uint32
-	active    int32
-	db        *sql.DB
-	dsn       string
-	logger    zerolog.Logger
+	queueName  string
+	cancel     atomic.Value
+	stoppedC   chan struct{}
+	stopped    uint32
+	active     int32
+	db         *sql.DB
+	dsn        string
+	nodeURL    string
+	registered bool
+	logger     zerolog.Logger
 }
 
 type activation interface {
 	queueName() string
 	getDsn() string
+	nodeURL() string
 }
 
 func startActivator(q activation, onActivating func()) *activator {
 	a := &activator{
 		queueName: q.queueName(),
 		dsn:       q.getDsn(),
+		nodeURL:   q.nodeURL(),
 		stoppedC:  make(chan struct{}),
 		logger:    log.With().Str("queue", q.queueName()).Logger(),
 		active:    -1,
@@ -132,6 +136,7 @@ func (a *activator) activate(onActivating func()) (shouldRetry bool) {
 
 	a.logger.Info().Msg("Switching to PRIMARY mode...")
 
+	a.register()
 	onActivating()
 	atomic.StoreInt32(&a.active, 1)
 
@@ -160,6 +165,7 @@ func (a *activator) disconnect() {
 	if a.db == nil {
 		return
 	}
+	a.unregister()
 	a.db.Close()
 	a.db = nil
 }
@@ -175,6 +181,34 @@ func (a *activator) hasLock() bool {
 	return hasLock
 }
 
+// register records the URL of the node for the connection holding
+// the lock so that other nodes can notify the node of pushed jobs.
+func (a *activator) register() {
+	if a.nodeURL == "" {
+		return
+	}
+
+	if _, err := a.db.Exec(
+		"REPLACE INTO fireworq_node (connection_id, url) VALUES (CONNECTION_ID(), ?)",
+		a.nodeURL,
+	); err != nil {
+		a.logger.Error().Msgf("(activator) Failed to register the node: %s", err)
+		return
+	}
+	a.registered = true
+}
+
+func (a *activator) unregister() {
+	if !a.registered {
+		return
+	}
+	a.registered = false
+
+	// Failure is harmless; a stale row is never looked up since it
+	// is keyed by a connection which no longer holds the lock.
+	a.db.Exec("DELETE FROM fireworq_node WHERE connection_id = CONNECTION_ID()")
+}
+
 func (a *activator) getLock() error {
 	err := func() error {
 		ctx, cancel := context.WithCancel(context.Background())
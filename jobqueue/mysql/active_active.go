@@ -0,0 +1,183 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+type activeActiveJobQueue struct {
+	*jobQueue
+	grabber *grabber
+}
+
+// NewActiveActive creates a jobqueue.Impl which uses MySQL as a data
+// store and lets all the nodes in a cluster consume the queue
+// concurrently.
+//
+// Each node grabs jobs under its own grabber identity, which is
+// leased and renewed periodically.  Jobs grabbed by a node whose
+// lease has expired are returned to the queue by the other nodes.
+func NewActiveActive(definition *model.Queue, dsn string) jobqueue.Impl {
+	q := newJobQueue(definition, dsn)
+	return &activeActiveJobQueue{q, nil}
+}
+
+func (q *activeActiveJobQueue) Start() {
+	q.jobQueue.Start()
+
+	if _, err := q.db.Exec(q.sql.createGrabber); err != nil {
+		q.logger.Panic().Msgf("Failed to create grabber table: %s", err)
+	}
+
+	q.grabber = startGrabber(q, q.db, q.recoverDeadGrabber)
+}
+
+func (q *activeActiveJobQueue) Stop() <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		<-q.grabber.stop()
+		<-q.jobQueue.Stop()
+		stopped <- struct{}{}
+	}()
+	return stopped
+}
+
+func (q *activeActiveJobQueue) IsActive() bool {
+	return q.grabber.isActive()
+}
+
+// IsPruningNode returns true if the node has the lowest grabber ID
+// among the live nodes so that only one of them prunes failed jobs.
+func (q *activeActiveJobQueue) IsPruningNode() bool {
+	return q.grabber.isLeader()
+}
+
+// Push rejects a job with a concurrency key since the concurrency
+// limit is enforced only among the jobs dispatched by a single node.
+func (q *activeActiveJobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
+	if j.ConcurrencyKey() != "" {
+		return nil, &jobqueue.UnsupportedJobError{
+			Reason: "concurrency_key cannot be used in an active-active queue",
+		}
+	}
+	return q.jobQueue.Push(j)
+}
+
+func (q *activeActiveJobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
+	if !q.IsActive() {
+		return nil, &jobqueue.InactiveError{}
+	}
+
+	id := q.grabber.grabberID()
+	jobs, err := q.pop(limit, q.sql.launchByGrabber, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		j.(*job).grabberID = id
+	}
+	return jobs, nil
+}
+
+func (q *activeActiveJobQueue) NextTry() (uint64, bool) {
+	if !q.IsActive() {
+		return 0, false
+	}
+
+	return q.jobQueue.NextTry()
+}
+
+// Delete deletes a job only if it is still grabbed by the grabber
+// which popped it; otherwise it has been recovered and may be
+// dispatched by another node.
+func (q *activeActiveJobQueue) Delete(completedJob jobqueue.Job) {
+	log := q.logger.With().Str("method", "Delete").Logger()
+
+	j, ok := completedJob.(*job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	if _, err := q.db.Exec(q.sql.deleteGrabbedJob, j.RowID(), j.grabberID); err != nil {
+		log.Error().Msgf("Failed to delete a job: %s", err)
+	}
+}
+
+// Update updates a job only if it is still grabbed by the grabber
+// which popped it.
+func (q *activeActiveJobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
+	log := q.logger.With().Str("method", "Update").Logger()
+
+	j, ok := completedJob.(*job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	if _, err := q.db.Exec(
+		q.sql.updateGrabbedJob,
+		next.NextDelay(),
+		next.RetryCount(),
+		next.FailCount(),
+		j.RowID(),
+		j.grabberID,
+	); err != nil {
+		log.Error().Msgf("Failed to update a job: %s", err)
+	}
+}
+
+// Recover returns jobs grabbed by dead grabbers to the queue.
+func (q *activeActiveJobQueue) Recover() {
+	log := q.logger.With().Str("method", "Recover").Logger()
+
+	log.Info().Msgf("Recovering jobs of dead grabbers...")
+	if recovered, err := q.recover(q.sql.deadGrabberJobs, q.sql.recoverDeadGrabber); err == nil {
+		log.Info().Msgf("Recovering complete: %d job(s) recovered", recovered)
+	}
+}
+
+func (q *activeActiveJobQueue) recoverDeadGrabber() {
+	log := q.logger.With().Str("method", "Recover").Logger()
+
+	if recovered, err := q.recover(q.sql.deadGrabberJobs, q.sql.recoverDeadGrabber); err == nil && recovered > 0 {
+		log.Info().Msgf("Recovered %d job(s) of dead grabbers", recovered)
+	}
+}
+
+// Node returns the grabber of this node since every node is active.
+func (q *activeActiveJobQueue) Node() (*jobqueue.Node, error) {
+	id := q.grabber.grabberID()
+	if id == 0 {
+		return nil, nil
+	}
+
+	var node jobqueue.Node
+	err := q.db.QueryRow(
+		"SELECT grabber_id, host, url FROM fireworq_grabber WHERE grabber_id = ?",
+		id,
+	).Scan(&node.ID, &node.Host, &node.URL)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+// activation interface
+
+func (q *activeActiveJobQueue) queueName() string {
+	return q.name
+}
+
+func (q *activeActiveJobQueue) getDsn() string {
+	return q.dsn
+}
+
+func (q *activeActiveJobQueue) nodeURL() string {
+	return NodeURL()
+}
@@ -0,0 +1,185 @@
+package mysql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/test/jobqueue"
+	"github.com/fireworq/fireworq/test/mysql"
+)
+
+func TestActiveActiveSubtests(t *testing.T) {
+	jqtest.TestSubtests(t, runActiveActiveSubtests)
+}
+
+func TestActiveActiveCluster(t *testing.T) {
+	q := "jobqueue_mysql_active_active_test"
+
+	jq1 := NewActiveActive(&model.Queue{Name: q, MaxWorkers: 30}, Dsn())
+	jq1.Start()
+	defer func() { <-jq1.Stop() }()
+
+	jq2 := NewActiveActive(&model.Queue{Name: q, MaxWorkers: 30}, Dsn())
+	jq2.Start()
+	defer func() { <-jq2.Stop() }()
+
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	if countActive([]jobqueue.Impl{jq1, jq2}) != 2 {
+		t.Error("All the jobqueues should be active")
+	}
+
+	nodes := make(map[string]bool)
+	for _, jq := range []jobqueue.Impl{jq1, jq2} {
+		node, err := jq.(jobqueue.HasNodeInfo).Node()
+		if err != nil {
+			t.Error(err)
+		}
+		if node == nil {
+			t.Fatal("Must return an active node")
+		}
+		if len(node.Host) <= 0 {
+			t.Error("Must return a host name")
+		}
+		nodes[node.ID] = true
+	}
+	if len(nodes) != 2 {
+		t.Error("Each node should have its own grabber ID")
+	}
+
+	pruningNodes := 0
+	for _, jq := range []jobqueue.Impl{jq1, jq2} {
+		if jq.(jobqueue.PruningNodeElector).IsPruningNode() {
+			pruningNodes++
+		}
+	}
+	if pruningNodes != 1 {
+		t.Errorf("Only one node should prune failed jobs: %d", pruningNodes)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := jq1.Push(jqtest.NewTestJob("job", "http://example.com/", "{}")); err != nil {
+			t.Error(err)
+		}
+	}
+
+	popped := make(map[uint64]bool)
+	for _, jq := range []jobqueue.Impl{jq1, jq2, jq1, jq2} {
+		jobs, err := jq.Pop(3)
+		if err != nil {
+			t.Error(err)
+		}
+		for _, j := range jobs {
+			if popped[j.(*job).ID()] {
+				t.Errorf("Job %d is grabbed twice", j.(*job).ID())
+			}
+			popped[j.(*job).ID()] = true
+		}
+	}
+	if len(popped) != 10 {
+		t.Errorf("Wrong number of popped jobs: %d", len(popped))
+	}
+}
+
+func TestActiveActiveConcurrencyKey(t *testing.T) {
+	jq := NewActiveActive(&model.Queue{Name: "jobqueue_mysql_active_active_key_test", MaxWorkers: 30}, Dsn())
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	j := &keyedTestJob{jqtest.NewTestJob("job", "http://example.com/", "{}"), "user1"}
+	if _, err := jq.Push(j); err == nil {
+		t.Error("A job with a concurrency key should be rejected")
+	} else if _, ok := err.(*jobqueue.UnsupportedJobError); !ok {
+		t.Errorf("Wrong error: %s", err)
+	}
+}
+
+func TestDeadGrabberRecovery(t *testing.T) {
+	q := "jobqueue_mysql_dead_grabber_test"
+
+	jq1 := NewActiveActive(&model.Queue{Name: q, MaxWorkers: 30}, Dsn())
+	jq1.Start()
+	defer func() { <-jq1.Stop() }()
+
+	jq2 := NewActiveActive(&model.Queue{Name: q, MaxWorkers: 30}, Dsn())
+	jq2.Start()
+	defer func() { <-jq2.Stop() }()
+
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	if _, err := jq1.Push(jqtest.NewTestJob("job", "http://example.com/", "{}")); err != nil {
+		t.Error(err)
+	}
+	jobs, err := jq1.Pop(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("Wrong number of popped jobs: %d", len(jobs))
+	}
+	orphan := jobs[0].(*job)
+
+	// Let the lease of jq1 expire as if the node died.
+	db, err := sql.Open("mysql", Dsn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(
+		"UPDATE fireworq_grabber SET expires_at = 0 WHERE grabber_id = ?",
+		orphan.grabberID,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(2 * grabberRenewalInterval)
+
+	// The outdated result of the job must be ignored.
+	jq1.Delete(orphan)
+
+	recovered, err := jq2.Pop(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered) != 1 || recovered[0].(*job).ID() != orphan.ID() {
+		t.Fatal("A job of a dead grabber should be recovered")
+	}
+	if recovered[0].(*job).grabberID == orphan.grabberID {
+		t.Error("A recovered job should be grabbed by another grabber")
+	}
+
+	if !jq1.IsActive() {
+		t.Error("A node should rejoin with a new grabber ID")
+	}
+}
+
+func runActiveActiveSubtests(t *testing.T, db, q string, tests []jqtest.Subtest) {
+	dsn := Dsn()
+
+	jq := NewActiveActive(&model.Queue{Name: q, MaxWorkers: 30}, dsn)
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	for _, test := range tests {
+		err := mysqltest.TruncateTables(dsn)
+		if err != nil {
+			t.Error(err)
+		}
+		// Truncating tables revokes the lease.
+		jq.(*activeActiveJobQueue).grabber.renew()
+		test(t, jq)
+	}
+}
+
+type keyedTestJob struct {
+	jobqueue.IncomingJob
+	key string
+}
+
+func (j *keyedTestJob) ConcurrencyKey() string {
+	return j.key
+}
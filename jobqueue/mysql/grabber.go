@@ -0,0 +1,188 @@
+package mysql
+
+import (
+	"database/sql"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	grabberRenewalInterval = 1 * time.Second
+	grabberLeaseDuration   = 10 * time.Second
+)
+
+// grabber holds an identity of a node consuming a queue in
+// active-active mode.  The identity is leased in `fireworq_grabber`
+// and renewed periodically; jobs grabbed by a grabber whose lease has
+// expired are returned to the queue by any live node.
+type grabber struct {
+	queueName string
+	host      string
+	nodeURL   string
+	db        *sql.DB
+	id        uint64
+	renewedAt atomic.Value // time.Time
+	stopC     chan struct{}
+	stoppedC  chan struct{}
+	logger    zerolog.Logger
+}
+
+func startGrabber(q activation, db *sql.DB, recoverDead func()) *grabber {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	g := &grabber{
+		queueName: q.queueName(),
+		host:      host,
+		nodeURL:   q.nodeURL(),
+		db:        db,
+		stopC:     make(chan struct{}),
+		stoppedC:  make(chan struct{}),
+		logger:    log.With().Str("queue", q.queueName()).Logger(),
+	}
+	g.renewedAt.Store(time.Time{})
+	go g.loop(recoverDead)
+
+	return g
+}
+
+func (g *grabber) stop() <-chan struct{} {
+	close(g.stopC)
+	return g.stoppedC
+}
+
+// isActive returns true if the lease is surely valid.  The lease is
+// considered valid for grabberLeaseDuration since the time before the
+// last renewal was issued, which never outlives the one in the DB.
+func (g *grabber) isActive() bool {
+	if g.grabberID() == 0 {
+		return false
+	}
+	renewedAt := g.renewedAt.Load().(time.Time)
+	return time.Since(renewedAt) < grabberLeaseDuration
+}
+
+// isLeader returns true if the grabber has the lowest ID among the
+// live grabbers of the queue.
+func (g *grabber) isLeader() bool {
+	if !g.isActive() {
+		return false
+	}
+
+	var minID uint64
+	if err := g.db.QueryRow(`
+		SELECT COALESCE(MIN(grabber_id), 0) FROM fireworq_grabber
+		WHERE queue_name = ? AND expires_at > FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000)
+	`, g.queueName).Scan(&minID); err != nil {
+		g.logger.Error().Msgf("(grabber) Failed to find the leader: %s", err)
+		return false
+	}
+	return minID == g.grabberID()
+}
+
+func (g *grabber) grabberID() uint64 {
+	return atomic.LoadUint64(&g.id)
+}
+
+// File private methods
+
+func (g *grabber) loop(recoverDead func()) {
+	ticker := time.NewTicker(grabberRenewalInterval)
+Loop:
+	for {
+		if g.renew() {
+			g.cleanUp()
+			recoverDead()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-g.stopC:
+			break Loop
+		}
+	}
+	ticker.Stop()
+
+	g.release()
+	g.stoppedC <- struct{}{}
+}
+
+// renew extends the lease or registers a new grabber if there is no
+// valid lease.
+func (g *grabber) renew() bool {
+	now := time.Now()
+	lease := grabberLeaseDuration.Nanoseconds() / int64(time.Millisecond)
+
+	if id := g.grabberID(); id > 0 {
+		res, err := g.db.Exec(`
+			UPDATE fireworq_grabber SET expires_at = FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000) + ?
+			WHERE grabber_id = ? AND expires_at > FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000)
+		`, lease, id)
+		if err != nil {
+			g.logger.Error().Msgf("(grabber) Failed to renew the lease: %s", err)
+			return false
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			g.renewedAt.Store(now)
+			return true
+		}
+
+		// The lease has expired and the grabbed jobs may be
+		// recovered by another node.  Never reuse the identity.
+		g.logger.Warn().Msgf("(grabber) The lease of grabber %d has expired", id)
+		atomic.StoreUint64(&g.id, 0)
+	}
+
+	res, err := g.db.Exec(`
+		INSERT INTO fireworq_grabber (queue_name, host, url, expires_at)
+		VALUES (?, ?, ?, FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000) + ?)
+	`, g.queueName, g.host, g.nodeURL, lease)
+	if err != nil {
+		g.logger.Error().Msgf("(grabber) Failed to register a grabber: %s", err)
+		return false
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		g.logger.Error().Msgf("(grabber) Failed to register a grabber: %s", err)
+		return false
+	}
+
+	g.renewedAt.Store(now)
+	atomic.StoreUint64(&g.id, uint64(id))
+	g.logger.Info().Msgf("The node is now consuming the queue as grabber %d", id)
+
+	return true
+}
+
+// cleanUp removes expired grabbers of the queue.
+func (g *grabber) cleanUp() {
+	if _, err := g.db.Exec(`
+		DELETE FROM fireworq_grabber
+		WHERE queue_name = ? AND expires_at <= FLOOR(UNIX_TIMESTAMP(CURRENT_TIME(3)) * 1000)
+	`, g.queueName); err != nil {
+		g.logger.Error().Msgf("(grabber) Failed to clean up expired grabbers: %s", err)
+	}
+}
+
+// release removes the grabber so that the jobs it has grabbed are
+// recovered immediately by other nodes.
+func (g *grabber) release() {
+	id := g.grabberID()
+	if id == 0 {
+		return
+	}
+	atomic.StoreUint64(&g.id, 0)
+
+	if _, err := g.db.Exec(
+		"DELETE FROM fireworq_grabber WHERE grabber_id = ?",
+		id,
+	); err != nil {
+		g.logger.Error().Msgf("(grabber) Failed to release grabber %d: %s", id, err)
+	}
+}
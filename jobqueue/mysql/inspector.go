@@ -2,9 +2,6 @@ package mysql
 
 import (
 	"database/sql"
-	"encoding/base64"
-	"encoding/json"
-	"fmt"
 	"math"
 	"sort"
 	"strconv"
@@ -12,28 +9,25 @@ import (
 	"time"
 
 	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/stored"
 )
 
-type scanner interface {
-	Scan(args ...interface{}) error
-}
-
 type inspector struct {
 	db  *sql.DB
 	sql *sqls
 }
 
 func (i *inspector) Delete(jobID uint64) error {
-	_, err := i.db.Exec(i.sql.deleteJob, jobID)
+	_, err := i.db.Exec(i.sql.DeleteJob, jobID)
 	return err
 }
 
 func (i *inspector) Find(jobID uint64) (*jobqueue.InspectedJob, error) {
-	j, err := i.scan(i.db.QueryRow(i.sql.inspectJob, jobID))
+	j, err := stored.ScanJob(i.db.QueryRow(i.sql.InspectJob, jobID))
 	if err != nil {
 		return nil, err
 	}
-	return j, nil
+	return j.Inspect(), nil
 }
 
 func (i *inspector) FindAllGrabbed(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
@@ -65,15 +59,9 @@ func (i *inspector) findAllAsc(status string, minTime int64, maxTime int64, limi
 		minTime = 0
 	}
 	var minJobID uint64
-	if decoded, err := base64.StdEncoding.DecodeString(cursor); err == nil {
-		if pair := strings.SplitN(string(decoded), ",", 2); len(pair) == 2 {
-			t, err1 := strconv.Atoi(pair[0])
-			j, err2 := strconv.Atoi(pair[1])
-			if err1 == nil && err2 == nil {
-				minTime = int64(t)
-				minJobID = uint64(j)
-			}
-		}
+	if t, j, ok := stored.DecodeCursor(cursor); ok {
+		minTime = t
+		minJobID = j
 	}
 
 	ids := make([]interface{}, 0, limit+1)
@@ -82,7 +70,7 @@ func (i *inspector) findAllAsc(status string, minTime int64, maxTime int64, limi
 
 	if err := func() error {
 		rows, err := i.db.Query(
-			i.sql.inspectJobsAsc+strconv.FormatUint(uint64(limit)+1, 10),
+			i.sql.InspectJobsAsc+strconv.FormatUint(uint64(limit)+1, 10),
 			status,
 			minTime,
 			maxTime,
@@ -120,11 +108,11 @@ func (i *inspector) findAllAsc(status string, minTime int64, maxTime int64, limi
 		defer rows.Close()
 
 		for rows.Next() {
-			j, err := i.scan(rows)
+			j, err := stored.ScanJob(rows)
 			if err != nil {
 				return err
 			}
-			results = append(results, *j)
+			results = append(results, *j.Inspect())
 		}
 		return rows.Err()
 	}(); err != nil {
@@ -149,11 +137,10 @@ func (i *inspector) findAllAsc(status string, minTime int64, maxTime int64, limi
 
 	nextCursor := ""
 	if uint(len(results)) > limit {
-		nextCursor = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(
-			"%d,%d",
+		nextCursor = stored.EncodeCursor(
 			results[limit].NextTry.UnixNano()/int64(time.Millisecond),
 			results[limit].ID,
-		)))
+		)
 		results = results[:limit]
 	}
 
@@ -166,15 +153,9 @@ func (i *inspector) findAllDesc(status string, minTime int64, maxTime int64, lim
 	}
 
 	var maxJobID uint64 = math.MaxUint64
-	if decoded, err := base64.StdEncoding.DecodeString(cursor); err == nil {
-		if pair := strings.SplitN(string(decoded), ",", 2); len(pair) == 2 {
-			t, err1 := strconv.Atoi(pair[0])
-			j, err2 := strconv.Atoi(pair[1])
-			if err1 == nil && err2 == nil {
-				maxTime = int64(t)
-				maxJobID = uint64(j)
-			}
-		}
+	if t, j, ok := stored.DecodeCursor(cursor); ok {
+		maxTime = t
+		maxJobID = j
 	}
 
 	ids := make([]interface{}, 0, limit+1)
@@ -183,7 +164,7 @@ func (i *inspector) findAllDesc(status string, minTime int64, maxTime int64, lim
 
 	if err := func() error {
 		rows, err := i.db.Query(
-			i.sql.inspectJobs+strconv.FormatUint(uint64(limit)+1, 10),
+			i.sql.InspectJobs+strconv.FormatUint(uint64(limit)+1, 10),
 			status,
 			minTime,
 			maxTime,
@@ -221,11 +202,11 @@ func (i *inspector) findAllDesc(status string, minTime int64, maxTime int64, lim
 		defer rows.Close()
 
 		for rows.Next() {
-			j, err := i.scan(rows)
+			j, err := stored.ScanJob(rows)
 			if err != nil {
 				return err
 			}
-			results = append(results, *j)
+			results = append(results, *j.Inspect())
 		}
 		return rows.Err()
 	}(); err != nil {
@@ -250,35 +231,12 @@ func (i *inspector) findAllDesc(status string, minTime int64, maxTime int64, lim
 
 	nextCursor := ""
 	if uint(len(results)) > limit {
-		nextCursor = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(
-			"%d,%d",
+		nextCursor = stored.EncodeCursor(
 			results[limit].NextTry.UnixNano()/int64(time.Millisecond),
 			results[limit].ID,
-		)))
+		)
 		results = results[:limit]
 	}
 
 	return &jobqueue.InspectedJobs{Jobs: results, NextCursor: nextCursor}, nil
 }
-
-func (i *inspector) scan(s scanner) (*jobqueue.InspectedJob, error) {
-	var j jobqueue.InspectedJob
-	var createdAt uint64
-	var nextTry uint64
-	var retryCount uint
-
-	if err := s.Scan(&(j.ID), &(j.Category), &(j.URL), &(j.Payload), &nextTry, &(j.Status), &createdAt, &retryCount, &(j.RetryDelay), &(j.FailCount), &(j.Timeout)); err != nil {
-		return nil, err
-	}
-	if _, err := json.Marshal(j.Payload); err != nil {
-		payload, _ := json.Marshal(string(j.Payload))
-		j.Payload = json.RawMessage(payload)
-	}
-
-	secInMillisec := int64(time.Second / time.Millisecond)
-	j.NextTry = time.Unix(int64(nextTry)/secInMillisec, int64(nextTry)%secInMillisec*int64(time.Millisecond))
-	j.CreatedAt = time.Unix(int64(createdAt)/secInMillisec, int64(createdAt)%secInMillisec*int64(time.Millisecond))
-	j.MaxRetries = j.FailCount + retryCount
-
-	return &j, nil
-}
@@ -16,6 +16,8 @@ import (
 
 	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/sqljq"
+	"github.com/fireworq/fireworq/jobqueue/stored"
 	"github.com/fireworq/fireworq/model"
 )
 
@@ -29,6 +31,12 @@ func Dsn() string {
 	return config.Get("mysql_dsn")
 }
 
+// NodeURL returns the URL at which other nodes reach the API of this
+// node, which is specified in the configuration.
+func NodeURL() string {
+	return config.Get("node_url")
+}
+
 type jobQueue struct {
 	name    string
 	dsn     string
@@ -37,6 +45,7 @@ type jobQueue struct {
 	dbPop   *sql.DB
 	mu      sync.RWMutex
 	stopped uint32
+	shard   uint
 	logger  zerolog.Logger
 }
 
@@ -87,9 +96,37 @@ func (q *jobQueue) Start() {
 		log.Panic().Msgf("Failed to create queue failure log table: %s", err)
 	}
 
+	_, err = q.db.Exec(q.sql.createNode)
+	if err != nil {
+		log.Panic().Msgf("Failed to create node table: %s", err)
+	}
+
+	for _, m := range q.sql.migrations {
+		if err := q.migrate(&m); err != nil {
+			log.Panic().Msgf("Failed to add column %s to %s: %s", m.column, m.table, err)
+		}
+	}
+
 	q.connect()
 }
 
+func (q *jobQueue) migrate(m *migration) error {
+	var n int
+	if err := q.db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+	`, m.table, m.column).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	q.logger.Info().Msgf("Adding column %s to %s...", m.column, m.table)
+	_, err := q.db.Exec(m.query)
+	return err
+}
+
 func (q *jobQueue) Stop() <-chan struct{} {
 	atomic.StoreUint32(&q.stopped, 1)
 
@@ -109,7 +146,7 @@ func (q *jobQueue) IsActive() bool {
 func (q *jobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
 	log := q.logger.With().Str("method", "Push").Logger()
 
-	job := &incomingJob{j, 0}
+	job := &incomingJob{&stored.IncomingJob{IncomingJob: j}, q.shard}
 
 	r, err := q.db.Exec(
 		q.sql.insertJob,
@@ -121,6 +158,10 @@ func (q *jobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
 		job.URL(),
 		job.Payload(),
 		job.Timeout(),
+		job.ConcurrencyKey(),
+		job.ConcurrencyLimit(),
+		job.GroupID(),
+		job.IsDeadLetter(),
 	)
 	if err != nil {
 		log.Debug().Msgf("Failed to insert a job: %s", err)
@@ -132,18 +173,24 @@ func (q *jobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
 		log.Debug().Msgf("Cannot get the last insert ID of the new job: %s", err)
 		return nil, err
 	}
-	job.id = uint64(id)
+	job.JobID = uint64(id)
 
 	return job, nil
 }
 
 func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
-	log := q.logger.With().Str("method", "Pop").Logger()
-
 	if !q.IsActive() {
 		return nil, &jobqueue.InactiveError{}
 	}
 
+	return q.pop(limit, q.sql.launch)
+}
+
+// pop grabs jobs by a launch query, which takes args followed by the
+// IDs of the jobs.
+func (q *jobQueue) pop(limit uint, launch string, args ...interface{}) ([]jobqueue.Job, error) {
+	log := q.logger.With().Str("method", "Pop").Logger()
+
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 	if q.dbPop == nil {
@@ -207,15 +254,15 @@ func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
 		defer rows.Close()
 
 		for i := 0; rows.Next(); i++ {
-			var j job
-			if err := rows.Scan(&(j.id), &(j.category), &(j.url), &(j.payload), &(j.nextTry), &(j.status), &(j.createdAt), &(j.retryCount), &(j.retryDelay), &(j.failCount), &(j.timeout)); err != nil {
+			var f stored.Fields
+			if err := f.Scan(rows); err != nil {
 				log.Debug().Msgf("Failed to scan selected jobs: %s", err)
 				return err
 			}
-			j.status = "grabbed"
+			f.Status = "grabbed"
 
-			ids[i] = j.id
-			results = append(results, &j)
+			ids[i] = f.ID
+			results = append(results, &job{Job: stored.NewJob(f), shard: q.shard})
 		}
 		if err := rows.Err(); err != nil {
 			log.Debug().Msgf("Failed to read selected jobs: %s", err)
@@ -240,8 +287,8 @@ func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
 	// 3. UPDATE the status of jobs.
 	if err := func() error {
 		_, err = tx.Exec(
-			q.sql.launch+"("+strings.Join(placeholders, ",")+")",
-			ids...,
+			launch+"("+strings.Join(placeholders, ",")+")",
+			append(args, ids...)...,
 		)
 		if err != nil {
 			log.Debug().Msgf("Failed to grab jobs: %s", err)
@@ -258,12 +305,33 @@ func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
 	// Emulate `ORDER BY next_try ASC`, which causes `using filesort`
 	// together with `SELECT ~ WHERE ~ IN`.
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].(*job).nextTry < results[j].(*job).nextTry
+		return results[i].(*job).NextTry() < results[j].(*job).NextTry()
 	})
 
 	return results, nil
 }
 
+// NextTry returns the time when the earliest deferred job becomes due.
+func (q *jobQueue) NextTry() (uint64, bool) {
+	log := q.logger.With().Str("method", "NextTry").Logger()
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.dbPop == nil {
+		return 0, false
+	}
+
+	var next sql.NullInt64
+	if err := q.dbPop.QueryRow(q.sql.nextTry).Scan(&next); err != nil {
+		log.Debug().Msgf("Failed to select the next try: %s", err)
+		return 0, false
+	}
+	if !next.Valid {
+		return 0, false
+	}
+	return uint64(next.Int64), true
+}
+
 func (q *jobQueue) Delete(completedJob jobqueue.Job) {
 	log := q.logger.With().Str("method", "Delete").Logger()
 
@@ -273,7 +341,7 @@ func (q *jobQueue) Delete(completedJob jobqueue.Job) {
 		return
 	}
 
-	if _, err := q.db.Exec(q.sql.deleteJob, j.id); err != nil {
+	if _, err := q.db.Exec(q.sql.DeleteJob, j.RowID()); err != nil {
 		log.Error().Msgf("Failed to delete a job: %s", err)
 	}
 }
@@ -292,7 +360,7 @@ func (q *jobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
 		next.NextDelay(),
 		next.RetryCount(),
 		next.FailCount(),
-		j.id,
+		j.RowID(),
 	); err != nil {
 		log.Error().Msgf("Failed to update a job: %s", err)
 	}
@@ -301,10 +369,21 @@ func (q *jobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
 func (q *jobQueue) Recover() {
 	log := q.logger.With().Str("method", "Recover").Logger()
 
+	log.Info().Msgf("Recovering orphan jobs...")
+	if recovered, err := q.recover(q.sql.orphan, q.sql.recover); err == nil {
+		log.Info().Msgf("Recovering complete: %d job(s) recovered", recovered)
+	}
+}
+
+// recover returns jobs selected by an orphan query to the queue by a
+// recover query, which takes the IDs of the jobs.
+func (q *jobQueue) recover(orphan, recover string) (int, error) {
+	log := q.logger.With().Str("method", "Recover").Logger()
+
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	if q.dbPop == nil {
-		return
+		return 0, &jobqueue.ConnectionClosedError{}
 	}
 
 	var recovered int
@@ -315,10 +394,8 @@ func (q *jobQueue) Recover() {
 		placeholders = placeholders[:0]
 		ids = ids[:0]
 
-		log.Info().Msgf("Recovering orphan jobs...")
-
 		if err := func() error {
-			rows, err := q.dbPop.Query(q.sql.orphan)
+			rows, err := q.dbPop.Query(orphan)
 			if err != nil {
 				log.Error().Msgf("Failed to select orphan jobs: %s", err)
 				return err
@@ -340,23 +417,22 @@ func (q *jobQueue) Recover() {
 			}
 			return nil
 		}(); err != nil {
-			return
+			return recovered, err
 		}
 		if len(ids) <= 0 {
-			log.Info().Msgf("Recovering complete: %d job(s) recovered", recovered)
-			return
+			return recovered, nil
 		}
 
 		recovered += len(ids)
 
 		if err := func() error {
-			if _, err := q.dbPop.Exec(q.sql.recover+"("+strings.Join(placeholders, ",")+")", ids...); err != nil {
+			if _, err := q.dbPop.Exec(recover+"("+strings.Join(placeholders, ",")+")", ids...); err != nil {
 				log.Error().Msgf("Failed to recover orphan jobs: %s", err)
 				return err
 			}
 			return nil
 		}(); err != nil {
-			return
+			return recovered, err
 		}
 	}
 }
@@ -366,7 +442,7 @@ func (q *jobQueue) Inspector() jobqueue.Inspector {
 }
 
 func (q *jobQueue) FailureLog() jobqueue.FailureLog {
-	return &failureLog{db: q.db, sql: q.sql}
+	return sqljq.NewFailureLog(q.db, &q.sql.Queries, dialect)
 }
 
 func (q *jobQueue) Node() (*jobqueue.Node, error) {
@@ -53,14 +53,28 @@ func (q *primaryBackupJobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
 	return q.jobQueue.Pop(limit)
 }
 
+func (q *primaryBackupJobQueue) NextTry() (uint64, bool) {
+	if !q.IsActive() {
+		return 0, false
+	}
+
+	return q.jobQueue.NextTry()
+}
+
 func (q *primaryBackupJobQueue) Node() (*jobqueue.Node, error) {
+	return activeNode(q.db, q.activator.lockName())
+}
+
+// activeNode returns the node holding the lock of lockName.
+func activeNode(db *sql.DB, lockName string) (*jobqueue.Node, error) {
 	query := `
-		SELECT ID, HOST FROM information_schema.processlist
-		WHERE ID = IS_USED_LOCK(?)
+		SELECT p.ID, p.HOST, IFNULL(n.url, '') FROM information_schema.processlist AS p
+		LEFT JOIN fireworq_node AS n ON n.connection_id = p.ID
+		WHERE p.ID = IS_USED_LOCK(?)
 	`
 
 	var node jobqueue.Node
-	err := q.db.QueryRow(query, q.activator.lockName()).Scan(&node.ID, &node.Host)
+	err := db.QueryRow(query, lockName).Scan(&node.ID, &node.Host, &node.URL)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
@@ -84,3 +98,7 @@ func (q *primaryBackupJobQueue) queueName() string {
 func (q *primaryBackupJobQueue) getDsn() string {
 	return q.dsn
 }
+
+func (q *primaryBackupJobQueue) nodeURL() string {
+	return NodeURL()
+}
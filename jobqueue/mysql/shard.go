@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/model"
+)
+
+// The index of a shard is encoded in the upper bits of a job ID so
+// that the job can be located only by its ID.  Job IDs in the first
+// shard are the same as those in a queue without sharding.
+const shardIDBits = 48
+
+const localIDMask = uint64(1)<<shardIDBits - 1
+
+func shardedID(shard uint, id uint64) uint64 {
+	return uint64(shard)<<shardIDBits | id
+}
+
+func splitShardedID(id uint64) (uint, uint64) {
+	return uint(id >> shardIDBits), id & localIDMask
+}
+
+func hashShard(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// ShardDsns returns the data source names of the shards of the names
+// specified in the configuration.  The order of the names determines
+// the indices of the shards encoded in job IDs.
+func ShardDsns(names []string) ([]string, error) {
+	if len(names) > model.MaxQueueShards {
+		return nil, fmt.Errorf("Too many shards: %d > %d", len(names), model.MaxQueueShards)
+	}
+
+	dsnByName := make(map[string]string)
+	for _, def := range strings.Fields(config.Get("queue_mysql_shards")) {
+		pair := strings.SplitN(def, "=", 2)
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, fmt.Errorf("Invalid shard definition: %s", def)
+		}
+		dsnByName[pair[0]] = pair[1]
+	}
+
+	dsns := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("Duplicate shard: %s", name)
+		}
+		seen[name] = true
+
+		dsn, ok := dsnByName[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown shard: %s", name)
+		}
+		dsns = append(dsns, dsn)
+	}
+
+	return dsns, nil
+}
@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fireworq/fireworq/config"
+)
+
+func TestShardedID(t *testing.T) {
+	if shardedID(0, 12345) != 12345 {
+		t.Error("A job ID in the first shard should be the same as the local one")
+	}
+
+	shard, id := splitShardedID(shardedID(31, 12345))
+	if shard != 31 || id != 12345 {
+		t.Errorf("Wrong shard or local ID: %d, %d", shard, id)
+	}
+
+	if shardedID(31, localIDMask) >= 1<<53 {
+		t.Error("A job ID should be precisely representable in JSON numbers")
+	}
+}
+
+func TestShardDsns(t *testing.T) {
+	config.Locally("queue_mysql_shards", "s1=user@tcp(db1)/fireworq  s2=user@tcp(db2)/fireworq?a=b", func() {
+		dsns, err := ShardDsns([]string{"s2", "s1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"user@tcp(db2)/fireworq?a=b", "user@tcp(db1)/fireworq"}
+		if !reflect.DeepEqual(dsns, expected) {
+			t.Errorf("Wrong DSNs: %v", dsns)
+		}
+
+		if _, err := ShardDsns([]string{"s1", "s3"}); err == nil {
+			t.Error("An unknown shard should be rejected")
+		}
+		if _, err := ShardDsns([]string{"s1", "s1"}); err == nil {
+			t.Error("A duplicate shard should be rejected")
+		}
+	})
+
+	config.Locally("queue_mysql_shards", "s1", func() {
+		if _, err := ShardDsns([]string{"s1"}); err == nil {
+			t.Error("A broken shard definition should be rejected")
+		}
+	})
+}
+
+func TestShardedCursor(t *testing.T) {
+	a, b := "a", ""
+	cursor := encodeShardedCursor([]*string{&a, nil, &b})
+	decoded := decodeShardedCursor(cursor, 3)
+	if decoded[0] == nil || *decoded[0] != "a" {
+		t.Error("A cursor of a shard should be restored")
+	}
+	if decoded[1] != nil {
+		t.Error("An exhausted shard should remain exhausted")
+	}
+	if decoded[2] == nil || *decoded[2] != "" {
+		t.Error("A cursor of a shard should be restored")
+	}
+
+	if encodeShardedCursor([]*string{nil, nil}) != "" {
+		t.Error("A cursor should be empty if all the shards are exhausted")
+	}
+
+	for _, c := range decodeShardedCursor("", 2) {
+		if c == nil || *c != "" {
+			t.Error("An empty cursor should start from the beginning of all the shards")
+		}
+	}
+}
+
+func TestMergeShards(t *testing.T) {
+	pages := [][]int{{1, 4, 5}, {}, {2, 3, 6}}
+	lens := []int{3, 0, 3}
+	merged, taken := mergeShards(lens, 4, func(s1, k1, s2, k2 int) bool {
+		return pages[s1][k1] < pages[s2][k2]
+	})
+
+	values := make([]int, 0, len(merged))
+	for _, m := range merged {
+		values = append(values, pages[m[0]][m[1]])
+	}
+	if !reflect.DeepEqual(values, []int{1, 2, 3, 4}) {
+		t.Errorf("Wrong merged items: %v", values)
+	}
+	if !reflect.DeepEqual(taken, []int{2, 0, 2}) {
+		t.Errorf("Wrong numbers of taken items: %v", taken)
+	}
+}
@@ -0,0 +1,287 @@
+package mysql
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+// shard is a job queue in a single database backing a part of a
+// sharded queue.
+type shard interface {
+	jobqueue.Impl
+	Recover()
+	NextTry() (uint64, bool)
+	Node() (*jobqueue.Node, error)
+}
+
+type shardedJobQueue struct {
+	name      string
+	dsn       string
+	policy    string
+	shards    []shard
+	cores     []*jobQueue
+	activator *activator // only in primary-backup mode
+	pushed    uint32
+	popped    uint32
+	logger    zerolog.Logger
+}
+
+// NewSharded creates a jobqueue.Impl which distributes jobs across
+// multiple MySQL databases specified by dsns.
+//
+// A pushed job is put in one of the shards according to the shard
+// policy of the queue and gets an ID which encodes the index of the
+// shard.  Jobs are popped fairly from all the shards.
+//
+// In primary-backup mode, the active node is determined by the lock
+// in the first shard.  In active-active mode, each node consumes
+// each shard under its own grabber identity.
+func NewSharded(definition *model.Queue, dsns []string) jobqueue.Impl {
+	q := &shardedJobQueue{
+		name:   definition.Name,
+		dsn:    dsns[0],
+		policy: definition.ShardPolicy,
+		shards: make([]shard, len(dsns)),
+		cores:  make([]*jobQueue, len(dsns)),
+		logger: log.With().Str("queue", definition.Name).Logger(),
+	}
+
+	for i, dsn := range dsns {
+		core := newJobQueue(definition, dsn)
+		core.shard = uint(i)
+		core.logger = core.logger.With().Int("shard", i).Logger()
+
+		q.cores[i] = core
+		if definition.ConsumptionMode == model.ConsumptionModeActiveActive {
+			q.shards[i] = &activeActiveJobQueue{core, nil}
+		} else {
+			q.shards[i] = core
+		}
+	}
+
+	return q
+}
+
+func (q *shardedJobQueue) Start() {
+	for _, s := range q.shards {
+		s.Start()
+	}
+
+	if _, ok := q.shards[0].(*activeActiveJobQueue); !ok {
+		q.activator = startActivator(q, q.Recover)
+	}
+}
+
+func (q *shardedJobQueue) Stop() <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		if q.activator != nil {
+			<-q.activator.stop()
+		}
+		for _, s := range q.shards {
+			<-s.Stop()
+		}
+		stopped <- struct{}{}
+	}()
+	return stopped
+}
+
+func (q *shardedJobQueue) IsActive() bool {
+	if q.activator != nil {
+		return q.activator.isActive()
+	}
+
+	for _, s := range q.shards {
+		if s.IsActive() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPruningNode returns true if the node is active in primary-backup
+// mode or elected by the first shard in active-active mode.
+func (q *shardedJobQueue) IsPruningNode() bool {
+	if q.activator != nil {
+		return q.activator.isActive()
+	}
+	if s, ok := q.shards[0].(*activeActiveJobQueue); ok {
+		return s.IsPruningNode()
+	}
+	return false
+}
+
+// Push puts a job in the shard chosen by the shard policy.  A job
+// distributed round-robin is put in the next shard if the shard is
+// unavailable.
+func (q *shardedJobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
+	i, fixed := q.shardOf(j)
+	if fixed {
+		return q.shards[i].Push(j)
+	}
+
+	var lastErr error
+	for k := 0; k < len(q.shards); k++ {
+		pushed, err := q.shards[(i+k)%len(q.shards)].Push(j)
+		if err == nil {
+			return pushed, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (q *shardedJobQueue) shardOf(j jobqueue.IncomingJob) (i int, fixed bool) {
+	n := len(q.shards)
+
+	// Keep the order of jobs in a group.
+	if g := j.GroupID(); g != "" {
+		return hashShard(g, n), true
+	}
+
+	if q.policy == model.ShardPolicyHash {
+		key := j.ConcurrencyKey()
+		if key == "" {
+			key = j.Category()
+		}
+		return hashShard(key, n), true
+	}
+
+	return int(atomic.AddUint32(&q.pushed, 1) % uint32(n)), false
+}
+
+// Pop grabs jobs from all the shards.  Each shard is given a fair
+// share of limit first, starting from a different shard at every
+// call, and then shards with more jobs fill the rest.
+func (q *shardedJobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
+	if !q.IsActive() {
+		return nil, &jobqueue.InactiveError{}
+	}
+
+	n := len(q.shards)
+	start := int(atomic.AddUint32(&q.popped, 1) % uint32(n))
+	full := make([]bool, n)
+	results := make([]jobqueue.Job, 0, limit)
+
+	var lastErr error
+	for pass := 0; pass < 2 && uint(len(results)) < limit; pass++ {
+		for k := 0; k < n && uint(len(results)) < limit; k++ {
+			i := (start + k) % n
+			if pass > 0 && !full[i] {
+				continue
+			}
+
+			quota := limit - uint(len(results))
+			if pass == 0 {
+				rest := uint(n - k)
+				quota = (quota + rest - 1) / rest
+			}
+
+			jobs, err := q.shards[i].Pop(quota)
+			if _, ok := err.(*jobqueue.InactiveError); ok {
+				continue
+			} else if err != nil {
+				q.logger.Debug().Msgf("Failed to pop jobs from shard %d: %s", i, err)
+				lastErr = err
+				continue
+			}
+
+			full[i] = uint(len(jobs)) >= quota
+			results = append(results, jobs...)
+		}
+	}
+
+	if len(results) <= 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	// Jobs from each shard are already in order of next_try.
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].(*job).NextTry() < results[j].(*job).NextTry()
+	})
+
+	return results, nil
+}
+
+func (q *shardedJobQueue) NextTry() (uint64, bool) {
+	if !q.IsActive() {
+		return 0, false
+	}
+
+	var next uint64
+	found := false
+	for _, s := range q.shards {
+		if t, ok := s.NextTry(); ok && (!found || t < next) {
+			next = t
+			found = true
+		}
+	}
+	return next, found
+}
+
+func (q *shardedJobQueue) Delete(completedJob jobqueue.Job) {
+	q.shardOfJob(completedJob).Delete(completedJob)
+}
+
+func (q *shardedJobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
+	q.shardOfJob(completedJob).Update(completedJob, next)
+}
+
+func (q *shardedJobQueue) shardOfJob(j jobqueue.Job) shard {
+	sj, ok := j.(*job)
+	if !ok || sj.shard >= uint(len(q.shards)) {
+		q.logger.Panic().Msgf("Invalid job structure: %v", j)
+	}
+	return q.shards[sj.shard]
+}
+
+// Recover returns orphan jobs in all the shards to the queue.
+func (q *shardedJobQueue) Recover() {
+	for _, s := range q.shards {
+		s.Recover()
+	}
+}
+
+func (q *shardedJobQueue) Inspector() jobqueue.Inspector {
+	return &shardedInspector{q.cores}
+}
+
+func (q *shardedJobQueue) FailureLog() jobqueue.FailureLog {
+	return &shardedFailureLog{q.cores}
+}
+
+func (q *shardedJobQueue) Node() (*jobqueue.Node, error) {
+	if q.activator != nil {
+		return activeNode(q.cores[0].db, q.activator.lockName())
+	}
+
+	for _, s := range q.shards {
+		if !s.IsActive() {
+			continue
+		}
+		if node, err := s.Node(); err != nil || node != nil {
+			return node, err
+		}
+	}
+	return nil, nil
+}
+
+// activation interface
+
+func (q *shardedJobQueue) queueName() string {
+	return q.name
+}
+
+func (q *shardedJobQueue) getDsn() string {
+	return q.dsn
+}
+
+func (q *shardedJobQueue) nodeURL() string {
+	return NodeURL()
+}
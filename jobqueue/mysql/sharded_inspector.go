@@ -0,0 +1,308 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/sqljq"
+)
+
+// A cursor of a sharded queue is composed of the cursors of the
+// shards which have more items.  It is a base64-encoded list of
+// `<shard>:<cursor>` separated by `;`.
+
+func decodeShardedCursor(cursor string, n int) []*string {
+	cursors := make([]*string, n)
+
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if cursor == "" || err != nil {
+		for i := range cursors {
+			start := ""
+			cursors[i] = &start
+		}
+		return cursors
+	}
+
+	for _, part := range strings.Split(string(decoded), ";") {
+		pair := strings.SplitN(part, ":", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		i, err := strconv.Atoi(pair[0])
+		if err != nil || i < 0 || i >= n {
+			continue
+		}
+		c := pair[1]
+		cursors[i] = &c
+	}
+	return cursors
+}
+
+func encodeShardedCursor(cursors []*string) string {
+	parts := make([]string, 0, len(cursors))
+	for i, c := range cursors {
+		if c != nil {
+			parts = append(parts, fmt.Sprintf("%d:%s", i, *c))
+		}
+	}
+	if len(parts) <= 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(parts, ";")))
+}
+
+// shardCursor makes a cursor of a shard which starts from an item.
+func shardCursor(t time.Time, id uint64) *string {
+	_, localID := splitShardedID(id)
+	c := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(
+		"%d,%d",
+		t.UnixNano()/int64(time.Millisecond),
+		localID,
+	)))
+	return &c
+}
+
+// mergeShards merges pages of shards, each of which is sorted, into
+// at most limit items.  It returns the pairs of a shard and an index
+// in the page of the shard in the merged order and the number of
+// items taken from each page.
+func mergeShards(lens []int, limit uint, before func(s1, k1, s2, k2 int) bool) ([][2]int, []int) {
+	taken := make([]int, len(lens))
+	merged := make([][2]int, 0, limit)
+	for uint(len(merged)) < limit {
+		best := -1
+		for s := range lens {
+			if taken[s] >= lens[s] {
+				continue
+			}
+			if best < 0 || before(s, taken[s], best, taken[best]) {
+				best = s
+			}
+		}
+		if best < 0 {
+			break
+		}
+		merged = append(merged, [2]int{best, taken[best]})
+		taken[best]++
+	}
+	return merged, taken
+}
+
+type shardedInspector struct {
+	cores []*jobQueue
+}
+
+func (i *shardedInspector) inspector(shard uint) *inspector {
+	return i.cores[shard].Inspector().(*inspector)
+}
+
+func (i *shardedInspector) Delete(jobID uint64) error {
+	shard, id := splitShardedID(jobID)
+	if shard >= uint(len(i.cores)) {
+		return nil
+	}
+	return i.inspector(shard).Delete(id)
+}
+
+func (i *shardedInspector) Find(jobID uint64) (*jobqueue.InspectedJob, error) {
+	shard, id := splitShardedID(jobID)
+	if shard >= uint(len(i.cores)) {
+		return nil, sql.ErrNoRows
+	}
+	j, err := i.inspector(shard).Find(id)
+	if err != nil {
+		return nil, err
+	}
+	j.ID = shardedID(shard, j.ID)
+	return j, nil
+}
+
+func (i *shardedInspector) FindAllGrabbed(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	return i.findAll(limit, cursor, order, (*inspector).FindAllGrabbed)
+}
+
+func (i *shardedInspector) FindAllWaiting(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	return i.findAll(limit, cursor, order, (*inspector).FindAllWaiting)
+}
+
+func (i *shardedInspector) FindAllDeferred(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	return i.findAll(limit, cursor, order, (*inspector).FindAllDeferred)
+}
+
+type findJobs func(i *inspector, limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error)
+
+func (i *shardedInspector) findAll(limit uint, cursor string, order jobqueue.SortOrder, find findJobs) (*jobqueue.InspectedJobs, error) {
+	cursors := decodeShardedCursor(cursor, len(i.cores))
+	pages := make([]*jobqueue.InspectedJobs, len(i.cores))
+	lens := make([]int, len(i.cores))
+
+	for s, c := range cursors {
+		if c == nil { // no more job
+			continue
+		}
+		page, err := find(i.inspector(uint(s)), limit, *c, order)
+		if err != nil {
+			return nil, err
+		}
+		for k := range page.Jobs {
+			page.Jobs[k].ID = shardedID(uint(s), page.Jobs[k].ID)
+		}
+		pages[s] = page
+		lens[s] = len(page.Jobs)
+	}
+
+	merged, taken := mergeShards(lens, limit, func(s1, k1, s2, k2 int) bool {
+		j1 := pages[s1].Jobs[k1]
+		j2 := pages[s2].Jobs[k2]
+		t1 := j1.NextTry.UnixNano()
+		t2 := j2.NextTry.UnixNano()
+		if order == jobqueue.Asc {
+			return t1 < t2 || (t1 == t2 && j1.ID < j2.ID)
+		}
+		return t1 > t2 || (t1 == t2 && j1.ID > j2.ID)
+	})
+
+	results := make([]jobqueue.InspectedJob, 0, len(merged))
+	for _, m := range merged {
+		results = append(results, pages[m[0]].Jobs[m[1]])
+	}
+
+	for s, page := range pages {
+		switch {
+		case page == nil:
+		case taken[s] < len(page.Jobs):
+			j := page.Jobs[taken[s]]
+			cursors[s] = shardCursor(j.NextTry, j.ID)
+		case page.NextCursor != "":
+			cursors[s] = &page.NextCursor
+		default:
+			cursors[s] = nil
+		}
+	}
+
+	return &jobqueue.InspectedJobs{Jobs: results, NextCursor: encodeShardedCursor(cursors)}, nil
+}
+
+type shardedFailureLog struct {
+	cores []*jobQueue
+}
+
+func (l *shardedFailureLog) failureLog(shard uint) *sqljq.FailureLog {
+	return l.cores[shard].FailureLog().(*sqljq.FailureLog)
+}
+
+func (l *shardedFailureLog) Add(failed jobqueue.Job, result *jobqueue.Result) error {
+	j, ok := failed.(*job)
+	if !ok || j.shard >= uint(len(l.cores)) {
+		return fmt.Errorf("Invalid job structure: %v", failed)
+	}
+	return l.failureLog(j.shard).Add(failed, result)
+}
+
+func (l *shardedFailureLog) Delete(failureID uint64) error {
+	shard, id := splitShardedID(failureID)
+	if shard >= uint(len(l.cores)) {
+		return nil
+	}
+	return l.failureLog(shard).Delete(id)
+}
+
+// Prune prunes failed jobs in each shard.  The retention applies to
+// each shard respectively.
+func (l *shardedFailureLog) Prune(failedBefore uint64, maxRows uint, limit uint) (uint, error) {
+	var total uint
+	for s := range l.cores {
+		if total >= limit {
+			break
+		}
+		n, err := l.failureLog(uint(s)).Prune(failedBefore, maxRows, limit-total)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (l *shardedFailureLog) Find(failureID uint64) (*jobqueue.FailedJob, error) {
+	shard, id := splitShardedID(failureID)
+	if shard >= uint(len(l.cores)) {
+		return nil, sql.ErrNoRows
+	}
+	j, err := l.failureLog(shard).Find(id)
+	if err != nil {
+		return nil, err
+	}
+	j.ID = shardedID(shard, j.ID)
+	j.JobID = shardedID(shard, j.JobID)
+	return j, nil
+}
+
+func (l *shardedFailureLog) FindAll(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	return l.findAll(limit, cursor, (*sqljq.FailureLog).FindAll, func(j1, j2 *jobqueue.FailedJob) bool {
+		t1 := j1.CreatedAt.UnixNano()
+		t2 := j2.CreatedAt.UnixNano()
+		return t1 > t2 || (t1 == t2 && j1.ID > j2.ID)
+	})
+}
+
+func (l *shardedFailureLog) FindAllRecentFailures(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	return l.findAll(limit, cursor, (*sqljq.FailureLog).FindAllRecentFailures, func(j1, j2 *jobqueue.FailedJob) bool {
+		t1 := j1.FailedAt.UnixNano()
+		t2 := j2.FailedAt.UnixNano()
+		return t1 > t2 || (t1 == t2 && j1.ID > j2.ID)
+	})
+}
+
+type findFailedJobs func(l *sqljq.FailureLog, limit uint, cursor string) (*jobqueue.FailedJobs, error)
+
+func (l *shardedFailureLog) findAll(limit uint, cursor string, find findFailedJobs, before func(j1, j2 *jobqueue.FailedJob) bool) (*jobqueue.FailedJobs, error) {
+	cursors := decodeShardedCursor(cursor, len(l.cores))
+	pages := make([]*jobqueue.FailedJobs, len(l.cores))
+	lens := make([]int, len(l.cores))
+
+	for s, c := range cursors {
+		if c == nil { // no more job
+			continue
+		}
+		page, err := find(l.failureLog(uint(s)), limit, *c)
+		if err != nil {
+			return nil, err
+		}
+		for k := range page.FailedJobs {
+			page.FailedJobs[k].ID = shardedID(uint(s), page.FailedJobs[k].ID)
+			page.FailedJobs[k].JobID = shardedID(uint(s), page.FailedJobs[k].JobID)
+		}
+		pages[s] = page
+		lens[s] = len(page.FailedJobs)
+	}
+
+	merged, taken := mergeShards(lens, limit, func(s1, k1, s2, k2 int) bool {
+		return before(&pages[s1].FailedJobs[k1], &pages[s2].FailedJobs[k2])
+	})
+
+	results := make([]jobqueue.FailedJob, 0, len(merged))
+	for _, m := range merged {
+		results = append(results, pages[m[0]].FailedJobs[m[1]])
+	}
+
+	for s, page := range pages {
+		switch {
+		case page == nil:
+		case taken[s] < len(page.FailedJobs):
+			j := page.FailedJobs[taken[s]]
+			cursors[s] = shardCursor(j.CreatedAt, j.ID)
+		case page.NextCursor != "":
+			cursors[s] = &page.NextCursor
+		default:
+			cursors[s] = nil
+		}
+	}
+
+	return &jobqueue.FailedJobs{FailedJobs: results, NextCursor: encodeShardedCursor(cursors)}, nil
+}
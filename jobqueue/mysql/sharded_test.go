@@ -0,0 +1,154 @@
+package mysql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/test/jobqueue"
+	"github.com/fireworq/fireworq/test/mysql"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestShardedSubtests(t *testing.T) {
+	jqtest.TestSubtests(t, runShardedSubtests)
+}
+
+func TestShardedInspector(t *testing.T) {
+	dsns := shardDsns(t)
+	for _, dsn := range dsns {
+		if err := mysqltest.TruncateTables(dsn); err != nil {
+			t.Error(err)
+		}
+	}
+
+	jq := NewSharded(&model.Queue{Name: "jobqueue_mysql_sharded_test", MaxWorkers: 30}, dsns)
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	pushed := make(map[uint64]bool)
+	for i := 0; i < 5; i++ {
+		j, err := jq.Push(jqtest.NewTestJob("job", "http://example.com/", "{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pushed[j.ToLoggable().ID()] = true
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	shards := make(map[uint]bool)
+	for id := range pushed {
+		shard, _ := splitShardedID(id)
+		shards[shard] = true
+	}
+	if len(shards) != 2 {
+		t.Error("Jobs should be distributed to all the shards")
+	}
+
+	inspector := jq.(jobqueue.HasInspector).Inspector()
+
+	var listed []jobqueue.InspectedJob
+	cursor := ""
+	for {
+		page, err := inspector.FindAllWaiting(2, cursor, jobqueue.Asc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Jobs) > 2 {
+			t.Errorf("Too many jobs in a page: %d", len(page.Jobs))
+		}
+		listed = append(listed, page.Jobs...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(listed) != len(pushed) {
+		t.Fatalf("Wrong number of listed jobs: %d", len(listed))
+	}
+	for k, j := range listed {
+		if !pushed[j.ID] {
+			t.Errorf("Unknown job: %d", j.ID)
+		}
+		if k > 0 && listed[k-1].NextTry.After(j.NextTry) {
+			t.Error("Jobs should be listed in order of their next try")
+		}
+	}
+
+	for id := range pushed {
+		j, err := inspector.Find(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.ID != id {
+			t.Errorf("Wrong job found: %d", j.ID)
+		}
+		if err := inspector.Delete(id); err != nil {
+			t.Error(err)
+		}
+		if _, err := inspector.Find(id); err == nil {
+			t.Error("A deleted job should not be found")
+		}
+	}
+}
+
+func TestShardedHash(t *testing.T) {
+	q := &shardedJobQueue{
+		policy: model.ShardPolicyHash,
+		shards: make([]shard, 4),
+	}
+	i, fixed := q.shardOf(jqtest.NewTestJob("job", "http://example.com/", "{}"))
+	if !fixed {
+		t.Error("A job should be put in a fixed shard by hashing")
+	}
+	for k := 0; k < 10; k++ {
+		if j, _ := q.shardOf(jqtest.NewTestJob("job", "http://example.com/", "{}")); j != i {
+			t.Error("Jobs of the same shard key should be put in the same shard")
+		}
+	}
+}
+
+func runShardedSubtests(t *testing.T, db, q string, tests []jqtest.Subtest) {
+	dsns := shardDsns(t)
+
+	jq := NewSharded(&model.Queue{Name: q, MaxWorkers: 30}, dsns)
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	for _, test := range tests {
+		for _, dsn := range dsns {
+			if err := mysqltest.TruncateTables(dsn); err != nil {
+				t.Error(err)
+			}
+		}
+		test(t, jq)
+	}
+}
+
+// shardDsns returns the DSNs of two shards, the second of which is a
+// database next to the test database.
+func shardDsns(t *testing.T) []string {
+	dsn := Dsn()
+
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DBName += "_shard1"
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE DATABASE IF NOT EXISTS `" + cfg.DBName + "`"); err != nil {
+		t.Fatal(err)
+	}
+
+	return []string{dsn, cfg.FormatDSN()}
+}
@@ -6,10 +6,12 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"regexp"
 	"strings"
 	"text/template"
 
+	"github.com/fireworq/fireworq/jobqueue/sqljq"
 	"github.com/fireworq/fireworq/model"
 )
 
@@ -30,24 +32,43 @@ type tableName struct {
 
 func (tn *tableName) makeQueries() *sqls {
 	return &sqls{
-		createJobqueue:     tn.makeQuery(tmplCreateJobqueue),
-		createFailure:      tn.makeQuery(tmplCreateFailure),
+		Queries: sqljq.Queries{
+			InsertFailedJob:    tn.makeQuery(tmplInsertFailedJob),
+			DeleteFailedJob:    tn.makeQuery(tmplDeleteFailedJob),
+			PruneFailedJobs:    tn.makeQuery(tmplPruneFailedJobs),
+			NthNewestFailure:   tn.makeQuery(tmplNthNewestFailure),
+			OldestFailures:     tn.makeQuery(tmplOldestFailures),
+			DeleteJob:          tn.makeQuery(tmplDeleteJob),
+			InspectJob:         tn.makeQuery(tmplInspectJob),
+			InspectJobs:        tn.makeQuery(tmplInspectJobs),
+			InspectJobsAsc:     tn.makeQuery(tmplInspectJobsAsc),
+			FailedJob:          tn.makeQuery(tmplFailedJob),
+			FailedJobs:         tn.makeQuery(tmplFailedJobs),
+			RecentlyFailedJobs: tn.makeQuery(tmplRecentlyFailedJobs),
+		},
+
+		createJobqueue: tn.makeQuery(tmplCreateJobqueue),
+		createFailure:  tn.makeQuery(tmplCreateFailure),
+		createNode:     tn.makeQuery(tmplCreateNode),
+		createGrabber:  tn.makeQuery(tmplCreateGrabber),
+		migrations: []migration{
+			{tn.JobQueue, "concurrency_key", tn.makeQuery(tmplAddConcurrencyKey)},
+			{tn.JobQueue, "group_id", tn.makeQuery(tmplAddGroupID)},
+			{tn.JobQueue, "dead_letter", tn.makeQuery(tmplAddDeadLetter)},
+		},
 		grab:               tn.makeQuery(tmplGrabJobs),
 		grabbed:            tn.makeQuery(tmplGrabbedJobs),
 		launch:             tn.makeQuery(tmplLaunchJobs),
+		launchByGrabber:    tn.makeQuery(tmplLaunchJobsByGrabber),
+		nextTry:            tn.makeQuery(tmplNextTry),
 		insertJob:          tn.makeQuery(tmplInsertJob),
-		insertFailedJob:    tn.makeQuery(tmplInsertFailedJob),
-		deleteFailedJob:    tn.makeQuery(tmplDeleteFailedJob),
-		deleteJob:          tn.makeQuery(tmplDeleteJob),
+		deleteGrabbedJob:   tn.makeQuery(tmplDeleteGrabbedJob),
 		updateJob:          tn.makeQuery(tmplUpdateJob),
+		updateGrabbedJob:   tn.makeQuery(tmplUpdateGrabbedJob),
 		orphan:             tn.makeQuery(tmplOrphanJobs),
 		recover:            tn.makeQuery(tmplRecoverJobs),
-		inspectJob:         tn.makeQuery(tmplInspectJob),
-		inspectJobs:        tn.makeQuery(tmplInspectJobs),
-		inspectJobsAsc:     tn.makeQuery(tmplInspectJobsAsc),
-		failedJob:          tn.makeQuery(tmplFailedJob),
-		failedJobs:         tn.makeQuery(tmplFailedJobs),
-		recentlyFailedJobs: tn.makeQuery(tmplRecentlyFailedJobs),
+		deadGrabberJobs:    tn.makeQuery(tmplDeadGrabberJobs),
+		recoverDeadGrabber: tn.makeQuery(tmplRecoverDeadGrabberJobs),
 	}
 }
 
@@ -57,47 +78,74 @@ func (tn *tableName) makeQuery(tmpl *template.Template) string {
 	return buffer.String()
 }
 
+// migration describes a column added to an existing table.  query
+// is executed only if the table lacks the column.
+type migration struct {
+	table  string
+	column string
+	query  string
+}
+
 type sqls struct {
+	sqljq.Queries
+
 	createJobqueue     string
 	createFailure      string
+	createNode         string
+	createGrabber      string
+	migrations         []migration
 	grab               string
 	grabbed            string
 	launch             string
+	launchByGrabber    string
+	nextTry            string
 	insertJob          string
-	insertFailedJob    string
-	deleteFailedJob    string
-	deleteJob          string
+	deleteGrabbedJob   string
 	updateJob          string
+	updateGrabbedJob   string
 	orphan             string
 	recover            string
-	inspectJob         string
-	inspectJobs        string
-	inspectJobsAsc     string
-	failedJob          string
-	failedJobs         string
-	recentlyFailedJobs string
+	deadGrabberJobs    string
+	recoverDeadGrabber string
 }
 
+// dialect appends the number of rows of a LIMIT clause to a query.
+var dialect = &sqljq.Dialect{InlineLimit: true, MaxID: math.MaxUint64}
+
 var (
-	invalidTablenameChars  *regexp.Regexp
-	tmplCreateJobqueue     *template.Template
-	tmplCreateFailure      *template.Template
-	tmplGrabJobs           *template.Template
-	tmplGrabbedJobs        *template.Template
-	tmplLaunchJobs         *template.Template
-	tmplInsertJob          *template.Template
-	tmplInsertFailedJob    *template.Template
-	tmplDeleteFailedJob    *template.Template
-	tmplDeleteJob          *template.Template
-	tmplUpdateJob          *template.Template
-	tmplOrphanJobs         *template.Template
-	tmplRecoverJobs        *template.Template
-	tmplInspectJob         *template.Template
-	tmplInspectJobs        *template.Template
-	tmplInspectJobsAsc     *template.Template
-	tmplFailedJob          *template.Template
-	tmplFailedJobs         *template.Template
-	tmplRecentlyFailedJobs *template.Template
+	invalidTablenameChars      *regexp.Regexp
+	tmplCreateJobqueue         *template.Template
+	tmplCreateFailure          *template.Template
+	tmplCreateNode             *template.Template
+	tmplCreateGrabber          *template.Template
+	tmplAddConcurrencyKey      *template.Template
+	tmplAddGroupID             *template.Template
+	tmplAddDeadLetter          *template.Template
+	tmplGrabJobs               *template.Template
+	tmplGrabbedJobs            *template.Template
+	tmplLaunchJobs             *template.Template
+	tmplLaunchJobsByGrabber    *template.Template
+	tmplNextTry                *template.Template
+	tmplInsertJob              *template.Template
+	tmplInsertFailedJob        *template.Template
+	tmplDeleteFailedJob        *template.Template
+	tmplPruneFailedJobs        *template.Template
+	tmplNthNewestFailure       *template.Template
+	tmplOldestFailures         *template.Template
+	tmplDeleteJob              *template.Template
+	tmplDeleteGrabbedJob       *template.Template
+	tmplUpdateJob              *template.Template
+	tmplUpdateGrabbedJob       *template.Template
+	tmplOrphanJobs             *template.Template
+	tmplRecoverJobs            *template.Template
+	tmplDeadGrabberJobs        *template.Template
+	tmplRecoverDeadGrabberJobs *template.Template
+	tmplInspectJob             *template.Template
+	tmplInspectJobs            *template.Template
+	tmplInspectJobsAsc         *template.Template
+	tmplFailedJob              *template.Template
+	tmplFailedJobs             *template.Template
+	tmplRecentlyFailedJobs     *template.Template
 )
 
 func mustLoadTemplate(name string) *template.Template {
@@ -122,16 +170,30 @@ func init() {
 	invalidTablenameChars = regexp.MustCompile("[^0-9a-z_]")
 	tmplCreateJobqueue = mustLoadTemplate("schema/job_queue")
 	tmplCreateFailure = mustLoadTemplate("schema/job_failure")
+	tmplCreateNode = mustLoadTemplate("schema/node")
+	tmplCreateGrabber = mustLoadTemplate("schema/grabber")
+	tmplAddConcurrencyKey = mustLoadTemplate("schema/job_queue_concurrency_key")
+	tmplAddGroupID = mustLoadTemplate("schema/job_queue_group_id")
+	tmplAddDeadLetter = mustLoadTemplate("schema/job_queue_dead_letter")
 	tmplGrabJobs = mustLoadTemplate("query/grab_jobs")
 	tmplGrabbedJobs = mustLoadTemplate("query/grabbed_jobs")
 	tmplLaunchJobs = mustLoadTemplate("query/launch_jobs")
+	tmplLaunchJobsByGrabber = mustLoadTemplate("query/launch_jobs_by_grabber")
+	tmplNextTry = mustLoadTemplate("query/next_try")
 	tmplInsertJob = mustLoadTemplate("query/insert_job")
 	tmplInsertFailedJob = mustLoadTemplate("query/insert_failed_job")
 	tmplDeleteFailedJob = mustLoadTemplate("query/delete_failed_job")
+	tmplPruneFailedJobs = mustLoadTemplate("query/prune_failed_jobs")
+	tmplNthNewestFailure = mustLoadTemplate("query/nth_newest_failure")
+	tmplOldestFailures = mustLoadTemplate("query/oldest_failures")
 	tmplDeleteJob = mustLoadTemplate("query/delete_job")
+	tmplDeleteGrabbedJob = mustLoadTemplate("query/delete_grabbed_job")
 	tmplUpdateJob = mustLoadTemplate("query/update_job")
+	tmplUpdateGrabbedJob = mustLoadTemplate("query/update_grabbed_job")
 	tmplOrphanJobs = mustLoadTemplate("query/orphan_jobs")
 	tmplRecoverJobs = mustLoadTemplate("query/recover_jobs")
+	tmplDeadGrabberJobs = mustLoadTemplate("query/dead_grabber_jobs")
+	tmplRecoverDeadGrabberJobs = mustLoadTemplate("query/recover_dead_grabber_jobs")
 	tmplInspectJob = mustLoadTemplate("query/inspect_job")
 	tmplInspectJobs = mustLoadTemplate("query/inspect_jobs")
 	tmplInspectJobsAsc = mustLoadTemplate("query/inspect_jobs_asc")
@@ -4,6 +4,7 @@ package jobqueue
 type Node struct {
 	ID   string `json:"id"`
 	Host string `json:"host"`
+	URL  string `json:"url,omitempty"` // where other nodes reach the API of the node
 }
 
 // HasNodeInfo is an interface describing that it has a Node information.
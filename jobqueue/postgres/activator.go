@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	activatorActivationInterval = 1 * time.Second
+)
+
+// activatorLockClass is the first key of advisory locks taken by
+// activators, which distinguishes them from advisory locks taken by
+// other applications sharing the database.  The second key is the
+// hash of the lock name.
+const activatorLockClass = 0x66697265 // "fire"
+
+type activator struct {
+	queueName  string
+	cancel     atomic.Value
+	stoppedC   chan struct{}
+	stopped    uint32
+	active     int32
+	db         *sql.DB
+	dsn        string
+	nodeURL    string
+	registered bool
+	logger     zerolog.Logger
+}
+
+type activation interface {
+	queueName() string
+	getDsn() string
+	nodeURL() string
+}
+
+func startActivator(q activation, onActivating func()) *activator {
+	a := &activator{
+		queueName: q.queueName(),
+		dsn:       q.getDsn(),
+		nodeURL:   q.nodeURL(),
+		stoppedC:  make(chan struct{}),
+		logger:    log.With().Str("queue", q.queueName()).Logger(),
+		active:    -1,
+	}
+	go a.loop(onActivating)
+
+	return a
+}
+
+func (a *activator) stop() <-chan struct{} {
+	atomic.StoreUint32(&a.stopped, 1)
+
+	if cancel := a.cancel.Load(); cancel != nil {
+		cancel.(context.CancelFunc)()
+	}
+
+	return a.stoppedC
+}
+
+func (a *activator) isActive() bool {
+	return atomic.LoadInt32(&a.active) > 0
+}
+
+func (a *activator) lockName() string {
+	return fmt.Sprintf("fireworq_jq(%s)", a.queueName)
+}
+
+// File private methods
+
+func (a *activator) loop(onActivating func()) {
+	ticker := time.NewTicker(activatorActivationInterval)
+	for a.activate(onActivating) {
+		<-ticker.C
+	}
+	ticker.Stop()
+
+	atomic.StoreInt32(&a.active, 0)
+	a.disconnect()
+	a.stoppedC <- struct{}{}
+}
+
+func (a *activator) activate(onActivating func()) (shouldRetry bool) {
+	if atomic.LoadUint32(&a.stopped) > 0 {
+		return false
+	}
+
+	if err := a.connect(); err != nil {
+		// This should not happen since sql.Open() won't try to
+		// connect to the DB and won't fail unless the DB driver name
+		// is invalid.  We just try again in case sql.Open() changes
+		// the behavior in future.
+		a.logger.Error().Msgf("(activator) %s", err)
+		return true
+	}
+
+	if a.hasLock() {
+		// Make sure that the queue is active since we have the lock.
+		// Without doing this, the queue won't be activated if the
+		// former call of getLock() failed to receive packets from the
+		// DB but the lock had actually been taken by the DB.
+		if atomic.SwapInt32(&a.active, 1) <= 0 {
+			a.logger.Info().Msg("The node is now in PRIMARY mode")
+		}
+		return true
+	}
+
+	if atomic.SwapInt32(&a.active, 0) != 0 {
+		a.logger.Info().Msg("The node is now in BACKUP mode")
+	}
+	a.logger.Debug().Msg("Queue (re)activating...")
+
+	if err := a.getLock(); err != nil {
+		if _, ok := err.(*stoppedError); ok {
+			return false
+		} else if _, ok := err.(*lockTimeoutError); ok {
+			// The lock is held by another node; just try again.
+			a.logger.Debug().Msg(err.Error())
+		} else {
+			// Connection failed (maybe DB server down).
+			// Try to reconnect later.
+			a.disconnect()
+			a.logger.Error().Msgf("(activator) %s", err)
+		}
+
+		return true
+	}
+
+	a.logger.Info().Msg("Switching to PRIMARY mode...")
+
+	a.register()
+	onActivating()
+	atomic.StoreInt32(&a.active, 1)
+
+	a.logger.Debug().Msg("Queue activated")
+	a.logger.Info().Msg("The node is now in PRIMARY mode")
+
+	return true
+}
+
+func (a *activator) connect() error {
+	if a.db != nil {
+		return nil
+	}
+
+	db, err := sql.Open("postgres", a.dsn)
+	if err != nil {
+		return err
+	}
+	// An advisory lock belongs to a session.
+	db.SetMaxOpenConns(1)
+	a.db = db
+
+	return nil
+}
+
+func (a *activator) disconnect() {
+	if a.db == nil {
+		return
+	}
+	a.unregister()
+	a.db.Close()
+	a.db = nil
+}
+
+func (a *activator) hasLock() bool {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND pid = pg_backend_pid()
+			  AND classid = $1 AND objid = hashtext($2)::oid AND objsubid = 2
+			  AND granted
+		)
+	`
+
+	var hasLock bool
+	if err := a.db.QueryRow(query, activatorLockClass, a.lockName()).Scan(&hasLock); err != nil {
+		return false
+	}
+
+	return hasLock
+}
+
+// register records the URL of the node for the connection holding
+// the lock so that other nodes can notify the node of pushed jobs.
+func (a *activator) register() {
+	if a.nodeURL == "" {
+		return
+	}
+
+	if _, err := a.db.Exec(`
+		INSERT INTO fireworq_node (connection_id, url) VALUES (pg_backend_pid(), $1)
+		ON CONFLICT (connection_id) DO UPDATE SET url = EXCLUDED.url
+	`, a.nodeURL); err != nil {
+		a.logger.Error().Msgf("(activator) Failed to register the node: %s", err)
+		return
+	}
+	a.registered = true
+}
+
+func (a *activator) unregister() {
+	if !a.registered {
+		return
+	}
+	a.registered = false
+
+	// Failure is harmless; a stale row is never looked up since it
+	// is keyed by a connection which no longer holds the lock.
+	a.db.Exec("DELETE FROM fireworq_node WHERE connection_id = pg_backend_pid()")
+}
+
+func (a *activator) getLock() error {
+	err := func() error {
+		ctx, cancel := context.WithCancel(context.Background())
+		a.cancel.Store(cancel)
+
+		if atomic.LoadUint32(&a.stopped) > 0 {
+			return &stoppedError{}
+		}
+
+		// Unlike `GET_LOCK` of MySQL, `pg_advisory_lock` cannot
+		// time out by itself.  Just try it in every activation
+		// interval instead.
+		var locked bool
+		if err := a.db.QueryRowContext(
+			ctx,
+			"SELECT pg_try_advisory_lock($1, hashtext($2))",
+			activatorLockClass,
+			a.lockName(),
+		).Scan(&locked); err != nil {
+			return err
+		}
+
+		if !locked {
+			return &lockTimeoutError{}
+		}
+
+		return nil
+	}()
+
+	if err == context.Canceled && atomic.LoadUint32(&a.stopped) > 0 {
+		return &stoppedError{}
+	}
+	return err
+}
+
+type lockTimeoutError struct{}
+
+func (err *lockTimeoutError) Error() string {
+	return "Lock is held by another node"
+}
+
+type stoppedError struct{}
+
+func (err *stoppedError) Error() string {
+	return "The activator has already been stopped"
+}
@@ -0,0 +1,315 @@
+package postgres
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	_ "github.com/lib/pq" // initialize the driver
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/sqljq"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+	"github.com/fireworq/fireworq/model"
+)
+
+// Dsn returns the data source name of the storage specified in the
+// configuration.
+func Dsn() string {
+	dsn := config.Get("queue_postgres_dsn")
+	if dsn != "" {
+		return dsn
+	}
+	return config.Get("postgres_dsn")
+}
+
+// NodeURL returns the URL at which other nodes reach the API of this
+// node, which is specified in the configuration.
+func NodeURL() string {
+	return config.Get("node_url")
+}
+
+type jobQueue struct {
+	name    string
+	dsn     string
+	sql     *sqls
+	db      *sql.DB
+	dbPop   *sql.DB
+	mu      sync.RWMutex
+	stopped uint32
+	logger  zerolog.Logger
+}
+
+// New creates a jobqueue.Impl which uses PostgreSQL as a data store.
+func New(definition *model.Queue, dsn string) jobqueue.Impl {
+	return newJobQueue(definition, dsn)
+}
+
+func newJobQueue(definition *model.Queue, dsn string) *jobQueue {
+	tableName := newTableName(definition)
+	return &jobQueue{
+		name:   definition.Name,
+		dsn:    dsn,
+		sql:    tableName.makeQueries(),
+		logger: log.With().Str("queue", definition.Name).Logger(),
+	}
+}
+
+func (q *jobQueue) Start() {
+	log := q.logger.With().Str("method", "Start").Logger()
+
+	db, err := sql.Open("postgres", q.dsn)
+	if err != nil {
+		log.Panic().Msgf("Cannot open DB: %s", err)
+	}
+	q.db = db
+
+	_, err = q.db.Exec(q.sql.createJobqueue)
+	if err != nil {
+		log.Panic().Msgf("Failed to create queue table: %s", err)
+	}
+
+	_, err = q.db.Exec(q.sql.createFailure)
+	if err != nil {
+		log.Panic().Msgf("Failed to create queue failure log table: %s", err)
+	}
+
+	_, err = q.db.Exec(q.sql.createNode)
+	if err != nil {
+		log.Panic().Msgf("Failed to create node table: %s", err)
+	}
+
+	q.connect()
+}
+
+func (q *jobQueue) Stop() <-chan struct{} {
+	atomic.StoreUint32(&q.stopped, 1)
+
+	stopped := make(chan struct{})
+	go func() {
+		q.disconnect()
+		q.db.Close()
+		stopped <- struct{}{}
+	}()
+	return stopped
+}
+
+func (q *jobQueue) IsActive() bool {
+	return true
+}
+
+func (q *jobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
+	log := q.logger.With().Str("method", "Push").Logger()
+
+	job := &stored.IncomingJob{IncomingJob: j}
+
+	if err := q.db.QueryRow(
+		q.sql.insertJob,
+		job.NextDelay(),
+		job.RetryCount(),
+		job.RetryDelay(),
+		job.FailCount(),
+		job.Category(),
+		job.URL(),
+		job.Payload(),
+		job.Timeout(),
+		job.ConcurrencyKey(),
+		job.ConcurrencyLimit(),
+		job.GroupID(),
+		job.IsDeadLetter(),
+	).Scan(&job.JobID); err != nil {
+		log.Debug().Msgf("Failed to insert a job: %s", err)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
+	log := q.logger.With().Str("method", "Pop").Logger()
+
+	if !q.IsActive() {
+		return nil, &jobqueue.InactiveError{}
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.dbPop == nil {
+		return nil, &jobqueue.ConnectionClosedError{}
+	}
+
+	// Jobs locked by another thread are skipped rather than waited
+	// for, so that grabbing them never blocks `Push()` nor another
+	// `Pop()`.
+	rows, err := q.dbPop.Query(q.sql.grab, limit)
+	if err != nil {
+		log.Debug().Msgf("Failed to grab jobs: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]jobqueue.Job, 0, limit)
+	for rows.Next() {
+		j, err := stored.ScanJob(rows)
+		if err != nil {
+			log.Debug().Msgf("Failed to scan grabbed jobs: %s", err)
+			return nil, err
+		}
+		results = append(results, j)
+	}
+	if err := rows.Err(); err != nil {
+		log.Debug().Msgf("Failed to read grabbed jobs: %s", err)
+		return nil, err
+	}
+
+	// `RETURNING` doesn't preserve the order of the subquery.
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].(*stored.Job).NextTry() < results[j].(*stored.Job).NextTry()
+	})
+
+	return results, nil
+}
+
+// NextTry returns the time when the earliest deferred job becomes due.
+func (q *jobQueue) NextTry() (uint64, bool) {
+	log := q.logger.With().Str("method", "NextTry").Logger()
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.dbPop == nil {
+		return 0, false
+	}
+
+	var next sql.NullInt64
+	if err := q.dbPop.QueryRow(q.sql.nextTry).Scan(&next); err != nil {
+		log.Debug().Msgf("Failed to select the next try: %s", err)
+		return 0, false
+	}
+	if !next.Valid {
+		return 0, false
+	}
+	return uint64(next.Int64), true
+}
+
+func (q *jobQueue) Delete(completedJob jobqueue.Job) {
+	log := q.logger.With().Str("method", "Delete").Logger()
+
+	j, ok := completedJob.(*stored.Job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	if _, err := q.db.Exec(q.sql.DeleteJob, j.RowID()); err != nil {
+		log.Error().Msgf("Failed to delete a job: %s", err)
+	}
+}
+
+func (q *jobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
+	log := q.logger.With().Str("method", "Update").Logger()
+
+	j, ok := completedJob.(*stored.Job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	if _, err := q.db.Exec(
+		q.sql.updateJob,
+		next.NextDelay(),
+		next.RetryCount(),
+		next.FailCount(),
+		j.RowID(),
+	); err != nil {
+		log.Error().Msgf("Failed to update a job: %s", err)
+	}
+}
+
+func (q *jobQueue) Recover() {
+	log := q.logger.With().Str("method", "Recover").Logger()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.dbPop == nil {
+		return
+	}
+
+	var recovered int64
+	for {
+		log.Info().Msgf("Recovering orphan jobs...")
+
+		res, err := q.dbPop.Exec(q.sql.recover)
+		if err != nil {
+			log.Error().Msgf("Failed to recover orphan jobs: %s", err)
+			return
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			log.Error().Msgf("Failed to count recovered jobs: %s", err)
+			return
+		}
+		if n <= 0 {
+			log.Info().Msgf("Recovering complete: %d job(s) recovered", recovered)
+			return
+		}
+
+		recovered += n
+	}
+}
+
+func (q *jobQueue) Inspector() jobqueue.Inspector {
+	return sqljq.NewInspector(q.db, &q.sql.Queries, dialect)
+}
+
+func (q *jobQueue) FailureLog() jobqueue.FailureLog {
+	return sqljq.NewFailureLog(q.db, &q.sql.Queries, dialect)
+}
+
+func (q *jobQueue) Node() (*jobqueue.Node, error) {
+	// `client_addr` is NULL for a connection over a Unix domain
+	// socket, which is always from the local host.
+	query := `
+		SELECT pid, COALESCE(host(client_addr), 'localhost') FROM pg_stat_activity
+		WHERE pid = pg_backend_pid()
+	`
+
+	var node jobqueue.Node
+	err := q.db.QueryRow(query).Scan(&node.ID, &node.Host)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func (q *jobQueue) connect() {
+	log := q.logger.With().Str("method", "activate").Logger()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dbPop, err := sql.Open("postgres", q.dsn)
+	if err != nil {
+		log.Panic().Msgf("Cannot open DB: %s", err)
+	}
+	// Restrict connections to prevent the backend PID from being
+	// changed.
+	dbPop.SetMaxOpenConns(1)
+	q.dbPop = dbPop
+}
+
+func (q *jobQueue) disconnect() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.dbPop != nil {
+		q.dbPop.Close()
+		q.dbPop = nil
+	}
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+type primaryBackupJobQueue struct {
+	*jobQueue
+	activator *activator
+}
+
+// NewPrimaryBackup creates a jobqueue.Impl which uses PostgreSQL as a
+// data store and restricts only one node to be active in a cluster.
+//
+// Inactive nodes become backup nodes, which will be active when the
+// active node dies.
+func NewPrimaryBackup(definition *model.Queue, dsn string) jobqueue.Impl {
+	q := newJobQueue(definition, dsn)
+	return &primaryBackupJobQueue{q, nil}
+}
+
+func (q *primaryBackupJobQueue) Start() {
+	q.jobQueue.Start()
+	q.activator = startActivator(
+		q,
+		q.Recover,
+	)
+}
+
+func (q *primaryBackupJobQueue) Stop() <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		<-q.activator.stop()
+		<-q.jobQueue.Stop()
+		stopped <- struct{}{}
+	}()
+	return stopped
+}
+
+func (q *primaryBackupJobQueue) IsActive() bool {
+	return q.activator.isActive()
+}
+
+func (q *primaryBackupJobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
+	if !q.IsActive() {
+		return nil, &jobqueue.InactiveError{}
+	}
+
+	return q.jobQueue.Pop(limit)
+}
+
+func (q *primaryBackupJobQueue) NextTry() (uint64, bool) {
+	if !q.IsActive() {
+		return 0, false
+	}
+
+	return q.jobQueue.NextTry()
+}
+
+func (q *primaryBackupJobQueue) Node() (*jobqueue.Node, error) {
+	query := `
+		SELECT a.pid, COALESCE(host(a.client_addr), 'localhost'), COALESCE(n.url, '')
+		FROM pg_locks AS l
+		JOIN pg_stat_activity AS a ON a.pid = l.pid
+		LEFT JOIN fireworq_node AS n ON n.connection_id = l.pid
+		WHERE l.locktype = 'advisory'
+		  AND l.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+		  AND l.classid = $1 AND l.objid = hashtext($2)::oid AND l.objsubid = 2
+		  AND l.granted
+	`
+
+	var node jobqueue.Node
+	err := q.db.QueryRow(query, activatorLockClass, q.activator.lockName()).Scan(&node.ID, &node.Host, &node.URL)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+// activation interface
+
+func (q *primaryBackupJobQueue) queueName() string {
+	return q.name
+}
+
+func (q *primaryBackupJobQueue) getDsn() string {
+	return q.dsn
+}
+
+func (q *primaryBackupJobQueue) nodeURL() string {
+	return NodeURL()
+}
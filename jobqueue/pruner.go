@@ -0,0 +1,126 @@
+package jobqueue
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	failurePruneInterval       = 1 * time.Minute
+	failurePruneBatchSize uint = 1000
+)
+
+// FailurePruner is an interface of a FailureLog which can delete old
+// failed jobs.
+type FailurePruner interface {
+	// Prune deletes at most limit failed jobs, oldest first, which
+	// failed before failedBefore milliseconds since the epoch or are
+	// older than the newest maxRows ones.  Zero disables each
+	// condition.  It returns the number of deleted failed jobs.
+	Prune(failedBefore uint64, maxRows uint, limit uint) (uint, error)
+}
+
+// PruningNodeElector is an interface of a job queue implementation
+// consumed by multiple nodes at once, which elects one of them to
+// prune failed jobs.
+type PruningNodeElector interface {
+	// IsPruningNode returns true if the node is elected to prune
+	// failed jobs of the queue.
+	IsPruningNode() bool
+}
+
+// pruner deletes failed jobs beyond the retention of a queue
+// periodically while the node is in charge of the queue.
+type pruner struct {
+	q        *jobQueue
+	maxAge   time.Duration
+	maxRows  uint
+	stopped  uint32
+	stopC    chan struct{}
+	stoppedC chan struct{}
+	logger   zerolog.Logger
+}
+
+func startPruner(q *jobQueue, maxAge time.Duration, maxRows uint) *pruner {
+	p := &pruner{
+		q:        q,
+		maxAge:   maxAge,
+		maxRows:  maxRows,
+		stopC:    make(chan struct{}),
+		stoppedC: make(chan struct{}),
+		logger:   log.With().Str("queue", q.name).Logger(),
+	}
+	go p.loop()
+	return p
+}
+
+func (p *pruner) stop() <-chan struct{} {
+	atomic.StoreUint32(&p.stopped, 1)
+	close(p.stopC)
+	return p.stoppedC
+}
+
+func (p *pruner) loop() {
+	ticker := time.NewTicker(failurePruneInterval)
+Loop:
+	for {
+		select {
+		case <-ticker.C:
+			p.prune()
+		case <-p.stopC:
+			break Loop
+		}
+	}
+	ticker.Stop()
+	p.stoppedC <- struct{}{}
+}
+
+func (p *pruner) prune() {
+	if !p.isPruningNode() {
+		return
+	}
+	failureLog, ok := p.q.FailureLog()
+	if !ok {
+		return
+	}
+	prunable, ok := failureLog.(FailurePruner)
+	if !ok {
+		return
+	}
+
+	var failedBefore uint64
+	if p.maxAge > 0 {
+		failedBefore = uint64(time.Now().Add(-p.maxAge).UnixNano() / int64(time.Millisecond))
+	}
+
+	var total uint
+	for atomic.LoadUint32(&p.stopped) == 0 {
+		n, err := prunable.Prune(failedBefore, p.maxRows, failurePruneBatchSize)
+		if err != nil {
+			p.logger.Error().Msgf("Failed to prune failed jobs: %s", err)
+			break
+		}
+		total += n
+		p.q.stats.pruneFailures(int64(n))
+		if n < failurePruneBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		p.logger.Info().Msgf("Pruned %d failed job(s)", total)
+	}
+}
+
+// isPruningNode returns true if the node should prune failed jobs.
+// Only the active node prunes them unless the queue elects one of the
+// nodes consuming it at once.
+func (p *pruner) isPruningNode() bool {
+	if elector, ok := p.q.impl.(PruningNodeElector); ok {
+		return elector.IsPruningNode()
+	}
+	return p.q.IsActive()
+}
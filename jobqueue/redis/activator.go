@@ -0,0 +1,161 @@
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+var (
+	activatorActivationInterval = 1 * time.Second
+	activatorLeaseDuration      = 5 * time.Second
+)
+
+// activator holds a lease on a key as long as the node is active.
+// The lease expires unless it is renewed in time, so that a backup
+// node takes it over when the active node dies.
+type activator struct {
+	queueName string
+	stoppedC  chan struct{}
+	stopped   uint32
+	active    int32
+	pool      *redis.Pool
+	key       string
+	holder    string
+	logger    zerolog.Logger
+}
+
+type activation interface {
+	queueName() string
+	getPool() *redis.Pool
+	leaseKey() string
+	nodeURL() string
+}
+
+func startActivator(q activation, onActivating func()) *activator {
+	a := &activator{
+		queueName: q.queueName(),
+		pool:      q.getPool(),
+		key:       q.leaseKey(),
+		holder:    newHolder(q.nodeURL()),
+		stoppedC:  make(chan struct{}),
+		logger:    log.With().Str("queue", q.queueName()).Logger(),
+		active:    -1,
+	}
+	go a.loop(onActivating)
+
+	return a
+}
+
+// newHolder describes the node as a value of the lease.  The ID
+// distinguishes queues on the same host.
+func newHolder(url string) string {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+
+	holder, _ := json.Marshal(&jobqueue.Node{
+		ID:   hex.EncodeToString(id),
+		Host: host,
+		URL:  url,
+	})
+	return string(holder)
+}
+
+func (a *activator) stop() <-chan struct{} {
+	atomic.StoreUint32(&a.stopped, 1)
+	return a.stoppedC
+}
+
+func (a *activator) isActive() bool {
+	return atomic.LoadInt32(&a.active) > 0
+}
+
+// File private methods
+
+func (a *activator) loop(onActivating func()) {
+	ticker := time.NewTicker(activatorActivationInterval)
+	for a.activate(onActivating) {
+		<-ticker.C
+	}
+	ticker.Stop()
+
+	atomic.StoreInt32(&a.active, 0)
+	a.release()
+	a.stoppedC <- struct{}{}
+}
+
+func (a *activator) activate(onActivating func()) (shouldRetry bool) {
+	if atomic.LoadUint32(&a.stopped) > 0 {
+		return false
+	}
+
+	conn := a.pool.Get()
+	defer conn.Close()
+
+	renewed, err := redis.Bool(scriptRenewLease.Do(
+		conn,
+		a.key,
+		a.holder,
+		int64(activatorLeaseDuration/time.Millisecond),
+	))
+	if err == nil && renewed {
+		if atomic.SwapInt32(&a.active, 1) <= 0 {
+			a.logger.Info().Msg("The node is now in PRIMARY mode")
+		}
+		return true
+	}
+
+	if atomic.SwapInt32(&a.active, 0) != 0 {
+		a.logger.Info().Msg("The node is now in BACKUP mode")
+	}
+	a.logger.Debug().Msg("Queue (re)activating...")
+
+	if _, err := redis.String(conn.Do(
+		"SET",
+		a.key,
+		a.holder,
+		"NX",
+		"PX", int64(activatorLeaseDuration/time.Millisecond),
+	)); err == redis.ErrNil {
+		// The lease is held by another node; just try again.
+		a.logger.Debug().Msg("Lease is held by another node")
+		return true
+	} else if err != nil {
+		// Connection failed (maybe Redis server down).  Try again
+		// later.
+		a.logger.Error().Msgf("(activator) %s", err)
+		return true
+	}
+
+	a.logger.Info().Msg("Switching to PRIMARY mode...")
+
+	onActivating()
+	atomic.StoreInt32(&a.active, 1)
+
+	a.logger.Debug().Msg("Queue activated")
+	a.logger.Info().Msg("The node is now in PRIMARY mode")
+
+	return true
+}
+
+func (a *activator) release() {
+	conn := a.pool.Get()
+	defer conn.Close()
+
+	// Failure is harmless; the lease expires soon.
+	scriptReleaseLease.Do(conn, a.key, a.holder)
+}
@@ -0,0 +1,167 @@
+package redis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+)
+
+type failureLog struct {
+	pool *redis.Pool
+	keys *keys
+}
+
+func (l *failureLog) Add(failed jobqueue.Job, result *jobqueue.Result) error {
+	log := log.With().Str("method", "failureLog.Add").Logger()
+
+	j, ok := failed.(*stored.Job)
+	if !ok {
+		return fmt.Errorf("Invalid job structure: %v", failed)
+	}
+
+	res, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	if _, err = scriptAddFailure.Do(
+		conn,
+		l.keys.prefix,
+		j.RowID(),
+		j.Category(),
+		failed.URL(),
+		failed.Payload(),
+		string(res),
+		failed.FailCount()+1,
+		stored.NowMillisecond(),
+		j.CreatedAt(),
+	); err != nil {
+		log.Debug().Msgf("Failed to add a job: %s", err)
+	}
+
+	return err
+}
+
+func (l *failureLog) Delete(failureID uint64) error {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	_, err := scriptDeleteFailure.Do(conn, l.keys.prefix, failureID)
+	return err
+}
+
+func (l *failureLog) Prune(failedBefore uint64, maxRows uint, limit uint) (uint, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	n, err := redis.Int(scriptPruneFailures.Do(conn, l.keys.prefix, failedBefore, maxRows, limit))
+	if err != nil {
+		return 0, err
+	}
+	return uint(n), nil
+}
+
+func (l *failureLog) Find(failureID uint64) (*jobqueue.FailedJob, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	fields, err := redis.StringMap(conn.Do("HGETALL", l.keys.failure(failureID)))
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) <= 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return l.scan(entry{id: failureID, fields: fields})
+}
+
+func (l *failureLog) FindAll(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	var maxTime int64 = math.MaxInt64
+	maxMember := member(math.MaxUint64)
+	if t, id, ok := stored.DecodeCursor(cursor); ok {
+		maxTime = t
+		maxMember = member(id)
+	}
+
+	return l.findAll(l.keys.failuresByCreation(), maxTime, maxMember, limit)
+}
+
+func (l *failureLog) FindAllRecentFailures(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	var maxID uint64 = math.MaxInt64
+	if _, id, ok := stored.DecodeCursor(cursor); ok {
+		maxID = id
+	}
+
+	// The index is scored by failure IDs.
+	return l.findAll(l.keys.recentFailures(), int64(maxID), member(maxID), limit)
+}
+
+func (l *failureLog) findAll(index string, from int64, fromMember string, limit uint) (*jobqueue.FailedJobs, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	found, err := entries(scriptPage.Do(
+		conn,
+		index,
+		l.keys.failures(),
+		from,
+		fromMember,
+		0,
+		limit+1,
+		"desc",
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]jobqueue.FailedJob, 0, limit+1)
+	for _, e := range found {
+		j, err := l.scan(e)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *j)
+	}
+
+	nextCursor := ""
+	if uint(len(results)) > limit {
+		nextCursor = stored.EncodeCursor(
+			results[limit].CreatedAt.UnixNano()/int64(time.Millisecond),
+			results[limit].ID,
+		)
+		results = results[:limit]
+	}
+
+	return &jobqueue.FailedJobs{FailedJobs: results, NextCursor: nextCursor}, nil
+}
+
+func (l *failureLog) scan(e entry) (*jobqueue.FailedJob, error) {
+	p := &fieldParser{fields: e.fields}
+	f := stored.Failure{
+		ID:        e.id,
+		JobID:     p.uint64("job_id"),
+		Category:  e.fields["category"],
+		URL:       e.fields["url"],
+		Payload:   e.fields["payload"],
+		Result:    e.fields["result"],
+		FailCount: p.uint("fail_count"),
+		FailedAt:  p.uint64("failed_at"),
+		CreatedAt: p.uint64("created_at"),
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return f.FailedJob()
+}
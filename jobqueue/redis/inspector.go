@@ -0,0 +1,133 @@
+package redis
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+)
+
+type inspector struct {
+	pool *redis.Pool
+	keys *keys
+}
+
+func (i *inspector) Delete(jobID uint64) error {
+	conn := i.pool.Get()
+	defer conn.Close()
+
+	_, err := scriptDelete.Do(conn, i.keys.prefix, jobID)
+	return err
+}
+
+func (i *inspector) Find(jobID uint64) (*jobqueue.InspectedJob, error) {
+	conn := i.pool.Get()
+	defer conn.Close()
+
+	fields, err := redis.StringMap(conn.Do("HGETALL", i.keys.job(jobID)))
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) <= 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return i.inspect(entry{id: jobID, fields: fields})
+}
+
+func (i *inspector) FindAllGrabbed(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	var maxTime = time.Now().UnixNano() / int64(time.Millisecond)
+	if order == jobqueue.Asc {
+		return i.findAllAsc(i.keys.grabbed(), 0, maxTime, limit, cursor)
+	}
+	return i.findAllDesc(i.keys.grabbed(), 0, maxTime, limit, cursor)
+}
+
+func (i *inspector) FindAllWaiting(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	var maxTime = time.Now().UnixNano() / int64(time.Millisecond)
+	if order == jobqueue.Asc {
+		return i.findAllAsc(i.keys.claimed(), 0, maxTime, limit, cursor)
+	}
+	return i.findAllDesc(i.keys.claimed(), 0, maxTime, limit, cursor)
+}
+
+func (i *inspector) FindAllDeferred(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	var minTime = time.Now().UnixNano() / int64(time.Millisecond)
+	if order == jobqueue.Asc {
+		return i.findAllAsc(i.keys.claimed(), minTime, math.MaxInt64, limit, cursor)
+	}
+	return i.findAllDesc(i.keys.claimed(), minTime, math.MaxInt64, limit, cursor)
+}
+
+func (i *inspector) findAllAsc(index string, minTime int64, maxTime int64, limit uint, cursor string) (*jobqueue.InspectedJobs, error) {
+	from := minTime
+	fromMember := ""
+	if t, id, ok := stored.DecodeCursor(cursor); ok {
+		from = t
+		fromMember = member(id)
+	}
+
+	return i.findAll(index, "asc", from, fromMember, maxTime, limit)
+}
+
+func (i *inspector) findAllDesc(index string, minTime int64, maxTime int64, limit uint, cursor string) (*jobqueue.InspectedJobs, error) {
+	from := maxTime
+	fromMember := member(math.MaxUint64)
+	if t, id, ok := stored.DecodeCursor(cursor); ok {
+		from = t
+		fromMember = member(id)
+	}
+
+	return i.findAll(index, "desc", from, fromMember, minTime, limit)
+}
+
+func (i *inspector) findAll(index, order string, from int64, fromMember string, bound int64, limit uint) (*jobqueue.InspectedJobs, error) {
+	conn := i.pool.Get()
+	defer conn.Close()
+
+	found, err := entries(scriptPage.Do(
+		conn,
+		index,
+		i.keys.jobs(),
+		from,
+		fromMember,
+		bound,
+		limit+1,
+		order,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]jobqueue.InspectedJob, 0, limit+1)
+	for _, e := range found {
+		j, err := i.inspect(e)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *j)
+	}
+
+	nextCursor := ""
+	if uint(len(results)) > limit {
+		nextCursor = stored.EncodeCursor(
+			results[limit].NextTry.UnixNano()/int64(time.Millisecond),
+			results[limit].ID,
+		)
+		results = results[:limit]
+	}
+
+	return &jobqueue.InspectedJobs{Jobs: results, NextCursor: nextCursor}, nil
+}
+
+func (i *inspector) inspect(e entry) (*jobqueue.InspectedJob, error) {
+	j, err := newJob(e)
+	if err != nil {
+		return nil, err
+	}
+	return j.Inspect(), nil
+}
@@ -0,0 +1,263 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+	"github.com/fireworq/fireworq/model"
+)
+
+// URL returns the URL of the Redis server specified in the
+// configuration.
+func URL() string {
+	return config.Get("queue_redis_url")
+}
+
+// NodeURL returns the URL at which other nodes reach the API of this
+// node, which is specified in the configuration.
+func NodeURL() string {
+	return config.Get("node_url")
+}
+
+type jobQueue struct {
+	name   string
+	url    string
+	keys   *keys
+	pool   *redis.Pool
+	logger zerolog.Logger
+}
+
+// New creates a jobqueue.Impl which uses Redis as a data store.
+func New(definition *model.Queue, url string) jobqueue.Impl {
+	return newJobQueue(definition, url)
+}
+
+func newJobQueue(definition *model.Queue, url string) *jobQueue {
+	return &jobQueue{
+		name:   definition.Name,
+		url:    url,
+		keys:   newKeys(definition),
+		logger: log.With().Str("queue", definition.Name).Logger(),
+	}
+}
+
+func (q *jobQueue) Start() {
+	log := q.logger.With().Str("method", "Start").Logger()
+
+	q.pool = &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.DialURL(q.url)
+		},
+	}
+
+	conn := q.pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		log.Panic().Msgf("Cannot connect to Redis: %s", err)
+	}
+}
+
+func (q *jobQueue) Stop() <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		q.pool.Close()
+		stopped <- struct{}{}
+	}()
+	return stopped
+}
+
+func (q *jobQueue) IsActive() bool {
+	return true
+}
+
+func (q *jobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
+	log := q.logger.With().Str("method", "Push").Logger()
+
+	job := &stored.IncomingJob{IncomingJob: j}
+
+	conn := q.pool.Get()
+	defer conn.Close()
+
+	now := stored.NowMillisecond()
+	id, err := redis.Uint64(scriptPush.Do(
+		conn,
+		q.keys.prefix,
+		now+job.NextDelay(),
+		now,
+		job.RetryCount(),
+		job.RetryDelay(),
+		job.FailCount(),
+		job.Category(),
+		job.URL(),
+		job.Payload(),
+		job.Timeout(),
+		job.ConcurrencyKey(),
+		job.ConcurrencyLimit(),
+		job.GroupID(),
+		job.IsDeadLetter(),
+	))
+	if err != nil {
+		log.Debug().Msgf("Failed to push a job: %s", err)
+		return nil, err
+	}
+	job.JobID = id
+
+	return job, nil
+}
+
+func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
+	log := q.logger.With().Str("method", "Pop").Logger()
+
+	conn := q.pool.Get()
+	defer conn.Close()
+
+	grabbed, err := entries(scriptGrab.Do(conn, q.keys.prefix, stored.NowMillisecond(), limit))
+	if err != nil {
+		log.Debug().Msgf("Failed to grab jobs: %s", err)
+		return nil, err
+	}
+
+	results := make([]jobqueue.Job, 0, len(grabbed))
+	for _, e := range grabbed {
+		j, err := newJob(e)
+		if err != nil {
+			log.Debug().Msgf("Failed to read grabbed jobs: %s", err)
+			return nil, err
+		}
+		results = append(results, j)
+	}
+
+	return results, nil
+}
+
+// NextTry returns the time when the earliest deferred job becomes due.
+func (q *jobQueue) NextTry() (uint64, bool) {
+	log := q.logger.With().Str("method", "NextTry").Logger()
+
+	conn := q.pool.Get()
+	defer conn.Close()
+
+	reply, err := redis.Strings(conn.Do(
+		"ZRANGEBYSCORE",
+		q.keys.ready(),
+		"("+strconv.FormatUint(stored.NowMillisecond(), 10),
+		"+inf",
+		"WITHSCORES",
+		"LIMIT", 0, 1,
+	))
+	if err != nil {
+		log.Debug().Msgf("Failed to select the next try: %s", err)
+		return 0, false
+	}
+	if len(reply) < 2 {
+		return 0, false
+	}
+
+	next, err := strconv.ParseFloat(reply[1], 64)
+	if err != nil {
+		log.Debug().Msgf("Invalid next try: %s", err)
+		return 0, false
+	}
+	return uint64(next), true
+}
+
+func (q *jobQueue) Delete(completedJob jobqueue.Job) {
+	log := q.logger.With().Str("method", "Delete").Logger()
+
+	j, ok := completedJob.(*stored.Job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	conn := q.pool.Get()
+	defer conn.Close()
+
+	if _, err := scriptDelete.Do(conn, q.keys.prefix, j.RowID()); err != nil {
+		log.Error().Msgf("Failed to delete a job: %s", err)
+	}
+}
+
+func (q *jobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
+	log := q.logger.With().Str("method", "Update").Logger()
+
+	j, ok := completedJob.(*stored.Job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	conn := q.pool.Get()
+	defer conn.Close()
+
+	if _, err := scriptUpdate.Do(
+		conn,
+		q.keys.prefix,
+		j.RowID(),
+		stored.NowMillisecond()+next.NextDelay(),
+		next.RetryCount(),
+		next.FailCount(),
+	); err != nil {
+		log.Error().Msgf("Failed to update a job: %s", err)
+	}
+}
+
+func (q *jobQueue) Recover() {
+	log := q.logger.With().Str("method", "Recover").Logger()
+
+	log.Info().Msgf("Recovering orphan jobs...")
+
+	conn := q.pool.Get()
+	defer conn.Close()
+
+	recovered, err := redis.Int(scriptRecover.Do(conn, q.keys.prefix))
+	if err != nil {
+		log.Error().Msgf("Failed to recover orphan jobs: %s", err)
+		return
+	}
+
+	log.Info().Msgf("Recovering complete: %d job(s) recovered", recovered)
+}
+
+func (q *jobQueue) Inspector() jobqueue.Inspector {
+	return &inspector{pool: q.pool, keys: q.keys}
+}
+
+func (q *jobQueue) FailureLog() jobqueue.FailureLog {
+	return &failureLog{pool: q.pool, keys: q.keys}
+}
+
+func newJob(e entry) (*stored.Job, error) {
+	p := &fieldParser{fields: e.fields}
+	f := stored.Fields{
+		ID:         e.id,
+		Category:   e.fields["category"],
+		URL:        e.fields["url"],
+		Payload:    e.fields["payload"],
+		Status:     e.fields["status"],
+		CreatedAt:  p.uint64("created_at"),
+		NextTry:    p.uint64("next_try"),
+		Timeout:    p.uint("timeout"),
+		RetryDelay: p.uint("retry_delay"),
+		RetryCount: p.uint("retry_count"),
+		FailCount:  p.uint("fail_count"),
+
+		ConcurrencyKey:   e.fields["concurrency_key"],
+		ConcurrencyLimit: p.uint("concurrency_limit"),
+		GroupID:          e.fields["group_id"],
+		DeadLetter:       e.fields["dead_letter"] == "1",
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return stored.NewJob(f), nil
+}
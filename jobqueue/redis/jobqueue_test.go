@@ -0,0 +1,366 @@
+package redis
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/test/jobqueue"
+	"github.com/fireworq/fireworq/test/redis"
+)
+
+func TestMain(m *testing.M) {
+	var status int
+	err := redistest.With(func(url string) {
+		config.Locally("queue_redis_url", url, func() {
+			status = m.Run()
+		})
+	})
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(status)
+}
+
+// Common tests
+
+func TestNew(t *testing.T) {
+	_ = New(&model.Queue{Name: "test", MaxWorkers: 30}, "dummy")
+}
+
+func TestSubtests(t *testing.T) {
+	jqtest.TestSubtests(t, runSubtests)
+}
+
+// Redis specific tests
+
+func TestRecover(t *testing.T) {
+	jq := newJobQueue(&model.Queue{Name: "jobqueue_redis_recover_test", MaxWorkers: 30}, URL())
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for _, payload := range []string{"1", "2", "3"} {
+		if _, err := jq.Push(&incomingTestJob{payload: payload}); err != nil {
+			t.Error(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(2)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(jobs) != 2 {
+		t.Errorf("Wrong queue length: %d", len(jobs))
+	}
+
+	jq.Recover()
+
+	jobs, err = jq.Pop(10)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(jobs) != 3 {
+		t.Errorf("Grabbed jobs must be recovered: %d", len(jobs))
+	}
+	for i, payload := range []string{"1", "2", "3"} {
+		if i < len(jobs) && jobs[i].Payload() != payload {
+			t.Errorf("Wrong job returned: %v", jobs[i])
+		}
+	}
+}
+
+func TestInspectPages(t *testing.T) {
+	jq := New(&model.Queue{Name: "jobqueue_redis_inspect_test", MaxWorkers: 30}, URL())
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	// Jobs sharing the same next try are ordered by their IDs.
+	for i := 0; i < 12; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: "1"}); err != nil {
+			t.Error(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	i := jq.(jobqueue.HasInspector).Inspector()
+	for _, order := range []jobqueue.SortOrder{jobqueue.Asc, jobqueue.Desc} {
+		var ids []uint64
+		cursor := ""
+		for {
+			r, err := i.FindAllWaiting(5, cursor, order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, j := range r.Jobs {
+				ids = append(ids, j.ID)
+			}
+			cursor = r.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+
+		if len(ids) != 12 {
+			t.Errorf("Wrong number of jobs: %d", len(ids))
+		}
+		for k := 1; k < len(ids); k++ {
+			if order == jobqueue.Asc && ids[k-1] >= ids[k] {
+				t.Errorf("Wrong order: %v", ids)
+			}
+			if order == jobqueue.Desc && ids[k-1] <= ids[k] {
+				t.Errorf("Wrong order: %v", ids)
+			}
+		}
+	}
+}
+
+func TestGroupBacklog(t *testing.T) {
+	jq := New(&model.Queue{Name: "jobqueue_redis_group_backlog_test", MaxWorkers: 30}, URL())
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	// A backlog of a group larger than the pop limit must not hide
+	// the jobs behind it.
+	for i := 0; i < 10; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: fmt.Sprintf("g%d", i), groupID: "g"}); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := jq.Push(&incomingTestJob{payload: "x"}); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].Payload() != "g0" || jobs[1].Payload() != "x" {
+		t.Fatalf("Wrong jobs returned: %v", jobs)
+	}
+
+	jobs2, err := jq.Pop(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs2) != 0 {
+		t.Errorf("Only the head of a group can be grabbed: %v", jobs2)
+	}
+
+	// The next job of the group gets ready when the head is deleted.
+	jq.Delete(jobs[0])
+	jobs2, err = jq.Pop(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs2) != 1 || jobs2[0].Payload() != "g1" {
+		t.Errorf("Wrong jobs returned: %v", jobs2)
+	}
+}
+
+func TestFailureLog(t *testing.T) {
+	jq := New(&model.Queue{Name: "jobqueue_redis_failure_log_test", MaxWorkers: 30}, URL())
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for i := 0; i < 5; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: fmt.Sprintf("%d", i)}); err != nil {
+			t.Error(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Error(err)
+	}
+	l := jq.(jobqueue.HasFailureLog).FailureLog()
+	for _, j := range jobs {
+		if err := l.Add(j, &jobqueue.Result{
+			Status:  jobqueue.ResultStatusPermanentFailure,
+			Message: j.Payload(),
+		}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, findAll := range []func(uint, string) (*jobqueue.FailedJobs, error){l.FindAll, l.FindAllRecentFailures} {
+		var failed []jobqueue.FailedJob
+		cursor := ""
+		for {
+			r, err := findAll(2, cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed = append(failed, r.FailedJobs...)
+			cursor = r.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+
+		if len(failed) != 5 {
+			t.Errorf("Wrong number of failed jobs: %d", len(failed))
+			continue
+		}
+		for i, f := range failed {
+			if f.Result.Message != fmt.Sprintf("%d", 4-i) {
+				t.Errorf("Wrong failed job: %v", f)
+			}
+			if f.FailCount != 1 {
+				t.Errorf("Wrong fail count: %d", f.FailCount)
+			}
+		}
+	}
+
+	r, err := l.FindAll(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := r.FailedJobs[0].ID
+	if f, err := l.Find(id); err != nil || f.ID != id {
+		t.Errorf("Failed job must be found: %v", err)
+	}
+	if err := l.Delete(id); err != nil {
+		t.Error(err)
+	}
+	if _, err := l.Find(id); err != sql.ErrNoRows {
+		t.Error("Deleted failed job should not be found")
+	}
+}
+
+func TestFailureLogPrune(t *testing.T) {
+	jq := New(&model.Queue{Name: "jobqueue_redis_failure_log_prune_test", MaxWorkers: 30}, URL())
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for i := 0; i < 5; i++ {
+		if _, err := jq.Push(&incomingTestJob{payload: fmt.Sprintf("%d", i)}); err != nil {
+			t.Error(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Error(err)
+	}
+	l := jq.(jobqueue.HasFailureLog).FailureLog()
+	for _, j := range jobs {
+		if err := l.Add(j, &jobqueue.Result{
+			Status:  jobqueue.ResultStatusPermanentFailure,
+			Message: j.Payload(),
+		}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	pruner := l.(jobqueue.FailurePruner)
+
+	if n, err := pruner.Prune(0, 0, 10); err != nil || n != 0 {
+		t.Errorf("Nothing should be pruned without retention: %d, %v", n, err)
+	}
+
+	if n, err := pruner.Prune(0, 3, 1); err != nil || n != 1 {
+		t.Errorf("Pruning should be limited: %d, %v", n, err)
+	}
+	if n, err := pruner.Prune(0, 3, 10); err != nil || n != 1 {
+		t.Errorf("Wrong number of pruned failed jobs: %d, %v", n, err)
+	}
+
+	r, err := l.FindAllRecentFailures(10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.FailedJobs) != 3 {
+		t.Fatalf("Wrong number of failed jobs: %d", len(r.FailedJobs))
+	}
+	if r.FailedJobs[2].Result.Message != "2" {
+		t.Errorf("Older failed jobs should be pruned first: %v", r.FailedJobs[2])
+	}
+	for _, f := range r.FailedJobs {
+		if _, err := l.Find(f.ID); err != nil {
+			t.Errorf("A retained failed job should be found: %v", err)
+		}
+	}
+
+	now := uint64(time.Now().Add(time.Second).UnixNano() / int64(time.Millisecond))
+	if n, err := pruner.Prune(now, 0, 10); err != nil || n != 3 {
+		t.Errorf("Expired failed jobs should be pruned: %d, %v", n, err)
+	}
+	if r, err := l.FindAll(10, ""); err != nil || len(r.FailedJobs) != 0 {
+		t.Errorf("All the failed jobs should be pruned: %v", err)
+	}
+}
+
+func runSubtests(t *testing.T, db, q string, tests []jqtest.Subtest) {
+	url := URL()
+
+	jq := New(&model.Queue{Name: q, MaxWorkers: 30}, url)
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for _, test := range tests {
+		err := redistest.FlushAll(url)
+		if err != nil {
+			t.Error(err)
+		}
+		test(t, jq)
+	}
+}
+
+type incomingTestJob struct {
+	payload string
+	groupID string
+}
+
+func (j *incomingTestJob) Category() string {
+	return "test_job"
+}
+
+func (j *incomingTestJob) URL() string {
+	return "http://localhost/"
+}
+
+func (j *incomingTestJob) Payload() string {
+	return j.payload
+}
+
+func (j *incomingTestJob) NextDelay() uint64 {
+	return 0
+}
+
+func (j *incomingTestJob) NextTry() uint64 {
+	return stored.NowMillisecond()
+}
+
+func (j *incomingTestJob) RetryCount() uint {
+	return 0
+}
+
+func (j *incomingTestJob) RetryDelay() uint {
+	return 0
+}
+
+func (j *incomingTestJob) Timeout() uint {
+	return 0
+}
+
+func (j *incomingTestJob) ConcurrencyKey() string {
+	return ""
+}
+
+func (j *incomingTestJob) ConcurrencyLimit() uint {
+	return 0
+}
+
+func (j *incomingTestJob) GroupID() string {
+	return j.groupID
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"encoding/json"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+type primaryBackupJobQueue struct {
+	*jobQueue
+	activator *activator
+}
+
+// NewPrimaryBackup creates a jobqueue.Impl which uses Redis as a data
+// store and restricts only one node to be active in a cluster.
+//
+// Inactive nodes become backup nodes, which will be active when the
+// active node dies.
+func NewPrimaryBackup(definition *model.Queue, url string) jobqueue.Impl {
+	q := newJobQueue(definition, url)
+	return &primaryBackupJobQueue{q, nil}
+}
+
+func (q *primaryBackupJobQueue) Start() {
+	q.jobQueue.Start()
+	q.activator = startActivator(
+		q,
+		q.Recover,
+	)
+}
+
+func (q *primaryBackupJobQueue) Stop() <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		<-q.activator.stop()
+		<-q.jobQueue.Stop()
+		stopped <- struct{}{}
+	}()
+	return stopped
+}
+
+func (q *primaryBackupJobQueue) IsActive() bool {
+	return q.activator.isActive()
+}
+
+func (q *primaryBackupJobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
+	if !q.IsActive() {
+		return nil, &jobqueue.InactiveError{}
+	}
+
+	return q.jobQueue.Pop(limit)
+}
+
+func (q *primaryBackupJobQueue) NextTry() (uint64, bool) {
+	if !q.IsActive() {
+		return 0, false
+	}
+
+	return q.jobQueue.NextTry()
+}
+
+func (q *primaryBackupJobQueue) Node() (*jobqueue.Node, error) {
+	conn := q.pool.Get()
+	defer conn.Close()
+
+	holder, err := redis.Bytes(conn.Do("GET", q.keys.lease()))
+	if err == redis.ErrNil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var node jobqueue.Node
+	if err := json.Unmarshal(holder, &node); err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+// activation interface
+
+func (q *primaryBackupJobQueue) queueName() string {
+	return q.name
+}
+
+func (q *primaryBackupJobQueue) getPool() *redis.Pool {
+	return q.pool
+}
+
+func (q *primaryBackupJobQueue) leaseKey() string {
+	return q.keys.lease()
+}
+
+func (q *primaryBackupJobQueue) nodeURL() string {
+	return NodeURL()
+}
@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+func TestNodeInCluster(t *testing.T) {
+	q := "jobqueue_redis_node_test"
+
+	jq1 := NewPrimaryBackup(&model.Queue{Name: q, MaxWorkers: 30}, URL())
+	jq1.Start()
+	defer func() { <-jq1.Stop() }()
+
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	jq2 := NewPrimaryBackup(&model.Queue{Name: q, MaxWorkers: 30}, URL())
+	jq2.Start()
+	defer func() { <-jq2.Stop() }()
+
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	if !jq1.IsActive() {
+		t.Error("Must be active")
+	}
+	if jq2.IsActive() {
+		t.Error("Must be inactive")
+	}
+
+	var nodes []*jobqueue.Node
+	for _, jq := range []jobqueue.Impl{jq1, jq2} {
+		hasNodeInfo, ok := jq.(jobqueue.HasNodeInfo)
+		if !ok {
+			t.Fatal("Must have Node() method")
+		}
+		node, err := hasNodeInfo.Node()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node == nil {
+			t.Fatal("Must return an active node")
+		}
+		if len(node.ID) <= 0 {
+			t.Error("Must return an ID")
+		}
+		if len(node.Host) <= 0 {
+			t.Error("Must return a host name")
+		}
+		nodes = append(nodes, node)
+	}
+	if nodes[0].ID != nodes[1].ID {
+		t.Error("Must return an active node ID")
+	}
+}
+
+func TestFailover(t *testing.T) {
+	q := "jobqueue_redis_failover_test"
+
+	jq1 := NewPrimaryBackup(&model.Queue{Name: q, MaxWorkers: 30}, URL())
+	jq1.Start()
+
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	jq2 := NewPrimaryBackup(&model.Queue{Name: q, MaxWorkers: 30}, URL())
+	jq2.Start()
+
+	time.Sleep(500 * time.Millisecond) // wait for up
+
+	if !jq1.IsActive() {
+		t.Error("The primary jobqueue should be active")
+	}
+	if jq2.IsActive() {
+		t.Error("A backup jobqueue should be inactive")
+	}
+
+	time.Sleep(2 * time.Second)
+
+	if !jq1.IsActive() {
+		t.Error("The primary jobqueue should be active")
+	}
+	if jq2.IsActive() {
+		t.Error("A backup jobqueue should be inactive")
+	}
+
+	<-jq1.Stop()
+	time.Sleep(2 * time.Second)
+
+	if !jq2.IsActive() {
+		t.Error("A backup jobqueue should be active after failing over")
+	}
+
+	<-jq2.Stop()
+}
@@ -0,0 +1,172 @@
+//go:generate go-assets-builder -p redis -o assets.go ../../data/jobqueue/redis
+
+package redis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/fireworq/fireworq/model"
+)
+
+func newKeys(definition *model.Queue) *keys {
+	return &keys{prefix: "fireworq:jq:" + definition.Name + ":"}
+}
+
+// keys describes names of keys of a queue.  Every key of a queue
+// starts with the same prefix so that scripts can derive keys of jobs
+// and groups by themselves.
+type keys struct {
+	prefix string
+}
+
+func (k *keys) claimed() string {
+	return k.prefix + "claimed"
+}
+
+// ready is an index of claimed jobs which can be grabbed, i.e. jobs
+// without a group and the heads of groups.
+func (k *keys) ready() string {
+	return k.prefix + "ready"
+}
+
+func (k *keys) grabbed() string {
+	return k.prefix + "grabbed"
+}
+
+func (k *keys) job(id uint64) string {
+	return k.prefix + "job:" + strconv.FormatUint(id, 10)
+}
+
+func (k *keys) jobs() string {
+	return k.prefix + "job:"
+}
+
+func (k *keys) failure(id uint64) string {
+	return k.prefix + "failure:" + strconv.FormatUint(id, 10)
+}
+
+func (k *keys) failures() string {
+	return k.prefix + "failure:"
+}
+
+func (k *keys) failuresByCreation() string {
+	return k.prefix + "failures"
+}
+
+func (k *keys) recentFailures() string {
+	return k.prefix + "recent_failures"
+}
+
+func (k *keys) lease() string {
+	return k.prefix + "primary"
+}
+
+// member returns a member of sorted sets for an ID.  IDs are padded
+// so that members with the same score are sorted by IDs.
+func member(id uint64) string {
+	return fmt.Sprintf("%020d", id)
+}
+
+var (
+	scriptPush          *redis.Script
+	scriptGrab          *redis.Script
+	scriptUpdate        *redis.Script
+	scriptDelete        *redis.Script
+	scriptRecover       *redis.Script
+	scriptPage          *redis.Script
+	scriptAddFailure    *redis.Script
+	scriptDeleteFailure *redis.Script
+	scriptPruneFailures *redis.Script
+	scriptRenewLease    *redis.Script
+	scriptReleaseLease  *redis.Script
+)
+
+func mustLoadScript(name string, keyCount int) *redis.Script {
+	f, err := Assets.Open(fmt.Sprintf("/data/jobqueue/redis/script/%s.lua", name))
+	if err != nil {
+		panic("Cannot load script (" + name + "): " + err.Error())
+	}
+
+	buf, err := ioutil.ReadAll(f)
+	if err != nil {
+		panic("Cannot load script (" + name + "): " + err.Error())
+	}
+
+	return redis.NewScript(keyCount, string(buf))
+}
+
+func init() {
+	scriptPush = mustLoadScript("push", 0)
+	scriptGrab = mustLoadScript("grab", 0)
+	scriptUpdate = mustLoadScript("update", 0)
+	scriptDelete = mustLoadScript("delete", 0)
+	scriptRecover = mustLoadScript("recover", 0)
+	scriptPage = mustLoadScript("page", 1)
+	scriptAddFailure = mustLoadScript("add_failure", 0)
+	scriptDeleteFailure = mustLoadScript("delete_failure", 0)
+	scriptPruneFailures = mustLoadScript("prune_failures", 0)
+	scriptRenewLease = mustLoadScript("renew_lease", 1)
+	scriptReleaseLease = mustLoadScript("release_lease", 1)
+}
+
+// entry is a hash returned by a script together with the ID
+// derived from the member of the sorted set which indexes it.
+type entry struct {
+	id     uint64
+	fields map[string]string
+}
+
+func entries(reply interface{}, err error) ([]entry, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]entry, 0, len(values))
+	for _, v := range values {
+		e, err := redis.Strings(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) <= 1 { // removed right after being indexed
+			continue
+		}
+
+		id, err := strconv.ParseUint(e[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]string, len(e)/2)
+		for i := 1; i+1 < len(e); i += 2 {
+			fields[e[i]] = e[i+1]
+		}
+		results = append(results, entry{id: id, fields: fields})
+	}
+	return results, nil
+}
+
+// fieldParser parses numeric fields of a hash keeping the first
+// error.
+type fieldParser struct {
+	fields map[string]string
+	err    error
+}
+
+func (p *fieldParser) uint64(name string) uint64 {
+	if p.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseUint(p.fields[name], 10, 64)
+	if err != nil {
+		p.err = fmt.Errorf("Invalid %s: %s", name, err)
+	}
+	return v
+}
+
+func (p *fieldParser) uint(name string) uint {
+	return uint(p.uint64(name))
+}
@@ -21,6 +21,13 @@ type Result struct {
 	Status  string `json:"status"`
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+
+	// RetryAfter, in seconds, overrides the retry delay of the job if
+	// it is not zero.
+	RetryAfter uint `json:"retry_after,omitempty"`
+	// KeepRetryCount makes a retry not consume the retry count of the
+	// job.
+	KeepRetryCount bool `json:"keep_retry_count,omitempty"`
 }
 
 // IsSuccess returns if the job succeeded
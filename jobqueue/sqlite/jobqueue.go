@@ -0,0 +1,251 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	_ "modernc.org/sqlite" // initialize the driver
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/sqljq"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+	"github.com/fireworq/fireworq/model"
+)
+
+// Dir returns the directory of the storage specified in the
+// configuration.
+func Dir() string {
+	return config.Get("sqlite_dir")
+}
+
+// Dsn returns the data source name of the database file in a
+// directory.
+func Dsn(dir string) string {
+	path := filepath.Join(dir, "fireworq.db")
+	return "file:" + path + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+}
+
+type jobQueue struct {
+	name   string
+	dir    string
+	sql    *sqls
+	db     *sql.DB
+	active int32
+	logger zerolog.Logger
+}
+
+// New creates a jobqueue.Impl which uses SQLite as a data store.  The
+// database file is placed in dir.
+//
+// Unlike other drivers backed by a database, the queue is active
+// while it is running and should not be shared with other processes.
+func New(definition *model.Queue, dir string) jobqueue.Impl {
+	tableName := newTableName(definition)
+	return &jobQueue{
+		name:   definition.Name,
+		dir:    dir,
+		sql:    tableName.makeQueries(),
+		logger: log.With().Str("queue", definition.Name).Logger(),
+	}
+}
+
+func (q *jobQueue) Start() {
+	log := q.logger.With().Str("method", "Start").Logger()
+
+	if err := os.MkdirAll(q.dir, 0755); err != nil {
+		log.Panic().Msgf("Cannot create data directory: %s", err)
+	}
+
+	db, err := sql.Open("sqlite", Dsn(q.dir))
+	if err != nil {
+		log.Panic().Msgf("Cannot open DB: %s", err)
+	}
+	q.db = db
+
+	_, err = q.db.Exec(q.sql.createJobqueue)
+	if err != nil {
+		log.Panic().Msgf("Failed to create queue table: %s", err)
+	}
+
+	_, err = q.db.Exec(q.sql.createFailure)
+	if err != nil {
+		log.Panic().Msgf("Failed to create queue failure log table: %s", err)
+	}
+
+	// Jobs grabbed by a former process are never completed since no
+	// other process shares the queue.
+	q.Recover()
+	atomic.StoreInt32(&q.active, 1)
+}
+
+func (q *jobQueue) Stop() <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		atomic.StoreInt32(&q.active, 0)
+		q.db.Close()
+		stopped <- struct{}{}
+	}()
+	return stopped
+}
+
+func (q *jobQueue) IsActive() bool {
+	return atomic.LoadInt32(&q.active) > 0
+}
+
+func (q *jobQueue) Push(j jobqueue.IncomingJob) (jobqueue.Job, error) {
+	log := q.logger.With().Str("method", "Push").Logger()
+
+	job := &stored.IncomingJob{IncomingJob: j}
+
+	now := stored.NowMillisecond()
+	r, err := q.db.Exec(
+		q.sql.insertJob,
+		now+job.NextDelay(),
+		now,
+		job.RetryCount(),
+		job.RetryDelay(),
+		job.FailCount(),
+		job.Category(),
+		job.URL(),
+		job.Payload(),
+		job.Timeout(),
+		job.ConcurrencyKey(),
+		job.ConcurrencyLimit(),
+		job.GroupID(),
+		job.IsDeadLetter(),
+	)
+	if err != nil {
+		log.Debug().Msgf("Failed to insert a job: %s", err)
+		return nil, err
+	}
+
+	id, err := r.LastInsertId()
+	if err != nil {
+		log.Debug().Msgf("Cannot get the last insert ID of the new job: %s", err)
+		return nil, err
+	}
+	job.JobID = uint64(id)
+
+	return job, nil
+}
+
+func (q *jobQueue) Pop(limit uint) ([]jobqueue.Job, error) {
+	log := q.logger.With().Str("method", "Pop").Logger()
+
+	rows, err := q.db.Query(q.sql.grab, stored.NowMillisecond(), limit)
+	if err != nil {
+		log.Debug().Msgf("Failed to grab jobs: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]jobqueue.Job, 0, limit)
+	for rows.Next() {
+		j, err := stored.ScanJob(rows)
+		if err != nil {
+			log.Debug().Msgf("Failed to scan grabbed jobs: %s", err)
+			return nil, err
+		}
+		results = append(results, j)
+	}
+	if err := rows.Err(); err != nil {
+		log.Debug().Msgf("Failed to read grabbed jobs: %s", err)
+		return nil, err
+	}
+
+	// `RETURNING` doesn't preserve the order of the subquery.
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].(*stored.Job).NextTry() < results[j].(*stored.Job).NextTry()
+	})
+
+	return results, nil
+}
+
+// NextTry returns the time when the earliest deferred job becomes due.
+func (q *jobQueue) NextTry() (uint64, bool) {
+	log := q.logger.With().Str("method", "NextTry").Logger()
+
+	var next sql.NullInt64
+	if err := q.db.QueryRow(q.sql.nextTry, stored.NowMillisecond()).Scan(&next); err != nil {
+		log.Debug().Msgf("Failed to select the next try: %s", err)
+		return 0, false
+	}
+	if !next.Valid {
+		return 0, false
+	}
+	return uint64(next.Int64), true
+}
+
+func (q *jobQueue) Delete(completedJob jobqueue.Job) {
+	log := q.logger.With().Str("method", "Delete").Logger()
+
+	j, ok := completedJob.(*stored.Job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	if _, err := q.db.Exec(q.sql.DeleteJob, j.RowID()); err != nil {
+		log.Error().Msgf("Failed to delete a job: %s", err)
+	}
+}
+
+func (q *jobQueue) Update(completedJob jobqueue.Job, next jobqueue.NextInfo) {
+	log := q.logger.With().Str("method", "Update").Logger()
+
+	j, ok := completedJob.(*stored.Job)
+	if !ok {
+		log.Panic().Msgf("Invalid job structure: %v", completedJob)
+		return
+	}
+
+	if _, err := q.db.Exec(
+		q.sql.updateJob,
+		stored.NowMillisecond()+next.NextDelay(),
+		next.RetryCount(),
+		next.FailCount(),
+		j.RowID(),
+	); err != nil {
+		log.Error().Msgf("Failed to update a job: %s", err)
+	}
+}
+
+func (q *jobQueue) Recover() {
+	log := q.logger.With().Str("method", "Recover").Logger()
+
+	log.Info().Msgf("Recovering orphan jobs...")
+
+	res, err := q.db.Exec(q.sql.recover)
+	if err != nil {
+		log.Error().Msgf("Failed to recover orphan jobs: %s", err)
+		return
+	}
+	recovered, _ := res.RowsAffected()
+
+	log.Info().Msgf("Recovering complete: %d job(s) recovered", recovered)
+}
+
+// Node returns the process itself since no other process shares the
+// queue.
+func (q *jobQueue) Node() (*jobqueue.Node, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return &jobqueue.Node{ID: strconv.Itoa(os.Getpid()), Host: host}, nil
+}
+
+func (q *jobQueue) Inspector() jobqueue.Inspector {
+	return sqljq.NewInspector(q.db, &q.sql.Queries, dialect)
+}
+
+func (q *jobQueue) FailureLog() jobqueue.FailureLog {
+	return sqljq.NewFailureLog(q.db, &q.sql.Queries, dialect)
+}
@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/test"
+	"github.com/fireworq/fireworq/test/jobqueue"
+	"github.com/fireworq/fireworq/test/sqlite"
+)
+
+func TestMain(m *testing.M) {
+	config.Locally("driver", "sqlite", func() {
+		status, err := test.Run(m)
+		if err != nil {
+			panic(err)
+		}
+		os.Exit(status)
+	})
+}
+
+// Common tests
+
+func TestNew(t *testing.T) {
+	_ = New(&model.Queue{Name: "test", MaxWorkers: 30}, "dummy")
+}
+
+func TestSubtests(t *testing.T) {
+	jqtest.TestSubtests(t, runSubtests)
+}
+
+// SQLite specific tests
+
+func TestRecoverOnStart(t *testing.T) {
+	dir := Dir()
+	def := &model.Queue{Name: "test_recover", MaxWorkers: 30}
+
+	jq := New(def, dir)
+	jq.Start()
+	if _, err := jq.Push(&incomingTestJob{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if jobs, err := jq.Pop(10); err != nil || len(jobs) != 1 {
+		t.Fatalf("A job should be popped: %v, %v", jobs, err)
+	}
+	<-jq.Stop()
+
+	jq = New(def, dir)
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+	if jobs, err := jq.Pop(10); err != nil || len(jobs) != 1 {
+		t.Errorf("A job grabbed by a former process should be recovered: %v, %v", jobs, err)
+	}
+}
+
+func TestFailureLogPrune(t *testing.T) {
+	jq := New(&model.Queue{Name: "test_failure_log_prune", MaxWorkers: 30}, Dir())
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for i := 0; i < 5; i++ {
+		if _, err := jq.Push(&incomingTestJob{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := jq.(jobqueue.HasFailureLog).FailureLog()
+	for _, j := range jobs {
+		if err := l.Add(j, &jobqueue.Result{Status: jobqueue.ResultStatusPermanentFailure}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	pruner := l.(jobqueue.FailurePruner)
+
+	if n, err := pruner.Prune(0, 0, 10); err != nil || n != 0 {
+		t.Errorf("Nothing should be pruned without retention: %d, %v", n, err)
+	}
+	if n, err := pruner.Prune(0, 3, 1); err != nil || n != 1 {
+		t.Errorf("Pruning should be limited: %d, %v", n, err)
+	}
+	if n, err := pruner.Prune(0, 3, 10); err != nil || n != 1 {
+		t.Errorf("Wrong number of pruned failed jobs: %d, %v", n, err)
+	}
+
+	past := uint64(time.Now().Add(-time.Minute).UnixNano() / int64(time.Millisecond))
+	if n, err := pruner.Prune(past, 0, 10); err != nil || n != 0 {
+		t.Errorf("Unexpired failed jobs should not be pruned: %d, %v", n, err)
+	}
+
+	now := uint64(time.Now().Add(time.Second).UnixNano() / int64(time.Millisecond))
+	if n, err := pruner.Prune(now, 0, 2); err != nil || n != 2 {
+		t.Errorf("Pruning expired failed jobs should be limited: %d, %v", n, err)
+	}
+	if n, err := pruner.Prune(now, 0, 10); err != nil || n != 1 {
+		t.Errorf("Expired failed jobs should be pruned: %d, %v", n, err)
+	}
+}
+
+type incomingTestJob struct{}
+
+func (j *incomingTestJob) Category() string       { return "test" }
+func (j *incomingTestJob) URL() string            { return "http://example.com/" }
+func (j *incomingTestJob) Payload() string        { return "{}" }
+func (j *incomingTestJob) NextDelay() uint64      { return 0 }
+func (j *incomingTestJob) RetryCount() uint       { return 0 }
+func (j *incomingTestJob) RetryDelay() uint       { return 0 }
+func (j *incomingTestJob) Timeout() uint          { return 0 }
+func (j *incomingTestJob) ConcurrencyKey() string { return "" }
+func (j *incomingTestJob) ConcurrencyLimit() uint { return 0 }
+func (j *incomingTestJob) GroupID() string        { return "" }
+
+func runSubtests(t *testing.T, db, q string, tests []jqtest.Subtest) {
+	dir := Dir()
+
+	jq := New(&model.Queue{Name: q, MaxWorkers: 30}, dir)
+	jq.Start()
+	defer func() { <-jq.Stop() }()
+
+	for _, test := range tests {
+		err := sqlitetest.TruncateTables(Dsn(dir))
+		if err != nil {
+			t.Error(err)
+		}
+		test(t, jq)
+	}
+}
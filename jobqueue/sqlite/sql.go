@@ -0,0 +1,143 @@
+//go:generate go-assets-builder -p sqlite -o assets.go ../../data/jobqueue/sqlite
+
+package sqlite
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/fireworq/fireworq/jobqueue/sqljq"
+	"github.com/fireworq/fireworq/model"
+)
+
+func newTableName(definition *model.Queue) *tableName {
+	re := invalidTablenameChars
+	name := string(re.ReplaceAll([]byte(definition.Name), []byte{'_'}))
+	return &tableName{
+		JobQueue: strings.Join([]string{"fireworq_jq(", name, ")"}, ""),
+		Failure:  strings.Join([]string{"fireworq_jq_fail(", name, ")"}, ""),
+	}
+}
+
+type tableName struct {
+	JobQueue string
+	Failure  string
+}
+
+func (tn *tableName) makeQueries() *sqls {
+	return &sqls{
+		Queries: sqljq.Queries{
+			InsertFailedJob:    tn.makeQuery(tmplInsertFailedJob),
+			DeleteFailedJob:    tn.makeQuery(tmplDeleteFailedJob),
+			PruneFailedJobs:    tn.makeQuery(tmplPruneFailedJobs),
+			NthNewestFailure:   tn.makeQuery(tmplNthNewestFailure),
+			OldestFailures:     tn.makeQuery(tmplOldestFailures),
+			DeleteJob:          tn.makeQuery(tmplDeleteJob),
+			InspectJob:         tn.makeQuery(tmplInspectJob),
+			InspectJobs:        tn.makeQuery(tmplInspectJobs),
+			InspectJobsAsc:     tn.makeQuery(tmplInspectJobsAsc),
+			FailedJob:          tn.makeQuery(tmplFailedJob),
+			FailedJobs:         tn.makeQuery(tmplFailedJobs),
+			RecentlyFailedJobs: tn.makeQuery(tmplRecentlyFailedJobs),
+		},
+
+		createJobqueue: tn.makeQuery(tmplCreateJobqueue),
+		createFailure:  tn.makeQuery(tmplCreateFailure),
+		grab:           tn.makeQuery(tmplGrabJobs),
+		nextTry:        tn.makeQuery(tmplNextTry),
+		insertJob:      tn.makeQuery(tmplInsertJob),
+		updateJob:      tn.makeQuery(tmplUpdateJob),
+		recover:        tn.makeQuery(tmplRecoverJobs),
+	}
+}
+
+func (tn *tableName) makeQuery(tmpl *template.Template) string {
+	buffer := new(bytes.Buffer)
+	_ = tmpl.Execute(buffer, tn) // ignore error
+	return buffer.String()
+}
+
+type sqls struct {
+	sqljq.Queries
+
+	createJobqueue string
+	createFailure  string
+	grab           string
+	nextTry        string
+	insertJob      string
+	updateJob      string
+	recover        string
+}
+
+// dialect takes the number of rows of a LIMIT clause as a parameter.
+// INTEGER is signed in SQLite.
+var dialect = &sqljq.Dialect{MaxID: math.MaxInt64}
+
+var (
+	invalidTablenameChars  *regexp.Regexp
+	tmplCreateJobqueue     *template.Template
+	tmplCreateFailure      *template.Template
+	tmplGrabJobs           *template.Template
+	tmplNextTry            *template.Template
+	tmplInsertJob          *template.Template
+	tmplInsertFailedJob    *template.Template
+	tmplDeleteFailedJob    *template.Template
+	tmplPruneFailedJobs    *template.Template
+	tmplNthNewestFailure   *template.Template
+	tmplOldestFailures     *template.Template
+	tmplDeleteJob          *template.Template
+	tmplUpdateJob          *template.Template
+	tmplRecoverJobs        *template.Template
+	tmplInspectJob         *template.Template
+	tmplInspectJobs        *template.Template
+	tmplInspectJobsAsc     *template.Template
+	tmplFailedJob          *template.Template
+	tmplFailedJobs         *template.Template
+	tmplRecentlyFailedJobs *template.Template
+)
+
+func mustLoadTemplate(name string) *template.Template {
+	f, err := Assets.Open(fmt.Sprintf("/data/jobqueue/sqlite/%s.sql", name))
+	if err != nil {
+		panic("Cannot load template (" + name + "): " + err.Error())
+	}
+
+	buf, err := ioutil.ReadAll(f)
+	if err != nil {
+		panic("Cannot load template (" + name + "): " + err.Error())
+	}
+
+	tmpl, err := template.New(name).Parse(string(buf))
+	if err != nil {
+		panic("Cannot load template (" + name + "): " + err.Error())
+	}
+	return tmpl
+}
+
+func init() {
+	invalidTablenameChars = regexp.MustCompile("[^0-9a-z_]")
+	tmplCreateJobqueue = mustLoadTemplate("schema/job_queue")
+	tmplCreateFailure = mustLoadTemplate("schema/job_failure")
+	tmplGrabJobs = mustLoadTemplate("query/grab_jobs")
+	tmplNextTry = mustLoadTemplate("query/next_try")
+	tmplInsertJob = mustLoadTemplate("query/insert_job")
+	tmplInsertFailedJob = mustLoadTemplate("query/insert_failed_job")
+	tmplDeleteFailedJob = mustLoadTemplate("query/delete_failed_job")
+	tmplPruneFailedJobs = mustLoadTemplate("query/prune_failed_jobs")
+	tmplNthNewestFailure = mustLoadTemplate("query/nth_newest_failure")
+	tmplOldestFailures = mustLoadTemplate("query/oldest_failures")
+	tmplDeleteJob = mustLoadTemplate("query/delete_job")
+	tmplUpdateJob = mustLoadTemplate("query/update_job")
+	tmplRecoverJobs = mustLoadTemplate("query/recover_jobs")
+	tmplInspectJob = mustLoadTemplate("query/inspect_job")
+	tmplInspectJobs = mustLoadTemplate("query/inspect_jobs")
+	tmplInspectJobsAsc = mustLoadTemplate("query/inspect_jobs_asc")
+	tmplFailedJob = mustLoadTemplate("query/failed_job")
+	tmplFailedJobs = mustLoadTemplate("query/failed_jobs")
+	tmplRecentlyFailedJobs = mustLoadTemplate("query/recently_failed_jobs")
+}
@@ -0,0 +1,201 @@
+package sqljq
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+)
+
+// storedJob is a job stored in a row of a job queue table.
+type storedJob interface {
+	jobqueue.Job
+	RowID() uint64
+	Category() string
+	CreatedAt() uint64
+}
+
+// FailureLog is a jobqueue.FailureLog of a failure log table.
+type FailureLog struct {
+	db      *sql.DB
+	sql     *Queries
+	dialect *Dialect
+}
+
+// NewFailureLog creates a FailureLog which runs queries on db.
+func NewFailureLog(db *sql.DB, queries *Queries, dialect *Dialect) *FailureLog {
+	return &FailureLog{db: db, sql: queries, dialect: dialect}
+}
+
+// Add adds a failed job with its last result.  The job should be
+// popped from a queue of the same table.
+func (l *FailureLog) Add(failed jobqueue.Job, result *jobqueue.Result) error {
+	log := log.With().Str("method", "failureLog.Add").Logger()
+
+	j, ok := failed.(storedJob)
+	if !ok {
+		return fmt.Errorf("Invalid job structure: %v", failed)
+	}
+
+	res, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	if _, err = l.db.Exec(
+		l.sql.InsertFailedJob,
+		j.RowID(),
+		j.Category(),
+		failed.URL(),
+		failed.Payload(),
+		string(res),
+		failed.FailCount()+1,
+		stored.NowMillisecond(),
+		j.CreatedAt(),
+	); err != nil {
+		log.Debug().Msgf("Failed to Insert a job: %s", err)
+	}
+
+	return err
+}
+
+// Delete deletes a failed job.
+func (l *FailureLog) Delete(failureID uint64) error {
+	_, err := l.db.Exec(l.sql.DeleteFailedJob, failureID)
+	return err
+}
+
+// Prune deletes at most limit failed jobs which failed before
+// failedBefore or are older than the newest maxRows ones.
+func (l *FailureLog) Prune(failedBefore uint64, maxRows uint, limit uint) (uint, error) {
+	// Failed jobs older than the newest maxRows ones have IDs up to
+	// maxID.
+	var maxID uint64
+	if maxRows > 0 {
+		query, args := l.dialect.limit(l.sql.NthNewestFailure, maxRows)
+		err := l.db.QueryRow(query, args...).Scan(&maxID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+	}
+	if failedBefore > 0 {
+		id, err := l.lastFailureBefore(failedBefore, limit)
+		if err != nil {
+			return 0, err
+		}
+		if id > maxID {
+			maxID = id
+		}
+	}
+	if maxID == 0 {
+		return 0, nil
+	}
+
+	query, args := l.dialect.limit(l.sql.PruneFailedJobs, limit, maxID)
+	res, err := l.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return uint(n), err
+}
+
+// lastFailureBefore returns the largest ID of the failed jobs which
+// failed before failedBefore among the oldest limit ones.  The
+// failed jobs are looked up in the order of their IDs since there is
+// no index of failed_at.
+func (l *FailureLog) lastFailureBefore(failedBefore uint64, limit uint) (uint64, error) {
+	query, args := l.dialect.limit(l.sql.OldestFailures, limit)
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var lastID uint64
+	for rows.Next() {
+		var id, failedAt uint64
+		if err := rows.Scan(&id, &failedAt); err != nil {
+			return 0, err
+		}
+		if failedAt >= failedBefore {
+			break
+		}
+		lastID = id
+	}
+	return lastID, rows.Err()
+}
+
+// Find finds a failed job.
+func (l *FailureLog) Find(failureID uint64) (*jobqueue.FailedJob, error) {
+	j, err := l.scan(l.db.QueryRow(l.sql.FailedJob, failureID))
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// FindAll finds failed jobs in the descending order of their creation
+// time.
+func (l *FailureLog) FindAll(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	return l.findAllByQuery(l.sql.FailedJobs, limit, cursor)
+}
+
+// FindAllRecentFailures finds failed jobs in the descending order of
+// their failure.
+func (l *FailureLog) FindAllRecentFailures(limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	return l.findAllByQuery(l.sql.RecentlyFailedJobs, limit, cursor)
+}
+
+func (l *FailureLog) findAllByQuery(query string, limit uint, cursor string) (*jobqueue.FailedJobs, error) {
+	var maxTime int64 = math.MaxInt64
+	maxID := l.dialect.MaxID
+	if t, id, ok := stored.DecodeCursor(cursor); ok {
+		maxTime = t
+		maxID = id
+	}
+
+	query, args := l.dialect.limit(query, limit+1, maxTime, maxTime, l.dialect.id(maxID))
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]jobqueue.FailedJob, 0, limit)
+	for rows.Next() {
+		j, err := l.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nextCursor := ""
+	if uint(len(results)) > limit {
+		nextCursor = stored.EncodeCursor(
+			results[limit].CreatedAt.UnixNano()/int64(time.Millisecond),
+			results[limit].ID,
+		)
+		results = results[:limit]
+	}
+
+	return &jobqueue.FailedJobs{FailedJobs: results, NextCursor: nextCursor}, nil
+}
+
+func (l *FailureLog) scan(s stored.Scanner) (*jobqueue.FailedJob, error) {
+	var f stored.Failure
+	if err := f.Scan(s); err != nil {
+		return nil, err
+	}
+	return f.FailedJob()
+}
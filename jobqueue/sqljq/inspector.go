@@ -0,0 +1,126 @@
+package sqljq
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/stored"
+)
+
+// Inspector is a jobqueue.Inspector of a job queue table.
+type Inspector struct {
+	db      *sql.DB
+	sql     *Queries
+	dialect *Dialect
+}
+
+// NewInspector creates an Inspector which runs queries on db.
+func NewInspector(db *sql.DB, queries *Queries, dialect *Dialect) *Inspector {
+	return &Inspector{db: db, sql: queries, dialect: dialect}
+}
+
+// Delete deletes a job.
+func (i *Inspector) Delete(jobID uint64) error {
+	_, err := i.db.Exec(i.sql.DeleteJob, jobID)
+	return err
+}
+
+// Find finds a job.
+func (i *Inspector) Find(jobID uint64) (*jobqueue.InspectedJob, error) {
+	j, err := stored.ScanJob(i.db.QueryRow(i.sql.InspectJob, jobID))
+	if err != nil {
+		return nil, err
+	}
+	return j.Inspect(), nil
+}
+
+// FindAllGrabbed finds jobs being processed.
+func (i *Inspector) FindAllGrabbed(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	var maxTime = time.Now().UnixNano() / int64(time.Millisecond)
+	if order == jobqueue.Asc {
+		return i.findAllAsc("grabbed", 0, maxTime, limit, cursor)
+	}
+	return i.findAllDesc("grabbed", 0, maxTime, limit, cursor)
+}
+
+// FindAllWaiting finds jobs waiting to be processed.
+func (i *Inspector) FindAllWaiting(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	var maxTime = time.Now().UnixNano() / int64(time.Millisecond)
+	if order == jobqueue.Asc {
+		return i.findAllAsc("claimed", 0, maxTime, limit, cursor)
+	}
+	return i.findAllDesc("claimed", 0, maxTime, limit, cursor)
+}
+
+// FindAllDeferred finds jobs scheduled in the future.
+func (i *Inspector) FindAllDeferred(limit uint, cursor string, order jobqueue.SortOrder) (*jobqueue.InspectedJobs, error) {
+	var minTime = time.Now().UnixNano() / int64(time.Millisecond)
+	if order == jobqueue.Asc {
+		return i.findAllAsc("claimed", minTime, math.MaxInt64, limit, cursor)
+	}
+	return i.findAllDesc("claimed", minTime, 0, limit, cursor)
+}
+
+func (i *Inspector) findAllAsc(status string, minTime int64, maxTime int64, limit uint, cursor string) (*jobqueue.InspectedJobs, error) {
+	if minTime >= math.MaxInt64 {
+		minTime = 0
+	}
+	var minJobID uint64
+	if t, j, ok := stored.DecodeCursor(cursor); ok {
+		minTime = t
+		minJobID = j
+	}
+
+	query, args := i.dialect.limit(i.sql.InspectJobsAsc, limit+1, status, minTime, maxTime, i.dialect.id(minJobID))
+	return i.findAll(query, limit, args...)
+}
+
+func (i *Inspector) findAllDesc(status string, minTime int64, maxTime int64, limit uint, cursor string) (*jobqueue.InspectedJobs, error) {
+	if maxTime <= 0 {
+		maxTime = math.MaxInt64
+	}
+	maxJobID := i.dialect.MaxID
+	if t, j, ok := stored.DecodeCursor(cursor); ok {
+		maxTime = t
+		maxJobID = j
+	}
+
+	query, args := i.dialect.limit(i.sql.InspectJobs, limit+1, status, minTime, maxTime, i.dialect.id(maxJobID))
+	return i.findAll(query, limit, args...)
+}
+
+func (i *Inspector) findAll(query string, limit uint, args ...interface{}) (*jobqueue.InspectedJobs, error) {
+	results := make([]jobqueue.InspectedJob, 0, limit+1)
+
+	if err := func() error {
+		rows, err := i.db.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			j, err := stored.ScanJob(rows)
+			if err != nil {
+				return err
+			}
+			results = append(results, *j.Inspect())
+		}
+		return rows.Err()
+	}(); err != nil {
+		return nil, err
+	}
+
+	nextCursor := ""
+	if uint(len(results)) > limit {
+		nextCursor = stored.EncodeCursor(
+			results[limit].NextTry.UnixNano()/int64(time.Millisecond),
+			results[limit].ID,
+		)
+		results = results[:limit]
+	}
+
+	return &jobqueue.InspectedJobs{Jobs: results, NextCursor: nextCursor}, nil
+}
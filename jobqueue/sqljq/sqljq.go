@@ -0,0 +1,55 @@
+// Package sqljq provides the parts of job queues common to the
+// drivers backed by SQL databases.
+package sqljq
+
+import (
+	"strconv"
+)
+
+// Dialect describes the differences of queries among databases.
+type Dialect struct {
+	// InlineLimit makes the number of rows of a LIMIT clause
+	// appended to a query instead of being passed as the last
+	// parameter of the query.
+	InlineLimit bool
+
+	// MaxID is the largest ID of a job or a failure, which depends on
+	// whether the integer type of the database is signed.
+	MaxID uint64
+}
+
+// limit returns query and args with the number of rows of its LIMIT
+// clause.
+func (d *Dialect) limit(query string, n uint, args ...interface{}) (string, []interface{}) {
+	if d.InlineLimit {
+		return query + strconv.FormatUint(uint64(n), 10), args
+	}
+	return query, append(args, n)
+}
+
+// id limits an ID taken from a cursor to the range of the database.
+func (d *Dialect) id(id uint64) uint64 {
+	if id > d.MaxID {
+		return d.MaxID
+	}
+	return id
+}
+
+// Queries are the statements used by an Inspector and a FailureLog.
+// A LIMIT clause of a query takes the number of rows as described in
+// Dialect.
+type Queries struct {
+	DeleteJob      string
+	InspectJob     string
+	InspectJobs    string
+	InspectJobsAsc string
+
+	InsertFailedJob    string
+	DeleteFailedJob    string
+	PruneFailedJobs    string
+	NthNewestFailure   string
+	OldestFailures     string
+	FailedJob          string
+	FailedJobs         string
+	RecentlyFailedJobs string
+}
@@ -16,6 +16,7 @@ type Stats struct {
 	TotalPermanentFailures int64 `json:"total_permanent_failures"`
 	TotalCompletes         int64 `json:"total_completes"`
 	TotalElapsed           int64 `json:"total_elapsed"`
+	TotalPrunedFailures    int64 `json:"total_pruned_failures"`
 	PushesPerSecond        int64 `json:"pushes_per_second"`
 	PopsPerSecond          int64 `json:"pops_per_second"`
 }
@@ -28,6 +29,7 @@ type stats struct {
 	totalPermanentFailures int64
 	totalCompletes         int64
 	totalElapsed           int64
+	totalPrunedFailures    int64
 	pushesPerSecond        *ratecounter.RateCounter
 	popsPerSecond          *ratecounter.RateCounter
 }
@@ -69,6 +71,10 @@ func (s *stats) elapsed(t int64) {
 	atomic.AddInt64(&s.totalElapsed, t)
 }
 
+func (s *stats) pruneFailures(num int64) {
+	atomic.AddInt64(&s.totalPrunedFailures, num)
+}
+
 func (s *stats) export() *Stats {
 	return &Stats{
 		TotalPushes:            atomic.LoadInt64(&s.totalPushes),
@@ -78,6 +84,7 @@ func (s *stats) export() *Stats {
 		TotalPermanentFailures: atomic.LoadInt64(&s.totalPermanentFailures),
 		TotalCompletes:         atomic.LoadInt64(&s.totalCompletes),
 		TotalElapsed:           atomic.LoadInt64(&s.totalElapsed),
+		TotalPrunedFailures:    atomic.LoadInt64(&s.totalPrunedFailures),
 		PushesPerSecond:        s.pushesPerSecond.Rate(),
 		PopsPerSecond:          s.popsPerSecond.Rate(),
 	}
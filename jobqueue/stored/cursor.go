@@ -0,0 +1,31 @@
+package stored
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EncodeCursor encodes a cursor in a form of "<time>,<ID>".
+func EncodeCursor(t int64, id uint64) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d,%d", t, id)))
+}
+
+// DecodeCursor decodes a cursor in a form of "<time>,<ID>".
+func DecodeCursor(cursor string) (int64, uint64, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, false
+	}
+	pair := strings.SplitN(string(decoded), ",", 2)
+	if len(pair) != 2 {
+		return 0, 0, false
+	}
+	t, err1 := strconv.ParseInt(pair[0], 10, 64)
+	id, err2 := strconv.ParseUint(pair[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return t, id, true
+}
@@ -0,0 +1,52 @@
+package stored
+
+import (
+	"encoding/json"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+// Failure holds the fields of a failed job in a data store.
+type Failure struct {
+	ID        uint64
+	JobID     uint64
+	Category  string
+	URL       string
+	Payload   string
+	Result    string // JSON of a jobqueue.Result
+	FailCount uint
+	FailedAt  uint64 // milliseconds
+	CreatedAt uint64 // milliseconds
+}
+
+// Scan reads fields from s.  The columns of s should be in the order
+// of failure_id, job_id, category, url, payload, result, fail_count,
+// failed_at and created_at.
+func (f *Failure) Scan(s Scanner) error {
+	return s.Scan(&(f.ID), &(f.JobID), &(f.Category), &(f.URL), &(f.Payload), &(f.Result), &(f.FailCount), &(f.FailedAt), &(f.CreatedAt))
+}
+
+// FailedJob returns the failure as a jobqueue.FailedJob.
+func (f *Failure) FailedJob() (*jobqueue.FailedJob, error) {
+	j := jobqueue.FailedJob{
+		ID:        f.ID,
+		JobID:     f.JobID,
+		Category:  f.Category,
+		URL:       f.URL,
+		FailCount: f.FailCount,
+		FailedAt:  FromMillisecond(f.FailedAt),
+		CreatedAt: FromMillisecond(f.CreatedAt),
+	}
+
+	j.Payload = json.RawMessage(f.Payload)
+	if _, err := json.Marshal(j.Payload); err != nil {
+		payload, _ := json.Marshal(f.Payload)
+		j.Payload = json.RawMessage(payload)
+	}
+
+	if err := json.Unmarshal([]byte(f.Result), &(j.Result)); err != nil {
+		return nil, err
+	}
+
+	return &j, nil
+}
@@ -0,0 +1,237 @@
+// Package stored provides jobs stored in a data store, which are
+// shared by the drivers of job queues.
+package stored
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/logger"
+)
+
+// Scanner is an interface of a row of a query result such as
+// *sql.Row and *sql.Rows.
+type Scanner interface {
+	Scan(args ...interface{}) error
+}
+
+// IncomingJob : implements the following interfaces
+// - jobqueue.IncomingJob
+// - jobqueue.Job
+// - logger.LoggableJob
+type IncomingJob struct {
+	jobqueue.IncomingJob
+	JobID uint64 // set when the job is stored
+}
+
+// ID returns the ID of the job.
+func (j *IncomingJob) ID() uint64 {
+	return j.JobID
+}
+
+// FailCount returns the number of failures of the job, which is
+// always zero.
+func (j *IncomingJob) FailCount() uint {
+	return 0
+}
+
+// Status returns the status of the job, which is always "claimed".
+func (j *IncomingJob) Status() string {
+	return "claimed"
+}
+
+// CreatedAt returns the time when the job is created in milliseconds.
+func (j *IncomingJob) CreatedAt() uint64 {
+	return NowMillisecond()
+}
+
+// NextTry returns the time when the job is tried in milliseconds.
+func (j *IncomingJob) NextTry() uint64 {
+	return NowMillisecond() + j.NextDelay()
+}
+
+// IsDeadLetter returns true if the job is a dead letter of another
+// job.
+func (j *IncomingJob) IsDeadLetter() bool {
+	return jobqueue.IsDeadLetter(j.IncomingJob)
+}
+
+// ToLoggable returns the job itself.
+func (j *IncomingJob) ToLoggable() logger.LoggableJob {
+	return j
+}
+
+// Fields holds the fields of a job in a data store.
+type Fields struct {
+	ID         uint64
+	Category   string
+	URL        string
+	Payload    string
+	Status     string
+	CreatedAt  uint64 // milliseconds
+	NextTry    uint64 // milliseconds
+	Timeout    uint   // seconds
+	RetryDelay uint   // seconds
+	RetryCount uint
+	FailCount  uint
+
+	ConcurrencyKey   string
+	ConcurrencyLimit uint
+	GroupID          string
+	DeadLetter       bool
+}
+
+// Scan reads fields from s.  The columns of s should be in the order
+// of job_id, category, url, payload, next_try, status, created_at,
+// retry_count, retry_delay, fail_count, timeout, concurrency_key,
+// concurrency_limit, group_id and dead_letter.
+func (f *Fields) Scan(s Scanner) error {
+	return s.Scan(&(f.ID), &(f.Category), &(f.URL), &(f.Payload), &(f.NextTry), &(f.Status), &(f.CreatedAt), &(f.RetryCount), &(f.RetryDelay), &(f.FailCount), &(f.Timeout), &(f.ConcurrencyKey), &(f.ConcurrencyLimit), &(f.GroupID), &(f.DeadLetter))
+}
+
+// Job : implements the following interfaces
+// - jobqueue.Job
+// - logger.LoggableJob
+type Job struct {
+	f Fields
+}
+
+// NewJob creates a Job of fields.
+func NewJob(f Fields) *Job {
+	return &Job{f}
+}
+
+// ScanJob creates a Job of fields read by (*Fields).Scan.
+func ScanJob(s Scanner) (*Job, error) {
+	var f Fields
+	if err := f.Scan(s); err != nil {
+		return nil, err
+	}
+	return &Job{f}, nil
+}
+
+// ID returns the ID of the job.
+func (j *Job) ID() uint64 {
+	return j.f.ID
+}
+
+// RowID returns the ID of the job in the data store.  It is the same
+// as ID() unless a driver encodes something else in the ID.
+func (j *Job) RowID() uint64 {
+	return j.f.ID
+}
+
+// Category returns the category of the job.
+func (j *Job) Category() string {
+	return j.f.Category
+}
+
+// URL returns the URL of the job.
+func (j *Job) URL() string {
+	return j.f.URL
+}
+
+// Payload returns the payload of the job.
+func (j *Job) Payload() string {
+	return j.f.Payload
+}
+
+// NextTry returns the time when the job is tried in milliseconds.
+func (j *Job) NextTry() uint64 {
+	return j.f.NextTry
+}
+
+// RetryCount returns the number of retries left.
+func (j *Job) RetryCount() uint {
+	return j.f.RetryCount
+}
+
+// RetryDelay returns the delay of a retry in seconds.
+func (j *Job) RetryDelay() uint {
+	return j.f.RetryDelay
+}
+
+// FailCount returns the number of failures of the job.
+func (j *Job) FailCount() uint {
+	return j.f.FailCount
+}
+
+// Timeout returns the timeout of the job in seconds.
+func (j *Job) Timeout() uint {
+	return j.f.Timeout
+}
+
+// ConcurrencyKey returns the concurrency key of the job.
+func (j *Job) ConcurrencyKey() string {
+	return j.f.ConcurrencyKey
+}
+
+// ConcurrencyLimit returns the concurrency limit of the job.
+func (j *Job) ConcurrencyLimit() uint {
+	return j.f.ConcurrencyLimit
+}
+
+// GroupID returns the ID of the group of the job.
+func (j *Job) GroupID() string {
+	return j.f.GroupID
+}
+
+// IsDeadLetter returns true if the job is a dead letter of another
+// job.
+func (j *Job) IsDeadLetter() bool {
+	return j.f.DeadLetter
+}
+
+// Status returns the status of the job.
+func (j *Job) Status() string {
+	return j.f.Status
+}
+
+// CreatedAt returns the time when the job is created in milliseconds.
+func (j *Job) CreatedAt() uint64 {
+	return j.f.CreatedAt
+}
+
+// ToLoggable returns the job itself.
+func (j *Job) ToLoggable() logger.LoggableJob {
+	return j
+}
+
+// Inspect returns the job as a jobqueue.InspectedJob with the ID of
+// the job in the data store.
+func (j *Job) Inspect() *jobqueue.InspectedJob {
+	payload := json.RawMessage(j.f.Payload)
+	if _, err := json.Marshal(payload); err != nil {
+		payload, _ = json.Marshal(j.f.Payload)
+	}
+
+	return &jobqueue.InspectedJob{
+		ID:         j.f.ID,
+		Category:   j.f.Category,
+		URL:        j.f.URL,
+		Payload:    payload,
+		Status:     j.f.Status,
+		CreatedAt:  FromMillisecond(j.f.CreatedAt),
+		NextTry:    FromMillisecond(j.f.NextTry),
+		Timeout:    j.f.Timeout,
+		FailCount:  j.f.FailCount,
+		MaxRetries: j.f.FailCount + j.f.RetryCount,
+		RetryDelay: j.f.RetryDelay,
+
+		ConcurrencyKey:   j.f.ConcurrencyKey,
+		ConcurrencyLimit: j.f.ConcurrencyLimit,
+		GroupID:          j.f.GroupID,
+	}
+}
+
+// NowMillisecond returns the current time in milliseconds.
+func NowMillisecond() uint64 {
+	return uint64(time.Now().UnixNano() / int64(time.Millisecond))
+}
+
+// FromMillisecond returns the time of t in milliseconds.
+func FromMillisecond(t uint64) time.Time {
+	secInMillisec := int64(time.Second / time.Millisecond)
+	return time.Unix(int64(t)/secInMillisec, int64(t)%secInMillisec*int64(time.Millisecond))
+}
@@ -9,8 +9,10 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/dispatcher"
@@ -18,6 +20,7 @@ import (
 	logwriter "github.com/fireworq/fireworq/log"
 	repository "github.com/fireworq/fireworq/repository/factory"
 	"github.com/fireworq/fireworq/service"
+	"github.com/fireworq/fireworq/snapshot"
 	"github.com/fireworq/fireworq/web"
 
 	"github.com/rs/zerolog"
@@ -56,9 +59,17 @@ func main() {
 	accessLog := initLogging(syscall.SIGUSR1)
 	initProcess()
 	dispatcher.Init()
+	initTLSReloading(syscall.SIGUSR1)
 	web.Init()
+	snapshotter := initSnapshot()
 
 	startServer(accessLog)
+
+	if snapshotter != nil {
+		if err := snapshotter.Stop(); err != nil {
+			log.Error().Msgf("Cannot save a snapshot: %s", err)
+		}
+	}
 }
 
 type cmdArgs struct {
@@ -140,6 +151,41 @@ func initProcess() {
 	}
 }
 
+func initTLSReloading(sig syscall.Signal) {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, sig)
+	go func() {
+		for {
+			s := <-sigc
+			log.Info().Msgf("Received signal %q; reload TLS certificates", s)
+			if err := dispatcher.ReloadTLS(); err != nil {
+				log.Error().Msg(err.Error())
+			}
+		}
+	}()
+}
+
+func initSnapshot() *snapshot.Snapshotter {
+	path := config.Get("inmemory_snapshot")
+	if path == "" {
+		return nil
+	}
+	if config.Get("driver") != "in-memory" && config.Get("queue_driver") != "in-memory" {
+		return nil
+	}
+
+	interval, err := strconv.Atoi(config.Get("inmemory_snapshot_interval"))
+	if err != nil {
+		log.Panic().Msg(err.Error())
+	}
+
+	s, err := snapshot.Start(path, time.Duration(interval)*time.Second)
+	if err != nil {
+		log.Panic().Msgf("Cannot restore a snapshot: %s", err)
+	}
+	return s
+}
+
 func initLogging(sig syscall.Signal) (accessLog logwriter.Writer) {
 	// Access log
 
@@ -205,11 +251,13 @@ func startServer(accessLogWriter io.Writer) {
 	service := service.NewService(repos)
 
 	app := &web.Application{
-		AccessLogWriter:   accessLogWriter,
-		Version:           versionString(" "),
-		Service:           service,
-		QueueRepository:   repos.Queue,
-		RoutingRepository: repos.Routing,
+		AccessLogWriter:       accessLogWriter,
+		Version:               versionString(" "),
+		Service:               service,
+		QueueRepository:       repos.Queue,
+		RoutingRepository:     repos.Routing,
+		HostLimitRepository:   repos.HostLimit,
+		QueueSecretRepository: repos.QueueSecret,
 	}
 	app.Serve()
 }
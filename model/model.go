@@ -7,10 +7,118 @@ type Queue struct {
 	MaxWorkers             uint    `json:"max_workers"`
 	MaxDispatchesPerSecond float64 `json:"max_dispatches_per_second,omitempty"`
 	MaxBurstSize           uint    `json:"max_burst_size,omitempty"`
+	GroupFailurePolicy     string  `json:"group_failure_policy,omitempty"`
+	DeadLetterQueue        string  `json:"dead_letter_queue,omitempty"`
+	DeadLetterCategory     string  `json:"dead_letter_category,omitempty"`
+	DeadLetterURL          string  `json:"dead_letter_url,omitempty"`
+	ResponseMode           string  `json:"response_mode,omitempty"`
+	TLSCertFile            string  `json:"tls_cert_file,omitempty"`
+	TLSKeyFile             string  `json:"tls_key_file,omitempty"`
+	TLSCAFile              string  `json:"tls_ca_file,omitempty"`
+	TLSMinVersion          string  `json:"tls_min_version,omitempty"`
+	KeepAlive              *bool   `json:"keep_alive,omitempty"`
+	MaxConnsPerHost        uint    `json:"max_conns_per_host,omitempty"`
+	MaxIdleConnsPerHost    uint    `json:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout        uint    `json:"idle_conn_timeout,omitempty"`
+	ProxyURL               string  `json:"proxy_url,omitempty"`
+	DefaultTimeout         uint    `json:"default_timeout,omitempty"`
+	WorkerType             string  `json:"worker_type,omitempty"`
+	ConsumptionMode        string  `json:"consumption_mode,omitempty"`
+	ShardPolicy            string  `json:"shard_policy,omitempty"`
+	FailureRetentionAge    uint    `json:"failure_retention_age,omitempty"`
+	FailureRetentionRows   uint    `json:"failure_retention_rows,omitempty"`
+
+	StatusMapping map[string]string `json:"status_mapping,omitempty"`
+	Command       []string          `json:"command,omitempty"`
+	Shards        []string          `json:"shards,omitempty"`
 }
 
+// Types of workers which handle jobs in a queue.
+const (
+	// WorkerTypeHTTP makes an HTTP request to the URL of a job.  This
+	// is the default.
+	WorkerTypeHTTP = "http"
+	// WorkerTypeCommand runs the command of the queue on the host of
+	// Fireworq for each job.
+	WorkerTypeCommand = "command"
+	// WorkerTypeProcess passes jobs to long-lived processes of the
+	// command of the queue on the host of Fireworq.
+	WorkerTypeProcess = "process"
+	// WorkerTypePull lets workers pull jobs through the API instead
+	// of dispatching them.
+	WorkerTypePull = "pull"
+)
+
+// Modes of consuming a queue by nodes in a cluster.
+const (
+	// ConsumptionModePrimaryBackup lets only one node dispatch jobs
+	// in the queue while the others back it up.  This is the
+	// default.
+	ConsumptionModePrimaryBackup = "primary-backup"
+	// ConsumptionModeActiveActive lets all the nodes dispatch jobs in
+	// the queue concurrently.
+	ConsumptionModeActiveActive = "active-active"
+)
+
+// Policies of distributing pushed jobs across the shards of a queue.
+// A job in a group is always put in the shard determined by hashing
+// its group ID so that the jobs in the group keep their order.
+const (
+	// ShardPolicyRoundRobin puts jobs in the shards in turn.  This is
+	// the default.
+	ShardPolicyRoundRobin = "round-robin"
+	// ShardPolicyHash puts a job in the shard determined by hashing
+	// its shard key, which is its group ID, its concurrency key or
+	// its category, whichever is found first.
+	ShardPolicyHash = "hash"
+)
+
+// MaxQueueShards is the maximum number of shards of a queue.
+const MaxQueueShards = 32
+
+// Modes of interpreting a response from a worker.
+const (
+	// ResponseModeJSON requires a JSON body with a result status.
+	// This is the default.
+	ResponseModeJSON = "json"
+	// ResponseModeStatus determines a result status by the HTTP
+	// status code of the response.
+	ResponseModeStatus = "status"
+)
+
+// Policies applied to a job group when its head job fails permanently.
+const (
+	// GroupFailurePolicySkip discards the failed job and proceeds to
+	// the next job in the group.  This is the default.
+	GroupFailurePolicySkip = "skip"
+	// GroupFailurePolicyBlock keeps the failed job at the head of the
+	// group and blocks the following jobs until it is deleted.
+	GroupFailurePolicyBlock = "block"
+)
+
 // Routing describes a routing.
 type Routing struct {
 	QueueName   string `json:"queue_name"`
 	JobCategory string `json:"job_category"`
 }
+
+// HostLimit describes limits of dispatching jobs to a destination
+// host, which are shared by all the queues.
+type HostLimit struct {
+	Host                 string  `json:"host"`
+	MaxConcurrency       uint    `json:"max_concurrency,omitempty"`
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second,omitempty"`
+	MaxBurstSize         uint    `json:"max_burst_size,omitempty"`
+}
+
+// QueueSecret describes secrets of a queue to sign requests to
+// workers.  Requests are signed with each of the secrets so that a
+// secret can be rotated by having a new one and the old one at the
+// same time.
+type QueueSecret struct {
+	QueueName string   `json:"queue_name"`
+	Secrets   []string `json:"secrets"`
+}
+
+// MaxQueueSecrets is the maximum number of active secrets of a queue.
+const MaxQueueSecrets = 2
@@ -5,6 +5,8 @@ import (
 	"github.com/fireworq/fireworq/repository"
 	"github.com/fireworq/fireworq/repository/inmemory"
 	"github.com/fireworq/fireworq/repository/mysql"
+	"github.com/fireworq/fireworq/repository/postgres"
+	"github.com/fireworq/fireworq/repository/sqlite"
 
 	"github.com/rs/zerolog/log"
 )
@@ -23,15 +25,47 @@ func NewRepositories() *repository.Repositories {
 		}
 
 		impl = &repository.Repositories{
-			Queue:   mysql.NewQueueRepository(db),
-			Routing: mysql.NewRoutingRepository(db),
+			Queue:       mysql.NewQueueRepository(db),
+			Routing:     mysql.NewRoutingRepository(db),
+			HostLimit:   mysql.NewHostLimitRepository(db),
+			QueueSecret: mysql.NewQueueSecretRepository(db),
+		}
+	}
+	if driver == "postgres" {
+		log.Info().Msg("Select postgres as a driver for repositories")
+		db, err := postgres.NewDB()
+		if err != nil {
+			log.Panic().Msg(err.Error())
+		}
+
+		impl = &repository.Repositories{
+			Queue:       postgres.NewQueueRepository(db),
+			Routing:     postgres.NewRoutingRepository(db),
+			HostLimit:   postgres.NewHostLimitRepository(db),
+			QueueSecret: postgres.NewQueueSecretRepository(db),
+		}
+	}
+	if driver == "sqlite" {
+		log.Info().Msg("Select sqlite as a driver for repositories")
+		db, err := sqlite.NewDB()
+		if err != nil {
+			log.Panic().Msg(err.Error())
+		}
+
+		impl = &repository.Repositories{
+			Queue:       sqlite.NewQueueRepository(db),
+			Routing:     sqlite.NewRoutingRepository(db),
+			HostLimit:   sqlite.NewHostLimitRepository(db),
+			QueueSecret: sqlite.NewQueueSecretRepository(db),
 		}
 	}
 	if driver == "in-memory" {
 		log.Info().Msg("Select in-memory as a driver for repositories")
 		impl = &repository.Repositories{
-			Queue:   inmemory.NewQueueRepository(),
-			Routing: inmemory.NewRoutingRepository(),
+			Queue:       inmemory.NewQueueRepository(),
+			Routing:     inmemory.NewRoutingRepository(),
+			HostLimit:   inmemory.NewHostLimitRepository(),
+			QueueSecret: inmemory.NewQueueSecretRepository(),
 		}
 	}
 
@@ -1,6 +1,7 @@
 package factory
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/fireworq/fireworq/model"
@@ -188,6 +189,31 @@ func TestQueue(t *testing.T) {
 	}
 }
 
+func TestQueueTransport(t *testing.T) {
+	repo := NewRepositories()
+
+	keepAlive := false
+	if _, err := repo.Queue.Add(&model.Queue{
+		Name:                "repo_queue_transport_test_queue",
+		KeepAlive:           &keepAlive,
+		MaxConnsPerHost:     20,
+		MaxIdleConnsPerHost: 5,
+		IdleConnTimeout:     30,
+	}); err != nil {
+		t.Error(err)
+	}
+	defer repo.Queue.DeleteByName("repo_queue_transport_test_queue")
+
+	q, err := repo.Queue.FindByName("repo_queue_transport_test_queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.KeepAlive == nil || *q.KeepAlive || q.MaxConnsPerHost != 20 ||
+		q.MaxIdleConnsPerHost != 5 || q.IdleConnTimeout != 30 {
+		t.Errorf("Transport settings of a queue can be retrieved: %#v", q)
+	}
+}
+
 func TestRouting(t *testing.T) {
 	repo := NewRepositories()
 
@@ -279,3 +305,229 @@ func TestRouting(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHostLimit(t *testing.T) {
+	repo := NewRepositories()
+
+	{
+		ls, err := repo.HostLimit.FindAll()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(ls) != 0 {
+			t.Error("There should be no host limit at first")
+		}
+	}
+
+	if u, err := repo.HostLimit.Add(&model.HostLimit{Host: "repo-host-limit-test-1.example.com", MaxConcurrency: 10}); !u || err != nil {
+		t.Errorf("updated = %v (should be true), error: %s", u, err)
+	}
+	if u, err := repo.HostLimit.Add(&model.HostLimit{
+		Host:                 "repo-host-limit-test-2.example.com:8080",
+		MaxRequestsPerSecond: 2.5,
+		MaxBurstSize:         5,
+	}); !u || err != nil {
+		t.Errorf("updated = %v (should be true), error: %s", u, err)
+	}
+
+	{
+		ls, err := repo.HostLimit.FindAll()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(ls) != 2 {
+			t.Error("There should be defined host limits")
+		}
+
+		if l := ls[0]; l.Host != "repo-host-limit-test-1.example.com" ||
+			l.MaxConcurrency != 10 || l.MaxRequestsPerSecond != 0.0 || l.MaxBurstSize != 0 {
+			t.Errorf("Defined host limits can be retrieved in host order: %#v", l)
+		}
+		if l := ls[1]; l.Host != "repo-host-limit-test-2.example.com:8080" ||
+			l.MaxConcurrency != 0 || l.MaxRequestsPerSecond != 2.5 || l.MaxBurstSize != 5 {
+			t.Errorf("Defined host limits can be retrieved in host order: %#v", l)
+		}
+	}
+
+	{
+		l, err := repo.HostLimit.FindByHost("repo-host-limit-test-1.example.com")
+		if err != nil {
+			t.Error(err)
+		}
+		if l.Host != "repo-host-limit-test-1.example.com" || l.MaxConcurrency != 10 {
+			t.Errorf("Defined host limit can be retrieved by host: %#v", l)
+		}
+	}
+
+	revision, err := repo.HostLimit.Revision()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if u, err := repo.HostLimit.Add(&model.HostLimit{Host: "repo-host-limit-test-1.example.com", MaxConcurrency: 10}); u || err != nil {
+		t.Errorf("updated = %v (should be false), error: %s", u, err)
+	}
+
+	revision1, err := repo.HostLimit.Revision()
+	if err != nil {
+		t.Error(err)
+	}
+	if revision1 != revision {
+		t.Errorf("Revision %d != %d", revision1, revision)
+	}
+
+	if u, err := repo.HostLimit.Add(&model.HostLimit{Host: "repo-host-limit-test-1.example.com", MaxConcurrency: 5}); !u || err != nil {
+		t.Errorf("updated = %v (should be true), error: %s", u, err)
+	}
+
+	revision2, err := repo.HostLimit.Revision()
+	if err != nil {
+		t.Error(err)
+	}
+	if revision2 <= revision {
+		t.Errorf("Revision !(%d > %d)", revision2, revision)
+	}
+
+	if err := repo.HostLimit.DeleteByHost("repo-host-limit-test-1.example.com"); err != nil {
+		t.Error(err)
+	}
+
+	{
+		l, err := repo.HostLimit.FindByHost("repo-host-limit-test-1.example.com")
+		if err == nil || l != nil {
+			t.Error("Deleted host limit should not be found")
+		}
+	}
+
+	if err := repo.HostLimit.DeleteByHost("repo-host-limit-test-2.example.com:8080"); err != nil {
+		t.Error(err)
+	}
+
+	{
+		ls, err := repo.HostLimit.FindAll()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(ls) != 0 {
+			t.Error("There should be no host limits")
+		}
+	}
+}
+
+func TestQueueSecret(t *testing.T) {
+	repo := NewRepositories()
+
+	{
+		ss, err := repo.QueueSecret.FindAll()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(ss) != 0 {
+			t.Error("There should be no queue secret at first")
+		}
+	}
+
+	if u, err := repo.QueueSecret.Add(&model.QueueSecret{QueueName: "repo_queue_secret_test_1", Secrets: []string{"foo"}}); !u || err != nil {
+		t.Errorf("updated = %v (should be true), error: %s", u, err)
+	}
+	if u, err := repo.QueueSecret.Add(&model.QueueSecret{QueueName: "repo_queue_secret_test_2", Secrets: []string{"bar", "baz"}}); !u || err != nil {
+		t.Errorf("updated = %v (should be true), error: %s", u, err)
+	}
+
+	{
+		ss, err := repo.QueueSecret.FindAll()
+		if err != nil {
+			t.Error(err)
+		}
+		if len(ss) != 2 {
+			t.Error("There should be defined queue secrets")
+		}
+
+		if s := ss[0]; s.QueueName != "repo_queue_secret_test_1" || !reflect.DeepEqual(s.Secrets, []string{"foo"}) {
+			t.Errorf("Defined queue secrets can be retrieved in name order: %#v", s)
+		}
+		if s := ss[1]; s.QueueName != "repo_queue_secret_test_2" || !reflect.DeepEqual(s.Secrets, []string{"bar", "baz"}) {
+			t.Errorf("Defined queue secrets can be retrieved in name order: %#v", s)
+		}
+	}
+
+	revision, err := repo.QueueSecret.Revision()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if u, err := repo.QueueSecret.Add(&model.QueueSecret{QueueName: "repo_queue_secret_test_1", Secrets: []string{"foo"}}); u || err != nil {
+		t.Errorf("updated = %v (should be false), error: %s", u, err)
+	}
+
+	revision1, err := repo.QueueSecret.Revision()
+	if err != nil {
+		t.Error(err)
+	}
+	if revision1 != revision {
+		t.Errorf("Revision %d != %d", revision1, revision)
+	}
+
+	if u, err := repo.QueueSecret.Add(&model.QueueSecret{QueueName: "repo_queue_secret_test_1", Secrets: []string{"qux", "foo"}}); !u || err != nil {
+		t.Errorf("updated = %v (should be true), error: %s", u, err)
+	}
+
+	revision2, err := repo.QueueSecret.Revision()
+	if err != nil {
+		t.Error(err)
+	}
+	if revision2 <= revision {
+		t.Errorf("Revision !(%d > %d)", revision2, revision)
+	}
+
+	{
+		s, err := repo.QueueSecret.FindByQueueName("repo_queue_secret_test_1")
+		if err != nil {
+			t.Error(err)
+		}
+		if s.QueueName != "repo_queue_secret_test_1" || !reflect.DeepEqual(s.Secrets, []string{"qux", "foo"}) {
+			t.Errorf("Defined queue secret can be retrieved by queue name: %#v", s)
+		}
+	}
+
+	for _, name := range []string{"repo_queue_secret_test_1", "repo_queue_secret_test_2"} {
+		if err := repo.QueueSecret.DeleteByQueueName(name); err != nil {
+			t.Error(err)
+		}
+	}
+
+	{
+		s, err := repo.QueueSecret.FindByQueueName("repo_queue_secret_test_1")
+		if err == nil || s != nil {
+			t.Error("Deleted queue secret should not be found")
+		}
+	}
+}
+
+func TestQueueSecretDeletedWithQueue(t *testing.T) {
+	repo := NewRepositories()
+
+	name := "repo_queue_secret_deleted_test_queue"
+	if _, err := repo.Queue.Add(&model.Queue{Name: name}); err != nil {
+		t.Error(err)
+	}
+	if _, err := repo.QueueSecret.Add(&model.QueueSecret{QueueName: name, Secrets: []string{"foo"}}); err != nil {
+		t.Error(err)
+	}
+
+	revision, err := repo.QueueSecret.Revision()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := repo.Queue.DeleteByName(name); err != nil {
+		t.Error(err)
+	}
+
+	if s, err := repo.QueueSecret.FindByQueueName(name); err == nil || s != nil {
+		t.Error("A secret of a deleted queue should not be found")
+	}
+	if r, err := repo.QueueSecret.Revision(); err != nil || r == revision {
+		t.Error("Deleting a queue should update the revision of queue secrets")
+	}
+}
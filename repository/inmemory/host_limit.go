@@ -0,0 +1,84 @@
+package inmemory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/repository"
+)
+
+type hostLimitStorage struct {
+	sync.RWMutex
+	m        map[string]model.HostLimit
+	revision uint64
+}
+
+var hls = &hostLimitStorage{m: make(map[string]model.HostLimit)}
+
+type hostLimitRepository struct{}
+
+// NewHostLimitRepository creates a new repository.HostLimitRepository
+// which uses in-memory data store.
+func NewHostLimitRepository() repository.HostLimitRepository {
+	return &hostLimitRepository{}
+}
+
+func (r *hostLimitRepository) Add(l *model.HostLimit) (bool, error) {
+	hls.Lock()
+	defer hls.Unlock()
+
+	if current, ok := hls.m[l.Host]; !ok || current != *l {
+		hls.m[l.Host] = *l
+		r.updateRevision()
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (r *hostLimitRepository) FindAll() ([]model.HostLimit, error) {
+	hls.RLock()
+	defer hls.RUnlock()
+
+	limits := make([]model.HostLimit, 0, len(hls.m))
+	for _, l := range hls.m {
+		limits = append(limits, l)
+	}
+
+	sort.Slice(limits, func(i, j int) bool {
+		return limits[i].Host < limits[j].Host
+	})
+
+	return limits, nil
+}
+
+func (r *hostLimitRepository) FindByHost(host string) (*model.HostLimit, error) {
+	hls.RLock()
+	defer hls.RUnlock()
+
+	l, ok := hls.m[host]
+	if !ok {
+		return nil, errors.New("Host limit not found")
+	}
+	return &l, nil
+}
+
+func (r *hostLimitRepository) DeleteByHost(host string) error {
+	hls.Lock()
+	defer hls.Unlock()
+
+	delete(hls.m, host)
+	r.updateRevision()
+	return nil
+}
+
+func (r *hostLimitRepository) updateRevision() {
+	atomic.AddUint64(&hls.revision, 1)
+}
+
+func (r *hostLimitRepository) Revision() (uint64, error) {
+	return atomic.LoadUint64(&hls.revision), nil
+}
@@ -75,7 +75,7 @@ func (r *queueRepository) DeleteByName(name string) error {
 
 	delete(qs.m, name)
 	r.updateRevision()
-	return nil
+	return (&queueSecretRepository{}).DeleteByQueueName(name)
 }
 
 func (r *queueRepository) updateRevision() {
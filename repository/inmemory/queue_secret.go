@@ -0,0 +1,87 @@
+package inmemory
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/repository"
+)
+
+type queueSecretStorage struct {
+	sync.RWMutex
+	m        map[string]model.QueueSecret
+	revision uint64
+}
+
+var qss = &queueSecretStorage{m: make(map[string]model.QueueSecret)}
+
+type queueSecretRepository struct{}
+
+// NewQueueSecretRepository creates a new
+// repository.QueueSecretRepository which uses in-memory data store.
+func NewQueueSecretRepository() repository.QueueSecretRepository {
+	return &queueSecretRepository{}
+}
+
+func (r *queueSecretRepository) Add(s *model.QueueSecret) (bool, error) {
+	qss.Lock()
+	defer qss.Unlock()
+
+	if current, ok := qss.m[s.QueueName]; !ok || !reflect.DeepEqual(current.Secrets, s.Secrets) {
+		secrets := make([]string, len(s.Secrets))
+		copy(secrets, s.Secrets)
+		qss.m[s.QueueName] = model.QueueSecret{QueueName: s.QueueName, Secrets: secrets}
+		r.updateRevision()
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (r *queueSecretRepository) FindAll() ([]model.QueueSecret, error) {
+	qss.RLock()
+	defer qss.RUnlock()
+
+	secrets := make([]model.QueueSecret, 0, len(qss.m))
+	for _, s := range qss.m {
+		secrets = append(secrets, s)
+	}
+
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].QueueName < secrets[j].QueueName
+	})
+
+	return secrets, nil
+}
+
+func (r *queueSecretRepository) FindByQueueName(queueName string) (*model.QueueSecret, error) {
+	qss.RLock()
+	defer qss.RUnlock()
+
+	s, ok := qss.m[queueName]
+	if !ok {
+		return nil, errors.New("Queue secret not found")
+	}
+	return &s, nil
+}
+
+func (r *queueSecretRepository) DeleteByQueueName(queueName string) error {
+	qss.Lock()
+	defer qss.Unlock()
+
+	delete(qss.m, queueName)
+	r.updateRevision()
+	return nil
+}
+
+func (r *queueSecretRepository) updateRevision() {
+	atomic.AddUint64(&qss.revision, 1)
+}
+
+func (r *queueSecretRepository) Revision() (uint64, error) {
+	return atomic.LoadUint64(&qss.revision), nil
+}
@@ -0,0 +1,68 @@
+package inmemory
+
+import (
+	"encoding/json"
+
+	"github.com/fireworq/fireworq/model"
+)
+
+type snapshot struct {
+	Queues       []model.Queue       `json:"queues"`
+	Routings     []model.Routing     `json:"routings"`
+	HostLimits   []model.HostLimit   `json:"host_limits"`
+	QueueSecrets []model.QueueSecret `json:"queue_secrets"`
+}
+
+// Snapshot serializes all the repositories.
+func Snapshot() ([]byte, error) {
+	var err error
+	s := &snapshot{}
+	if s.Queues, err = NewQueueRepository().FindAll(); err != nil {
+		return nil, err
+	}
+	if s.Routings, err = NewRoutingRepository().FindAll(); err != nil {
+		return nil, err
+	}
+	if s.HostLimits, err = NewHostLimitRepository().FindAll(); err != nil {
+		return nil, err
+	}
+	if s.QueueSecrets, err = NewQueueSecretRepository().FindAll(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// Restore adds the items in the data serialized by Snapshot to the
+// repositories.
+func Restore(data []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	for _, q := range s.Queues {
+		q := q
+		if _, err := NewQueueRepository().Add(&q); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.Routings {
+		if _, err := NewRoutingRepository().Add(r.JobCategory, r.QueueName); err != nil {
+			return err
+		}
+	}
+	for _, l := range s.HostLimits {
+		l := l
+		if _, err := NewHostLimitRepository().Add(&l); err != nil {
+			return err
+		}
+	}
+	for _, secret := range s.QueueSecrets {
+		secret := secret
+		if _, err := NewQueueSecretRepository().Add(&secret); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,4 +1,4 @@
-//go:generate go-assets-builder -p mysql -o assets.go ../../data/repository
+//go:generate go-assets-builder -p mysql -o assets.go ../../data/repository/mysql
 
 package mysql
 
@@ -19,7 +19,18 @@ func init() {
 	schema = []string{
 		"/data/repository/mysql/schema/queue.sql",
 		"/data/repository/mysql/schema/queue_throttle.sql",
+		"/data/repository/mysql/schema/queue_group.sql",
+		"/data/repository/mysql/schema/queue_dead_letter.sql",
+		"/data/repository/mysql/schema/queue_response.sql",
+		"/data/repository/mysql/schema/queue_tls.sql",
+		"/data/repository/mysql/schema/queue_transport.sql",
+		"/data/repository/mysql/schema/queue_worker.sql",
+		"/data/repository/mysql/schema/queue_consumption.sql",
+		"/data/repository/mysql/schema/queue_shard.sql",
+		"/data/repository/mysql/schema/queue_failure_retention.sql",
+		"/data/repository/mysql/schema/queue_secret.sql",
 		"/data/repository/mysql/schema/routing.sql",
+		"/data/repository/mysql/schema/host_limit.sql",
 		"/data/repository/mysql/schema/config_revision.sql",
 	}
 }
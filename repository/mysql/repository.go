@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/fireworq/fireworq/repository"
+	"github.com/fireworq/fireworq/repository/sqlrepo"
+)
+
+// NewQueueRepository creates a repository.QueueRepository which uses
+// MySQL as a data store.
+func NewQueueRepository(db *sql.DB) repository.QueueRepository {
+	return sqlrepo.NewQueueRepository(db, dialect{})
+}
+
+// NewRoutingRepository creates a repository.RoutingRepository which uses
+// MySQL as a data store.
+func NewRoutingRepository(db *sql.DB) repository.RoutingRepository {
+	return sqlrepo.NewRoutingRepository(db, dialect{})
+}
+
+// NewHostLimitRepository creates a repository.HostLimitRepository
+// which uses MySQL as a data store.
+func NewHostLimitRepository(db *sql.DB) repository.HostLimitRepository {
+	return sqlrepo.NewHostLimitRepository(db, dialect{})
+}
+
+// NewQueueSecretRepository creates a repository.QueueSecretRepository
+// which uses MySQL as a data store.
+func NewQueueSecretRepository(db *sql.DB) repository.QueueSecretRepository {
+	return sqlrepo.NewQueueSecretRepository(db, dialect{})
+}
+
+// dialect : implements sqlrepo.Dialect
+type dialect struct{}
+
+func (dialect) Placeholder(n int) string {
+	return "?"
+}
+
+// Upsert returns an ON DUPLICATE KEY UPDATE clause.  MySQL reports no
+// affected rows if the values are unchanged.
+func (dialect) Upsert(table, key string, columns ...string) string {
+	sets := make([]string, len(columns))
+	for i, c := range columns {
+		sets[i] = c + " = VALUES(" + c + ")"
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (dialect) Increment(table, key, column string) string {
+	return "ON DUPLICATE KEY UPDATE " + column + " = " + column + " + 1"
+}
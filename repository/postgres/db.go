@@ -0,0 +1,77 @@
+//go:generate go-assets-builder -p postgres -o assets.go ../../data/repository/postgres
+
+package postgres
+
+import (
+	"database/sql"
+	"io/ioutil"
+
+	"github.com/fireworq/fireworq/config"
+
+	_ "github.com/lib/pq" // initialize the driver
+	"github.com/rs/zerolog/log"
+)
+
+var schema []string
+
+func init() {
+	schema = []string{
+		"/data/repository/postgres/schema/queue.sql",
+		"/data/repository/postgres/schema/queue_throttle.sql",
+		"/data/repository/postgres/schema/queue_group.sql",
+		"/data/repository/postgres/schema/queue_dead_letter.sql",
+		"/data/repository/postgres/schema/queue_response.sql",
+		"/data/repository/postgres/schema/queue_tls.sql",
+		"/data/repository/postgres/schema/queue_transport.sql",
+		"/data/repository/postgres/schema/queue_worker.sql",
+		"/data/repository/postgres/schema/queue_consumption.sql",
+		"/data/repository/postgres/schema/queue_shard.sql",
+		"/data/repository/postgres/schema/queue_failure_retention.sql",
+		"/data/repository/postgres/schema/queue_secret.sql",
+		"/data/repository/postgres/schema/routing.sql",
+		"/data/repository/postgres/schema/host_limit.sql",
+		"/data/repository/postgres/schema/config_revision.sql",
+	}
+}
+
+// Dsn returns the data source name of the storage specified in the
+// configuration.
+func Dsn() string {
+	dsn := config.Get("repository_postgres_dsn")
+	if dsn != "" {
+		return dsn
+	}
+	return config.Get("postgres_dsn")
+}
+
+// NewDB creates an instance of DB handler.
+func NewDB() (*sql.DB, error) {
+	dsn := Dsn()
+
+	log.Info().Msgf("Connecting database %s ...", dsn)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range schema {
+		f, err := Assets.Open(path)
+		if err != nil {
+			log.Panic().Msg(err.Error())
+		}
+
+		query, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			log.Panic().Msg(err.Error())
+		}
+
+		_, err = db.Exec(string(query))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
@@ -21,8 +21,29 @@ type RoutingRepository interface {
 	Reload() error
 }
 
-// Repositories contains a queue repository and a routing repository.
+// HostLimitRepository is an interface of a host limit repository.
+type HostLimitRepository interface {
+	Add(l *model.HostLimit) (bool, error)
+	FindAll() ([]model.HostLimit, error)
+	FindByHost(host string) (*model.HostLimit, error)
+	DeleteByHost(host string) error
+	Revision() (uint64, error)
+}
+
+// QueueSecretRepository is an interface of a queue secret repository.
+type QueueSecretRepository interface {
+	Add(s *model.QueueSecret) (bool, error)
+	FindAll() ([]model.QueueSecret, error)
+	FindByQueueName(queueName string) (*model.QueueSecret, error)
+	DeleteByQueueName(queueName string) error
+	Revision() (uint64, error)
+}
+
+// Repositories contains a queue repository, a routing repository, a
+// host limit repository and a queue secret repository.
 type Repositories struct {
-	Queue   QueueRepository
-	Routing RoutingRepository
+	Queue       QueueRepository
+	Routing     RoutingRepository
+	HostLimit   HostLimitRepository
+	QueueSecret QueueSecretRepository
 }
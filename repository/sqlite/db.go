@@ -0,0 +1,86 @@
+//go:generate go-assets-builder -p sqlite -o assets.go ../../data/repository/sqlite
+
+package sqlite
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fireworq/fireworq/config"
+
+	"github.com/rs/zerolog/log"
+	_ "modernc.org/sqlite" // initialize the driver
+)
+
+var schema []string
+
+func init() {
+	schema = []string{
+		"/data/repository/sqlite/schema/queue.sql",
+		"/data/repository/sqlite/schema/queue_throttle.sql",
+		"/data/repository/sqlite/schema/queue_group.sql",
+		"/data/repository/sqlite/schema/queue_dead_letter.sql",
+		"/data/repository/sqlite/schema/queue_response.sql",
+		"/data/repository/sqlite/schema/queue_tls.sql",
+		"/data/repository/sqlite/schema/queue_transport.sql",
+		"/data/repository/sqlite/schema/queue_worker.sql",
+		"/data/repository/sqlite/schema/queue_consumption.sql",
+		"/data/repository/sqlite/schema/queue_shard.sql",
+		"/data/repository/sqlite/schema/queue_failure_retention.sql",
+		"/data/repository/sqlite/schema/queue_secret.sql",
+		"/data/repository/sqlite/schema/routing.sql",
+		"/data/repository/sqlite/schema/host_limit.sql",
+		"/data/repository/sqlite/schema/config_revision.sql",
+	}
+}
+
+// Dir returns the directory of the storage specified in the
+// configuration.
+func Dir() string {
+	return config.Get("sqlite_dir")
+}
+
+// Dsn returns the data source name of the database file in a
+// directory.
+func Dsn(dir string) string {
+	path := filepath.Join(dir, "fireworq.db")
+	return "file:" + path + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+}
+
+// NewDB creates an instance of DB handler.
+func NewDB() (*sql.DB, error) {
+	dir := Dir()
+
+	log.Info().Msgf("Opening database in %s ...", dir)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", Dsn(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range schema {
+		f, err := Assets.Open(path)
+		if err != nil {
+			log.Panic().Msg(err.Error())
+		}
+
+		query, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			log.Panic().Msg(err.Error())
+		}
+
+		_, err = db.Exec(string(query))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/fireworq/fireworq/repository"
+	"github.com/fireworq/fireworq/repository/sqlrepo"
+)
+
+// NewQueueRepository creates a repository.QueueRepository which uses
+// SQLite as a data store.
+func NewQueueRepository(db *sql.DB) repository.QueueRepository {
+	return sqlrepo.NewQueueRepository(db, dialect{})
+}
+
+// NewRoutingRepository creates a repository.RoutingRepository which uses
+// SQLite as a data store.
+func NewRoutingRepository(db *sql.DB) repository.RoutingRepository {
+	return sqlrepo.NewRoutingRepository(db, dialect{})
+}
+
+// NewHostLimitRepository creates a repository.HostLimitRepository
+// which uses SQLite as a data store.
+func NewHostLimitRepository(db *sql.DB) repository.HostLimitRepository {
+	return sqlrepo.NewHostLimitRepository(db, dialect{})
+}
+
+// NewQueueSecretRepository creates a repository.QueueSecretRepository
+// which uses SQLite as a data store.
+func NewQueueSecretRepository(db *sql.DB) repository.QueueSecretRepository {
+	return sqlrepo.NewQueueSecretRepository(db, dialect{})
+}
+
+// dialect : implements sqlrepo.Dialect
+type dialect struct{}
+
+func (dialect) Placeholder(n int) string {
+	return "?"
+}
+
+// Upsert returns an ON CONFLICT clause, which skips the update if the
+// values are unchanged so that no rows are affected.
+func (dialect) Upsert(table, key string, columns ...string) string {
+	sets := make([]string, len(columns))
+	olds := make([]string, len(columns))
+	news := make([]string, len(columns))
+	for i, c := range columns {
+		sets[i] = c + " = excluded." + c
+		olds[i] = table + "." + c
+		news[i] = "excluded." + c
+	}
+	return "ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(sets, ", ") +
+		" WHERE " + row(olds) + " IS NOT " + row(news)
+}
+
+func (dialect) Increment(table, key, column string) string {
+	return "ON CONFLICT (" + key + ") DO UPDATE SET " + column + " = " + table + "." + column + " + 1"
+}
+
+func row(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
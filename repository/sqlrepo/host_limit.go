@@ -0,0 +1,120 @@
+package sqlrepo
+
+import (
+	"database/sql"
+
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/repository"
+)
+
+type hostLimitRepository struct {
+	db *database
+}
+
+// NewHostLimitRepository creates a repository.HostLimitRepository
+// which uses a SQL database in a dialect as a data store.
+func NewHostLimitRepository(db *sql.DB, dialect Dialect) repository.HostLimitRepository {
+	return &hostLimitRepository{db: &database{db, dialect}}
+}
+
+func (r *hostLimitRepository) Add(l *model.HostLimit) (bool, error) {
+	sql := `
+		INSERT INTO host_limit (host, max_concurrency, max_requests_per_second, max_burst_size)
+		VALUES ( ?, ?, ?, ? )
+	` + r.db.dialect.Upsert("host_limit", "host", "max_concurrency", "max_requests_per_second", "max_burst_size")
+	res, err := r.db.Exec(sql, l.Host, l.MaxConcurrency, l.MaxRequestsPerSecond, l.MaxBurstSize)
+	if err != nil {
+		return false, err
+	}
+
+	updated := false
+	i, err := res.RowsAffected()
+	if err == nil {
+		updated = i != 0
+	}
+
+	if updated {
+		return updated, r.updateRevision()
+	}
+	return updated, nil
+}
+
+func (r *hostLimitRepository) FindAll() ([]model.HostLimit, error) {
+	sql := `
+		SELECT host, max_concurrency, max_requests_per_second, max_burst_size
+		FROM host_limit
+		ORDER BY host ASC
+	`
+	rows, err := r.db.Query(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.HostLimit, 0)
+	for rows.Next() {
+		var l model.HostLimit
+		if err := rows.Scan(&(l.Host), &(l.MaxConcurrency), &(l.MaxRequestsPerSecond), &(l.MaxBurstSize)); err != nil {
+			return nil, err
+		}
+		results = append(results, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *hostLimitRepository) FindByHost(host string) (*model.HostLimit, error) {
+	sql := `
+		SELECT host, max_concurrency, max_requests_per_second, max_burst_size
+		FROM host_limit
+		WHERE host = ?
+	`
+
+	l := &model.HostLimit{}
+	err := r.db.QueryRow(sql, host).Scan(
+		&(l.Host),
+		&(l.MaxConcurrency),
+		&(l.MaxRequestsPerSecond),
+		&(l.MaxBurstSize),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (r *hostLimitRepository) DeleteByHost(host string) error {
+	sql := `
+		DELETE FROM host_limit
+		WHERE host = ?
+	`
+	_, err := r.db.Exec(sql, host)
+	if err != nil {
+		return err
+	}
+
+	return r.updateRevision()
+}
+
+func (r *hostLimitRepository) Revision() (uint64, error) {
+	var revision uint64
+	if err := r.db.QueryRow(`
+		SELECT revision FROM config_revision
+		WHERE name = 'host_limit'
+	`).Scan(&revision); err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+func (r *hostLimitRepository) updateRevision() error {
+	_, err := r.db.Exec(`
+		INSERT INTO config_revision (name, revision)
+		VALUES ('host_limit', 1)
+	` + r.db.dialect.Increment("config_revision", "name", "revision"))
+	return err
+}
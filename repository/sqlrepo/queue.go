@@ -0,0 +1,1053 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/repository"
+)
+
+type queueRepository struct {
+	db *database
+}
+
+// NewQueueRepository creates a repository.QueueRepository which uses
+// a SQL database in a dialect as a data store.
+func NewQueueRepository(db *sql.DB, dialect Dialect) repository.QueueRepository {
+	return &queueRepository{db: &database{db, dialect}}
+}
+
+func (r *queueRepository) Add(q *model.Queue) (bool, error) {
+	updated := false
+
+	sql := `
+		INSERT INTO queue (name, polling_interval, max_workers)
+		VALUES ( ?, ?, ? )
+	` + r.db.dialect.Upsert("queue", "name", "polling_interval", "max_workers")
+	res, err := r.db.Exec(sql, q.Name, q.PollingInterval, q.MaxWorkers)
+	if err != nil {
+		return updated, err
+	}
+	i, err := res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	sql = `
+		INSERT INTO queue_throttle (name, max_dispatches_per_second, max_burst_size)
+		VALUES ( ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_throttle", "name", "max_dispatches_per_second", "max_burst_size")
+	res, err = r.db.Exec(sql, q.Name, q.MaxDispatchesPerSecond, q.MaxBurstSize)
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	sql = `
+		INSERT INTO queue_group (name, group_failure_policy)
+		VALUES ( ?, ? )
+	` + r.db.dialect.Upsert("queue_group", "name", "group_failure_policy")
+	res, err = r.db.Exec(sql, q.Name, q.GroupFailurePolicy)
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	sql = `
+		INSERT INTO queue_dead_letter (name, dead_letter_queue, dead_letter_category, dead_letter_url)
+		VALUES ( ?, ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_dead_letter", "name", "dead_letter_queue", "dead_letter_category", "dead_letter_url")
+	res, err = r.db.Exec(sql, q.Name, q.DeadLetterQueue, q.DeadLetterCategory, q.DeadLetterURL)
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	mapping := []byte{}
+	if len(q.StatusMapping) > 0 {
+		mapping, err = json.Marshal(q.StatusMapping)
+		if err != nil {
+			return updated, err
+		}
+	}
+	sql = `
+		INSERT INTO queue_response (name, response_mode, status_mapping)
+		VALUES ( ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_response", "name", "response_mode", "status_mapping")
+	res, err = r.db.Exec(sql, q.Name, q.ResponseMode, string(mapping))
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	sql = `
+		INSERT INTO queue_tls (name, tls_cert_file, tls_key_file, tls_ca_file, tls_min_version)
+		VALUES ( ?, ?, ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_tls", "name", "tls_cert_file", "tls_key_file", "tls_ca_file", "tls_min_version")
+	res, err = r.db.Exec(sql, q.Name, q.TLSCertFile, q.TLSKeyFile, q.TLSCAFile, q.TLSMinVersion)
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	sql = `
+		INSERT INTO queue_transport (name, keep_alive, max_conns_per_host, max_idle_conns_per_host, idle_conn_timeout, proxy_url, default_timeout)
+		VALUES ( ?, ?, ?, ?, ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_transport", "name", "keep_alive", "max_conns_per_host", "max_idle_conns_per_host", "idle_conn_timeout", "proxy_url", "default_timeout")
+	res, err = r.db.Exec(sql, q.Name, q.KeepAlive, q.MaxConnsPerHost, q.MaxIdleConnsPerHost, q.IdleConnTimeout, q.ProxyURL, q.DefaultTimeout)
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	var command []byte
+	if len(q.Command) > 0 {
+		command, err = json.Marshal(q.Command)
+		if err != nil {
+			return updated, err
+		}
+	}
+	sql = `
+		INSERT INTO queue_worker (name, worker_type, command)
+		VALUES ( ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_worker", "name", "worker_type", "command")
+	res, err = r.db.Exec(sql, q.Name, q.WorkerType, string(command))
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	sql = `
+		INSERT INTO queue_consumption (name, consumption_mode)
+		VALUES ( ?, ? )
+	` + r.db.dialect.Upsert("queue_consumption", "name", "consumption_mode")
+	res, err = r.db.Exec(sql, q.Name, q.ConsumptionMode)
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	var shards []byte
+	if len(q.Shards) > 0 {
+		shards, err = json.Marshal(q.Shards)
+		if err != nil {
+			return updated, err
+		}
+	}
+	sql = `
+		INSERT INTO queue_shard (name, shard_policy, shards)
+		VALUES ( ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_shard", "name", "shard_policy", "shards")
+	res, err = r.db.Exec(sql, q.Name, q.ShardPolicy, string(shards))
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	sql = `
+		INSERT INTO queue_failure_retention (name, max_age, max_rows)
+		VALUES ( ?, ?, ? )
+	` + r.db.dialect.Upsert("queue_failure_retention", "name", "max_age", "max_rows")
+	res, err = r.db.Exec(sql, q.Name, q.FailureRetentionAge, q.FailureRetentionRows)
+	if err != nil {
+		return updated, err
+	}
+	i, err = res.RowsAffected()
+	if err == nil {
+		updated = updated || (i != 0)
+	}
+
+	if updated {
+		return updated, r.updateRevision()
+	}
+	return updated, nil
+}
+
+func (r *queueRepository) FindAll() ([]model.Queue, error) {
+	sql := `
+		SELECT name, polling_interval, max_workers
+		FROM queue
+		ORDER BY name ASC
+	`
+	rows, err := r.db.Query(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.Queue, 0)
+	for rows.Next() {
+		var q model.Queue
+		if err := rows.Scan(&(q.Name), &(q.PollingInterval), &(q.MaxWorkers)); err != nil {
+			return nil, err
+		}
+		results = append(results, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(results))
+	for i, q := range results {
+		names[i] = q.Name
+	}
+	throttles, err := r.findQueueThrottles(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if throttle, ok := throttles[q.Name]; ok {
+			results[i].MaxDispatchesPerSecond = throttle.maxDispatchesPerSecond
+			results[i].MaxBurstSize = throttle.maxBurstSize
+		}
+	}
+
+	policies, err := r.findGroupFailurePolicies(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		results[i].GroupFailurePolicy = policies[q.Name]
+	}
+
+	deadLetters, err := r.findQueueDeadLetters(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if deadLetter, ok := deadLetters[q.Name]; ok {
+			results[i].DeadLetterQueue = deadLetter.queue
+			results[i].DeadLetterCategory = deadLetter.category
+			results[i].DeadLetterURL = deadLetter.url
+		}
+	}
+
+	responses, err := r.findQueueResponses(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if response, ok := responses[q.Name]; ok {
+			results[i].ResponseMode = response.mode
+			results[i].StatusMapping = response.statusMapping
+		}
+	}
+
+	tlss, err := r.findQueueTLSs(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if tls, ok := tlss[q.Name]; ok {
+			results[i].TLSCertFile = tls.certFile
+			results[i].TLSKeyFile = tls.keyFile
+			results[i].TLSCAFile = tls.caFile
+			results[i].TLSMinVersion = tls.minVersion
+		}
+	}
+
+	transports, err := r.findQueueTransports(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if transport, ok := transports[q.Name]; ok {
+			transport.apply(&results[i])
+		}
+	}
+
+	workers, err := r.findQueueWorkers(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if worker, ok := workers[q.Name]; ok {
+			results[i].WorkerType = worker.workerType
+			results[i].Command = worker.command
+		}
+	}
+
+	modes, err := r.findConsumptionModes(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		results[i].ConsumptionMode = modes[q.Name]
+	}
+
+	shards, err := r.findQueueShards(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if shard, ok := shards[q.Name]; ok {
+			results[i].ShardPolicy = shard.shardPolicy
+			results[i].Shards = shard.shards
+		}
+	}
+
+	retentions, err := r.findFailureRetentions(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range results {
+		if retention, ok := retentions[q.Name]; ok {
+			results[i].FailureRetentionAge = retention.maxAge
+			results[i].FailureRetentionRows = retention.maxRows
+		}
+	}
+
+	return results, nil
+}
+
+func (r *queueRepository) FindByName(name string) (*model.Queue, error) {
+	sql := `
+		SELECT name, polling_interval, max_workers FROM queue
+		WHERE name = ?
+	`
+
+	queue := &model.Queue{}
+	err := r.db.QueryRow(sql, name).Scan(
+		&(queue.Name),
+		&(queue.PollingInterval),
+		&(queue.MaxWorkers),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	throttles, err := r.findQueueThrottles([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if throttle, ok := throttles[queue.Name]; ok {
+		queue.MaxDispatchesPerSecond = throttle.maxDispatchesPerSecond
+		queue.MaxBurstSize = throttle.maxBurstSize
+	}
+
+	policies, err := r.findGroupFailurePolicies([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	queue.GroupFailurePolicy = policies[queue.Name]
+
+	deadLetters, err := r.findQueueDeadLetters([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if deadLetter, ok := deadLetters[queue.Name]; ok {
+		queue.DeadLetterQueue = deadLetter.queue
+		queue.DeadLetterCategory = deadLetter.category
+		queue.DeadLetterURL = deadLetter.url
+	}
+
+	responses, err := r.findQueueResponses([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if response, ok := responses[queue.Name]; ok {
+		queue.ResponseMode = response.mode
+		queue.StatusMapping = response.statusMapping
+	}
+
+	tlss, err := r.findQueueTLSs([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if tls, ok := tlss[queue.Name]; ok {
+		queue.TLSCertFile = tls.certFile
+		queue.TLSKeyFile = tls.keyFile
+		queue.TLSCAFile = tls.caFile
+		queue.TLSMinVersion = tls.minVersion
+	}
+
+	transports, err := r.findQueueTransports([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if transport, ok := transports[queue.Name]; ok {
+		transport.apply(queue)
+	}
+
+	workers, err := r.findQueueWorkers([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if worker, ok := workers[queue.Name]; ok {
+		queue.WorkerType = worker.workerType
+		queue.Command = worker.command
+	}
+
+	modes, err := r.findConsumptionModes([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	queue.ConsumptionMode = modes[queue.Name]
+
+	shards, err := r.findQueueShards([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if shard, ok := shards[queue.Name]; ok {
+		queue.ShardPolicy = shard.shardPolicy
+		queue.Shards = shard.shards
+	}
+
+	retentions, err := r.findFailureRetentions([]string{queue.Name})
+	if err != nil {
+		return nil, err
+	}
+	if retention, ok := retentions[queue.Name]; ok {
+		queue.FailureRetentionAge = retention.maxAge
+		queue.FailureRetentionRows = retention.maxRows
+	}
+
+	return queue, nil
+}
+
+type queueThrottle struct {
+	maxDispatchesPerSecond float64
+	maxBurstSize           uint
+}
+
+func (r *queueRepository) findQueueThrottles(names []string) (map[string]queueThrottle, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, max_dispatches_per_second, max_burst_size
+		FROM queue_throttle
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name                   string
+		maxDispatchesPerSecond float64
+		maxBurstSize           uint
+		throttleByName         = make(map[string]queueThrottle, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &maxDispatchesPerSecond, &maxBurstSize); err != nil {
+			return nil, err
+		}
+		throttleByName[name] = queueThrottle{maxDispatchesPerSecond, maxBurstSize}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return throttleByName, nil
+}
+
+func (r *queueRepository) findGroupFailurePolicies(names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, group_failure_policy
+		FROM queue_group
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name         string
+		policy       string
+		policyByName = make(map[string]string, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &policy); err != nil {
+			return nil, err
+		}
+		policyByName[name] = policy
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return policyByName, nil
+}
+
+type queueDeadLetter struct {
+	queue    string
+	category string
+	url      string
+}
+
+func (r *queueRepository) findQueueDeadLetters(names []string) (map[string]queueDeadLetter, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, dead_letter_queue, dead_letter_category, dead_letter_url
+		FROM queue_dead_letter
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name             string
+		deadLetter       queueDeadLetter
+		deadLetterByName = make(map[string]queueDeadLetter, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &deadLetter.queue, &deadLetter.category, &deadLetter.url); err != nil {
+			return nil, err
+		}
+		deadLetterByName[name] = deadLetter
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deadLetterByName, nil
+}
+
+type queueResponse struct {
+	mode          string
+	statusMapping map[string]string
+}
+
+func (r *queueRepository) findQueueResponses(names []string) (map[string]queueResponse, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, response_mode, status_mapping
+		FROM queue_response
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name           string
+		mode           string
+		mapping        []byte
+		responseByName = make(map[string]queueResponse, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &mode, &mapping); err != nil {
+			return nil, err
+		}
+		response := queueResponse{mode: mode}
+		if len(mapping) > 0 {
+			if err := json.Unmarshal(mapping, &response.statusMapping); err != nil {
+				return nil, err
+			}
+		}
+		responseByName[name] = response
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return responseByName, nil
+}
+
+type queueTLS struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	minVersion string
+}
+
+func (r *queueRepository) findQueueTLSs(names []string) (map[string]queueTLS, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, tls_cert_file, tls_key_file, tls_ca_file, tls_min_version
+		FROM queue_tls
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name      string
+		tls       queueTLS
+		tlsByName = make(map[string]queueTLS, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &tls.certFile, &tls.keyFile, &tls.caFile, &tls.minVersion); err != nil {
+			return nil, err
+		}
+		tlsByName[name] = tls
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tlsByName, nil
+}
+
+type queueTransport struct {
+	keepAlive           sql.NullBool
+	maxConnsPerHost     uint
+	maxIdleConnsPerHost uint
+	idleConnTimeout     uint
+	proxyURL            string
+	defaultTimeout      uint
+}
+
+func (t queueTransport) apply(q *model.Queue) {
+	if t.keepAlive.Valid {
+		keepAlive := t.keepAlive.Bool
+		q.KeepAlive = &keepAlive
+	}
+	q.MaxConnsPerHost = t.maxConnsPerHost
+	q.MaxIdleConnsPerHost = t.maxIdleConnsPerHost
+	q.IdleConnTimeout = t.idleConnTimeout
+	q.ProxyURL = t.proxyURL
+	q.DefaultTimeout = t.defaultTimeout
+}
+
+func (r *queueRepository) findQueueTransports(names []string) (map[string]queueTransport, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, keep_alive, max_conns_per_host, max_idle_conns_per_host, idle_conn_timeout, proxy_url, default_timeout
+		FROM queue_transport
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name            string
+		t               queueTransport
+		transportByName = make(map[string]queueTransport, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &t.keepAlive, &t.maxConnsPerHost, &t.maxIdleConnsPerHost, &t.idleConnTimeout, &t.proxyURL, &t.defaultTimeout); err != nil {
+			return nil, err
+		}
+		transportByName[name] = t
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transportByName, nil
+}
+
+type queueWorker struct {
+	workerType string
+	command    []string
+}
+
+func (r *queueRepository) findQueueWorkers(names []string) (map[string]queueWorker, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, worker_type, command
+		FROM queue_worker
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name         string
+		workerType   string
+		command      []byte
+		workerByName = make(map[string]queueWorker, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &workerType, &command); err != nil {
+			return nil, err
+		}
+		worker := queueWorker{workerType: workerType}
+		if len(command) > 0 {
+			if err := json.Unmarshal(command, &worker.command); err != nil {
+				return nil, err
+			}
+		}
+		workerByName[name] = worker
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return workerByName, nil
+}
+
+func (r *queueRepository) findConsumptionModes(names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, consumption_mode
+		FROM queue_consumption
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name       string
+		mode       string
+		modeByName = make(map[string]string, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &mode); err != nil {
+			return nil, err
+		}
+		modeByName[name] = mode
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return modeByName, nil
+}
+
+type queueShard struct {
+	shardPolicy string
+	shards      []string
+}
+
+func (r *queueRepository) findQueueShards(names []string) (map[string]queueShard, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, shard_policy, shards
+		FROM queue_shard
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name        string
+		shardByName = make(map[string]queueShard, len(names))
+	)
+	for rows.Next() {
+		var (
+			shard  queueShard
+			shards []byte
+		)
+		if err := rows.Scan(&name, &shard.shardPolicy, &shards); err != nil {
+			return nil, err
+		}
+		if len(shards) > 0 {
+			if err := json.Unmarshal(shards, &shard.shards); err != nil {
+				return nil, err
+			}
+		}
+		shardByName[name] = shard
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shardByName, nil
+}
+
+type queueFailureRetention struct {
+	maxAge  uint
+	maxRows uint
+}
+
+func (r *queueRepository) findFailureRetentions(names []string) (map[string]queueFailureRetention, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	sql := `
+		SELECT name, max_age, max_rows
+		FROM queue_failure_retention
+		WHERE name IN (` + strings.Repeat("?,", len(names)-1) + `?)
+	`
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	rows, err := r.db.Query(sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		name            string
+		retention       queueFailureRetention
+		retentionByName = make(map[string]queueFailureRetention, len(names))
+	)
+	for rows.Next() {
+		if err := rows.Scan(&name, &retention.maxAge, &retention.maxRows); err != nil {
+			return nil, err
+		}
+		retentionByName[name] = retention
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return retentionByName, nil
+}
+
+func (r *queueRepository) DeleteByName(name string) error {
+	sql := `
+		DELETE FROM queue
+		WHERE name = ?
+	`
+	_, err := r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_throttle
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_group
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_dead_letter
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_response
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_tls
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_transport
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_worker
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_consumption
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_shard
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	sql = `
+		DELETE FROM queue_failure_retention
+		WHERE name = ?
+	`
+	_, err = r.db.Exec(sql, name)
+	if err != nil {
+		return err
+	}
+
+	if err := (&queueSecretRepository{db: r.db}).DeleteByQueueName(name); err != nil {
+		return err
+	}
+
+	return r.updateRevision()
+}
+
+func (r *queueRepository) Revision() (uint64, error) {
+	var revision uint64
+	if err := r.db.QueryRow(`
+		SELECT revision FROM config_revision
+		WHERE name = 'queue_definition'
+	`).Scan(&revision); err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+func (r *queueRepository) updateRevision() error {
+	_, err := r.db.Exec(`
+		INSERT INTO config_revision (name, revision)
+		VALUES ('queue_definition', 1)
+	` + r.db.dialect.Increment("config_revision", "name", "revision"))
+	return err
+}
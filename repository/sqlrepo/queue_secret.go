@@ -0,0 +1,131 @@
+package sqlrepo
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/repository"
+)
+
+type queueSecretRepository struct {
+	db *database
+}
+
+// NewQueueSecretRepository creates a repository.QueueSecretRepository
+// which uses a SQL database in a dialect as a data store.
+func NewQueueSecretRepository(db *sql.DB, dialect Dialect) repository.QueueSecretRepository {
+	return &queueSecretRepository{db: &database{db, dialect}}
+}
+
+func (r *queueSecretRepository) Add(s *model.QueueSecret) (bool, error) {
+	secrets, err := json.Marshal(s.Secrets)
+	if err != nil {
+		return false, err
+	}
+
+	sql := `
+		INSERT INTO queue_secret (name, secrets)
+		VALUES ( ?, ? )
+	` + r.db.dialect.Upsert("queue_secret", "name", "secrets")
+	res, err := r.db.Exec(sql, s.QueueName, string(secrets))
+	if err != nil {
+		return false, err
+	}
+
+	updated := false
+	i, err := res.RowsAffected()
+	if err == nil {
+		updated = i != 0
+	}
+
+	if updated {
+		return updated, r.updateRevision()
+	}
+	return updated, nil
+}
+
+func (r *queueSecretRepository) FindAll() ([]model.QueueSecret, error) {
+	sql := `
+		SELECT name, secrets
+		FROM queue_secret
+		ORDER BY name ASC
+	`
+	rows, err := r.db.Query(sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.QueueSecret, 0)
+	for rows.Next() {
+		var (
+			s       model.QueueSecret
+			secrets []byte
+		)
+		if err := rows.Scan(&(s.QueueName), &secrets); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(secrets, &(s.Secrets)); err != nil {
+			return nil, err
+		}
+		results = append(results, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *queueSecretRepository) FindByQueueName(queueName string) (*model.QueueSecret, error) {
+	sql := `
+		SELECT name, secrets
+		FROM queue_secret
+		WHERE name = ?
+	`
+
+	s := &model.QueueSecret{}
+	var secrets []byte
+	err := r.db.QueryRow(sql, queueName).Scan(&(s.QueueName), &secrets)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(secrets, &(s.Secrets)); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (r *queueSecretRepository) DeleteByQueueName(queueName string) error {
+	sql := `
+		DELETE FROM queue_secret
+		WHERE name = ?
+	`
+	_, err := r.db.Exec(sql, queueName)
+	if err != nil {
+		return err
+	}
+
+	return r.updateRevision()
+}
+
+func (r *queueSecretRepository) Revision() (uint64, error) {
+	var revision uint64
+	if err := r.db.QueryRow(`
+		SELECT revision FROM config_revision
+		WHERE name = 'queue_secret'
+	`).Scan(&revision); err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+func (r *queueSecretRepository) updateRevision() error {
+	_, err := r.db.Exec(`
+		INSERT INTO config_revision (name, revision)
+		VALUES ('queue_secret', 1)
+	` + r.db.dialect.Increment("config_revision", "name", "revision"))
+	return err
+}
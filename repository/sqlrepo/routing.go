@@ -1,4 +1,4 @@
-package mysql
+package sqlrepo
 
 import (
 	"database/sql"
@@ -10,14 +10,14 @@ import (
 
 type routingRepository struct {
 	sync.RWMutex
-	db       *sql.DB
+	db       *database
 	routings map[string]string
 }
 
 // NewRoutingRepository creates a repository.RoutingRepository which uses
-// MySQL as a data store.
-func NewRoutingRepository(db *sql.DB) repository.RoutingRepository {
-	r := &routingRepository{db: db}
+// a SQL database in a dialect as a data store.
+func NewRoutingRepository(db *sql.DB, dialect Dialect) repository.RoutingRepository {
+	r := &routingRepository{db: &database{db, dialect}}
 	r.Reload()
 	return r
 }
@@ -40,9 +40,7 @@ func (r *routingRepository) Add(jobCategory string, queueName string) (bool, err
 	insertSQL := `
 		INSERT INTO routing (job_category, queue_name)
 		VALUES ( ?, ? )
-        ON DUPLICATE KEY UPDATE
-			queue_name = VALUES(queue_name)
-	`
+	` + r.db.dialect.Upsert("routing", "job_category", "queue_name")
 	res, err := r.db.Exec(insertSQL, jobCategory, queueName)
 	if err != nil {
 		return updated, err
@@ -141,8 +139,6 @@ func (r *routingRepository) updateRevision() error {
 	_, err := r.db.Exec(`
 		INSERT INTO config_revision (name, revision)
 		VALUES ('routing', 1)
-		ON DUPLICATE KEY UPDATE
-			revision = revision + 1
-	`)
+	` + r.db.dialect.Increment("config_revision", "name", "revision"))
 	return err
 }
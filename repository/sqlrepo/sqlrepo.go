@@ -0,0 +1,63 @@
+// Package sqlrepo provides repositories common to the drivers backed
+// by SQL databases.
+package sqlrepo
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Dialect describes the differences of queries among databases.
+type Dialect interface {
+	// Placeholder returns the placeholder of the n-th parameter of a
+	// query, where n starts from 1.
+	Placeholder(n int) string
+
+	// Upsert returns the clause following an INSERT statement into
+	// table, which updates columns of the row conflicting on key.
+	// The statement must affect no rows if the row is unchanged.
+	Upsert(table, key string, columns ...string) string
+
+	// Increment returns the clause following an INSERT statement into
+	// table, which increments column of the row conflicting on key.
+	Increment(table, key, column string) string
+}
+
+// database runs queries written with "?" placeholders in a dialect.
+type database struct {
+	*sql.DB
+	dialect Dialect
+}
+
+func (db *database) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.bind(query), args...)
+}
+
+func (db *database) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.bind(query), args...)
+}
+
+func (db *database) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.bind(query), args...)
+}
+
+func (db *database) bind(query string) string {
+	if db.dialect.Placeholder(1) == "?" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for {
+		i := strings.IndexByte(query, '?')
+		if i < 0 {
+			break
+		}
+		n++
+		b.WriteString(query[:i])
+		b.WriteString(db.dialect.Placeholder(n))
+		query = query[i+1:]
+	}
+	b.WriteString(query)
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package sqlrepo
+
+import (
+	"strconv"
+	"testing"
+)
+
+type numberedDialect struct{}
+
+func (numberedDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (numberedDialect) Upsert(table, key string, columns ...string) string {
+	return ""
+}
+
+func (numberedDialect) Increment(table, key, column string) string {
+	return ""
+}
+
+func TestBind(t *testing.T) {
+	db := &database{dialect: numberedDialect{}}
+
+	for _, c := range []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT name FROM queue WHERE name = ?", "SELECT name FROM queue WHERE name = $1"},
+		{"INSERT INTO queue (name, max_workers) VALUES ( ?, ? )", "INSERT INTO queue (name, max_workers) VALUES ( $1, $2 )"},
+		{"WHERE name IN (?,?,?)", "WHERE name IN ($1,$2,$3)"},
+	} {
+		if q := db.bind(c.query); q != c.expected {
+			t.Errorf("bind(%q) = %q (should be %q)", c.query, q, c.expected)
+		}
+	}
+}
@@ -43,6 +43,7 @@ The following variables/arguments are available.`)
 
 	fmt.Print(`
 [section-manual-setup]: ./production.md#manual-setup
+[section-backup]: ./production.md#backup
 [section-graceful-restart]: ./production.md#graceful-restart
 
 [api-put-queue]: ./api.md#api-put-queue
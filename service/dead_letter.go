@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/model"
+)
+
+func (s *Service) deadLetterHandler(q *model.Queue) jobqueue.DeadLetterHandler {
+	if q.DeadLetterURL == "" {
+		return nil
+	}
+
+	queueName := q.Name
+	dlq := q.DeadLetterQueue
+	category := q.DeadLetterCategory
+	url := q.DeadLetterURL
+
+	return func(job jobqueue.Job, res *jobqueue.Result) error {
+		payload, err := json.Marshal(jobqueue.NewDeadLetter(queueName, job, res))
+		if err != nil {
+			return err
+		}
+		dl := &deadLetterJob{
+			category: category,
+			url:      url,
+			payload:  string(payload),
+			timeout:  job.Timeout(),
+		}
+
+		qn := dlq
+		if qn != "" {
+			dl.category = job.ToLoggable().Category()
+		} else {
+			qn = s.routing.FindQueueNameByJobCategory(category)
+			if qn == "" {
+				qn = s.defaultQueueName
+			}
+		}
+		if qn == "" {
+			return fmt.Errorf("No routing of job category '%s' exists", category)
+		}
+
+		// The dispatcher of the failed job may be waiting for this
+		// function to return while the service is locked to stop
+		// the queue; look up the dead-letter queue without the lock.
+		jq, ok := s.lookUpJobQueue(qn)
+		if !ok {
+			return fmt.Errorf("Undefined queue: %s", qn)
+		}
+		_, err = jq.Push(dl)
+		return err
+	}
+}
+
+// deadLetterQueueName returns the name of the queue to which dead
+// letters of q are delivered.
+func (s *Service) deadLetterQueueName(q *model.Queue) string {
+	if q.DeadLetterQueue != "" {
+		return q.DeadLetterQueue
+	}
+	if q.DeadLetterCategory == "" {
+		return ""
+	}
+	if qn := s.routing.FindQueueNameByJobCategory(q.DeadLetterCategory); qn != "" {
+		return qn
+	}
+	return s.defaultQueueName
+}
+
+type deadLetterJob struct {
+	category string
+	url      string
+	payload  string
+	timeout  uint
+}
+
+func (j *deadLetterJob) Category() string       { return j.category }
+func (j *deadLetterJob) URL() string            { return j.url }
+func (j *deadLetterJob) Payload() string        { return j.payload }
+func (j *deadLetterJob) NextDelay() uint64      { return 0 }
+func (j *deadLetterJob) Timeout() uint          { return j.timeout }
+func (j *deadLetterJob) RetryDelay() uint       { return 0 }
+func (j *deadLetterJob) RetryCount() uint       { return 0 }
+func (j *deadLetterJob) ConcurrencyKey() string { return "" }
+func (j *deadLetterJob) ConcurrencyLimit() uint { return 0 }
+func (j *deadLetterJob) GroupID() string        { return "" }
+func (j *deadLetterJob) IsDeadLetter() bool     { return true }
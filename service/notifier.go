@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// How long the URL of the active node of a queue is cached.
+	nodeURLTTL = 5 * time.Second
+
+	notificationTimeout = 3 * time.Second
+)
+
+var notificationClient = &http.Client{Timeout: notificationTimeout}
+
+// primaryNotifier wakes up the dispatcher of a queue on the node
+// active on the queue when a job is pushed to an inactive node.
+//
+// Notifications are sent one at a time.  Pushes during a notification
+// are coalesced into another notification after it.
+type primaryNotifier struct {
+	queue   jobqueue.JobQueue
+	mu      sync.Mutex
+	sending bool
+	again   bool
+
+	// accessed only by the goroutine sending notifications
+	url     string
+	expires time.Time
+}
+
+func newPrimaryNotifier(q jobqueue.JobQueue) *primaryNotifier {
+	return &primaryNotifier{queue: q}
+}
+
+func (n *primaryNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.sending {
+		n.again = true
+		return
+	}
+	n.sending = true
+	go n.loop()
+}
+
+func (n *primaryNotifier) loop() {
+	for {
+		n.send()
+
+		n.mu.Lock()
+		if !n.again {
+			n.sending = false
+			n.mu.Unlock()
+			return
+		}
+		n.again = false
+		n.mu.Unlock()
+	}
+}
+
+func (n *primaryNotifier) send() {
+	base := n.nodeURL()
+	if base == "" {
+		return
+	}
+
+	u := strings.TrimRight(base, "/") + "/queue/" + url.PathEscape(n.queue.Name()) + "/ping"
+	resp, err := notificationClient.Post(u, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return
+		}
+		err = fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	log.Debug().Str("queue", n.queue.Name()).Msgf("Failed to notify the active node at %s: %s", base, err)
+	n.expires = time.Time{} // the active node may have changed
+}
+
+// nodeURL returns the URL of the node active on the queue or an empty
+// string if it is unknown.
+func (n *primaryNotifier) nodeURL() string {
+	now := time.Now()
+	if now.Before(n.expires) {
+		return n.url
+	}
+
+	n.url = ""
+	if node, err := n.queue.Node(); err == nil && node != nil {
+		n.url = node.URL
+	}
+	n.expires = now.Add(nodeURLTTL)
+	return n.url
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue"
+)
+
+func TestPrimaryNotifier(t *testing.T) {
+	var pinged int64
+	var path string
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.Path
+		<-release
+		atomic.AddInt64(&pinged, 1)
+		w.Write([]byte(`{"active":true}`))
+	}))
+	defer s.Close()
+
+	q := &notifiedJobQueue{url: s.URL + "/"}
+	n := newPrimaryNotifier(q)
+
+	n.notify()
+	time.Sleep(100 * time.Millisecond)
+	n.notify()
+	n.notify()
+	close(release)
+	time.Sleep(200 * time.Millisecond)
+
+	if v := atomic.LoadInt64(&pinged); v != 2 {
+		t.Errorf("Notifications during another one should be coalesced: %d", v)
+	}
+	if path != "/queue/test_queue/ping" {
+		t.Errorf("Wrong path: %s", path)
+	}
+	if v := atomic.LoadInt64(&q.looked); v != 1 {
+		t.Errorf("The URL of the active node should be cached: %d", v)
+	}
+
+	q.url = ""
+	n.expires = time.Time{}
+	n.notify()
+	time.Sleep(100 * time.Millisecond)
+	if v := atomic.LoadInt64(&pinged); v != 2 {
+		t.Errorf("No notification should be sent without the URL of the active node: %d", v)
+	}
+}
+
+type notifiedJobQueue struct {
+	jobqueue.JobQueue
+	url    string
+	looked int64
+}
+
+func (q *notifiedJobQueue) Name() string { return "test_queue" }
+
+func (q *notifiedJobQueue) Node() (*jobqueue.Node, error) {
+	atomic.AddInt64(&q.looked, 1)
+	return &jobqueue.Node{ID: "1", Host: "localhost", URL: q.url}, nil
+}
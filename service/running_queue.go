@@ -14,18 +14,22 @@ type RunningQueue interface {
 	PollingInterval() uint
 	MaxWorkers() uint
 	WorkerStats() *dispatcher.Stats
+	Puller() (dispatcher.Puller, bool)
+	Ping()
 	Deactivate() <-chan struct{}
 }
 
 type runningQueue struct {
 	jobqueue.JobQueue
 	dispatcher dispatcher.Dispatcher
+	notifier   *primaryNotifier
 }
 
-func startJobQueue(q *model.Queue) *runningQueue {
+func startJobQueue(q *model.Queue, deadLetter jobqueue.DeadLetterHandler) *runningQueue {
 	jq := factory.Start(q)
+	jq.SetDeadLetterHandler(deadLetter)
 	d := dispatcher.Start(jq, q)
-	return &runningQueue{jq, d}
+	return &runningQueue{jq, d, newPrimaryNotifier(jq)}
 }
 
 func (q *runningQueue) Deactivate() <-chan struct{} {
@@ -48,10 +52,19 @@ func (q *runningQueue) Stop() <-chan struct{} {
 
 func (q *runningQueue) Push(job jobqueue.IncomingJob) (uint64, error) {
 	id, err := q.JobQueue.Push(job)
+	if err == nil && !q.IsActive() {
+		q.notifier.notify()
+	}
 	q.dispatcher.Ping()
 	return id, err
 }
 
+// Ping wakes up the dispatcher of the queue to check the arrival of
+// new jobs.
+func (q *runningQueue) Ping() {
+	q.dispatcher.Ping()
+}
+
 func (q *runningQueue) PollingInterval() uint {
 	return q.dispatcher.PollingInterval()
 }
@@ -66,3 +79,10 @@ func (q *runningQueue) WorkerStats() *dispatcher.Stats {
 	}
 	return &dispatcher.Stats{}
 }
+
+// Puller returns the dispatcher of the queue if workers pull jobs from
+// it.
+func (q *runningQueue) Puller() (dispatcher.Puller, bool) {
+	p, ok := q.dispatcher.(dispatcher.Puller)
+	return p, ok
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/dispatcher"
+	"github.com/fireworq/fireworq/dispatcher/worker"
 	jobqueue "github.com/fireworq/fireworq/jobqueue/factory"
 	"github.com/fireworq/fireworq/model"
 	"github.com/fireworq/fireworq/repository"
@@ -27,11 +30,16 @@ type Service struct {
 	defaultQueueName string
 	queue            repository.QueueRepository
 	routing          repository.RoutingRepository
+	hostLimit        repository.HostLimitRepository
+	queueSecret      repository.QueueSecretRepository
 	runningQueues    map[string]RunningQueue
+	queueSnapshot    atomic.Value // a copy of runningQueues
 	mu               sync.Mutex
 	muJob            sync.RWMutex
 	queueW           *configWatcher
 	routingW         *configWatcher
+	hostLimitW       *configWatcher
+	queueSecretW     *configWatcher
 }
 
 // NewService creates a new Service instance.
@@ -40,6 +48,8 @@ func NewService(repos *repository.Repositories) *Service {
 		defaultQueueName: config.Get("queue_default"),
 		queue:            repos.Queue,
 		routing:          repos.Routing,
+		hostLimit:        repos.HostLimit,
+		queueSecret:      repos.QueueSecret,
 		runningQueues:    make(map[string]RunningQueue),
 	}
 	s.queueW = newConfigWatcher(
@@ -50,6 +60,14 @@ func NewService(repos *repository.Repositories) *Service {
 		s.routing.Revision,
 		s.reloadRoutings,
 	)
+	s.hostLimitW = newConfigWatcher(
+		s.hostLimit.Revision,
+		s.reloadHostLimits,
+	)
+	s.queueSecretW = newConfigWatcher(
+		s.queueSecret.Revision,
+		s.reloadQueueSecrets,
+	)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -57,9 +75,13 @@ func NewService(repos *repository.Repositories) *Service {
 	s.muJob.Lock()
 	defer s.muJob.Unlock()
 
+	s.loadHostLimits()
+	s.loadQueueSecrets()
 	s.startup()
 	s.queueW.start(configRefreshInterval())
 	s.routingW.start(configRefreshInterval())
+	s.hostLimitW.start(configRefreshInterval())
+	s.queueSecretW.start(configRefreshInterval())
 
 	return s
 }
@@ -73,6 +95,8 @@ func (s *Service) Stop() <-chan struct{} {
 	go func() {
 		<-s.queueW.stop()
 		<-s.routingW.stop()
+		<-s.hostLimitW.stop()
+		<-s.queueSecretW.stop()
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
@@ -125,7 +149,9 @@ func (s *Service) DeleteJobQueue(qn string) error {
 		<-jq.Deactivate()
 		<-jq.Stop()
 		delete(s.runningQueues, qn)
+		s.snapshotJobQueues()
 	}
+	jobqueue.Purge(qn)
 
 	return nil
 }
@@ -159,6 +185,97 @@ func (s *Service) addJobQueue(q *model.Queue) error {
 		return errors.New("Cannot configure MaxBurstSize without MaxDispatchesPerSecond")
 	}
 
+	switch q.GroupFailurePolicy {
+	case "", model.GroupFailurePolicySkip, model.GroupFailurePolicyBlock:
+	default:
+		return fmt.Errorf("Unknown GroupFailurePolicy: %s", q.GroupFailurePolicy)
+	}
+
+	switch {
+	case q.DeadLetterQueue != "" && q.DeadLetterCategory != "":
+		return errors.New("Cannot configure both DeadLetterQueue and DeadLetterCategory")
+	case q.DeadLetterQueue == q.Name:
+		return errors.New("DeadLetterQueue should be another queue")
+	case (q.DeadLetterQueue != "" || q.DeadLetterCategory != "") && q.DeadLetterURL == "":
+		return errors.New("Cannot configure DeadLetterQueue or DeadLetterCategory without DeadLetterURL")
+	case q.DeadLetterQueue == "" && q.DeadLetterCategory == "" && q.DeadLetterURL != "":
+		return errors.New("Cannot configure DeadLetterURL without DeadLetterQueue or DeadLetterCategory")
+	}
+	if dlq := s.deadLetterQueueName(q); dlq != "" {
+		if dlq == q.Name {
+			return errors.New("DeadLetterCategory should be routed to another queue")
+		}
+		if d, err := s.queue.FindByName(dlq); err == nil && s.deadLetterQueueName(d) == q.Name {
+			return fmt.Errorf("Dead letters of %s are delivered back to %s", dlq, q.Name)
+		}
+	}
+
+	switch q.ResponseMode {
+	case "", model.ResponseModeJSON:
+		if len(q.StatusMapping) > 0 {
+			return errors.New("Cannot configure StatusMapping without ResponseMode of status")
+		}
+	case model.ResponseModeStatus:
+		if err := worker.StatusMapping(q.StatusMapping).Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown ResponseMode: %s", q.ResponseMode)
+	}
+
+	tls := worker.TLSConfig{
+		CertFile:   q.TLSCertFile,
+		KeyFile:    q.TLSKeyFile,
+		CAFile:     q.TLSCAFile,
+		MinVersion: q.TLSMinVersion,
+	}
+	if err := tls.Validate(); err != nil {
+		return err
+	}
+
+	transport := worker.TransportConfig{ProxyURL: q.ProxyURL}
+	if err := transport.Validate(); err != nil {
+		return err
+	}
+
+	switch q.WorkerType {
+	case "", model.WorkerTypeHTTP, model.WorkerTypePull:
+		if len(q.Command) > 0 {
+			return errors.New("Cannot configure Command without WorkerType of command or process")
+		}
+		if q.WorkerType == model.WorkerTypePull && q.MaxDispatchesPerSecond > 0.0 {
+			return errors.New("Cannot configure MaxDispatchesPerSecond with WorkerType of pull")
+		}
+	case model.WorkerTypeCommand, model.WorkerTypeProcess:
+		if err := worker.ValidateCommand(q.Command); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown WorkerType: %s", q.WorkerType)
+	}
+
+	switch q.ConsumptionMode {
+	case "", model.ConsumptionModePrimaryBackup:
+	case model.ConsumptionModeActiveActive:
+		if jobqueue.Driver() != "mysql" {
+			return errors.New("Cannot configure ConsumptionMode of active-active without the queue driver of mysql")
+		}
+	default:
+		return fmt.Errorf("Unknown ConsumptionMode: %s", q.ConsumptionMode)
+	}
+
+	switch q.ShardPolicy {
+	case "", model.ShardPolicyRoundRobin, model.ShardPolicyHash:
+		if q.ShardPolicy != "" && len(q.Shards) == 0 {
+			return errors.New("Cannot configure ShardPolicy without Shards")
+		}
+	default:
+		return fmt.Errorf("Unknown ShardPolicy: %s", q.ShardPolicy)
+	}
+	if err := jobqueue.ValidateShards(q.Shards); err != nil {
+		return err
+	}
+
 	if q.PollingInterval == 0 {
 		q.PollingInterval = defaultPollingInterval()
 	}
@@ -192,6 +309,10 @@ func (s *Service) Push(job jobqueue.IncomingJob) (*PushResult, error) {
 		return nil, fmt.Errorf("No routing of job category '%s' exists", job.Category())
 	}
 
+	return s.pushTo(qn, job)
+}
+
+func (s *Service) pushTo(qn string, job jobqueue.IncomingJob) (*PushResult, error) {
 	ok, id, err := func() (bool, uint64, error) {
 		s.muJob.RLock()
 		defer s.muJob.RUnlock()
@@ -275,6 +396,34 @@ func (s *Service) reloadRoutings() {
 	s.routing.Reload()
 }
 
+func (s *Service) reloadHostLimits() {
+	log.Info().Msg("Reloading host limits...")
+	s.loadHostLimits()
+}
+
+func (s *Service) loadHostLimits() {
+	limits, err := s.hostLimit.FindAll()
+	if err != nil {
+		log.Error().Msgf("Cannot load host limits: %s", err)
+		return
+	}
+	dispatcher.SetHostLimits(limits)
+}
+
+func (s *Service) reloadQueueSecrets() {
+	log.Info().Msg("Reloading queue secrets...")
+	s.loadQueueSecrets()
+}
+
+func (s *Service) loadQueueSecrets() {
+	secrets, err := s.queueSecret.FindAll()
+	if err != nil {
+		log.Error().Msgf("Cannot load queue secrets: %s", err)
+		return
+	}
+	worker.SetQueueSecrets(secrets)
+}
+
 func (s *Service) initDefaultQueue(queueName string) error {
 	_, ok := s.getJobQueue(queueName)
 	if !ok {
@@ -294,8 +443,9 @@ func (s *Service) putJobQueue(q *model.Queue) RunningQueue {
 		delete(s.runningQueues, q.Name)
 	}
 
-	jq := startJobQueue(q)
+	jq := startJobQueue(q, s.deadLetterHandler(q))
 	s.runningQueues[q.Name] = jq
+	s.snapshotJobQueues()
 	return jq
 }
 
@@ -328,6 +478,25 @@ func (s *Service) destroyQueues() {
 		n--
 	}
 	s.runningQueues = make(map[string]RunningQueue)
+	s.snapshotJobQueues()
+}
+
+// snapshotJobQueues copies the running queues for lookUpJobQueue.  It
+// must be called whenever the running queues change.
+func (s *Service) snapshotJobQueues() {
+	queues := make(map[string]RunningQueue, len(s.runningQueues))
+	for qn, jq := range s.runningQueues {
+		queues[qn] = jq
+	}
+	s.queueSnapshot.Store(queues)
+}
+
+// lookUpJobQueue returns a RunningQueue of name qn without waiting for
+// the service to be unlocked.  The queue may be being stopped.
+func (s *Service) lookUpJobQueue(qn string) (RunningQueue, bool) {
+	queues, _ := s.queueSnapshot.Load().(map[string]RunningQueue)
+	jq, ok := queues[qn]
+	return jq, ok
 }
 
 func defaultPollingInterval() uint {
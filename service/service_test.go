@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/fireworq/fireworq/config"
+	"github.com/fireworq/fireworq/dispatcher/worker"
 	"github.com/fireworq/fireworq/jobqueue"
 	"github.com/fireworq/fireworq/model"
 	repository "github.com/fireworq/fireworq/repository/factory"
@@ -172,6 +173,311 @@ func TestAddJobQueue(t *testing.T) {
 			t.Error("AddJobQueue should fail with MaxDispatchesPerSecond but without MaxBurstSize")
 		}
 	}()
+
+	func() {
+		q := &model.Queue{
+			Name:               queueName,
+			GroupFailurePolicy: "retry",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an unknown GroupFailurePolicy")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:            queueName,
+			ConsumptionMode: "round-robin",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an unknown ConsumptionMode")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:            queueName,
+			ConsumptionMode: model.ConsumptionModeActiveActive,
+		}
+		config.Locally("queue_driver", "in-memory", func() {
+			err := svc.AddJobQueue(q)
+			if err == nil {
+				t.Error("AddJobQueue should fail with ConsumptionMode of active-active unless the queue driver is mysql")
+			}
+		})
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:        queueName,
+			Shards:      []string{"shard1"},
+			ShardPolicy: "random",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an unknown ShardPolicy")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:        queueName,
+			ShardPolicy: model.ShardPolicyHash,
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with ShardPolicy but without Shards")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:   queueName,
+			Shards: []string{"shard1"},
+		}
+		config.Locally("queue_mysql_shards", "shard1=user@tcp(localhost)/fireworq", func() {
+			config.Locally("queue_driver", "in-memory", func() {
+				err := svc.AddJobQueue(q)
+				if err == nil {
+					t.Error("AddJobQueue should fail with Shards unless the queue driver is mysql")
+				}
+			})
+			config.Locally("queue_driver", "mysql", func() {
+				q.Shards = []string{"shard2"}
+				err := svc.AddJobQueue(q)
+				if err == nil {
+					t.Error("AddJobQueue should fail with an unknown shard")
+				}
+			})
+		})
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:               queueName,
+			DeadLetterQueue:    "dead_letter_queue",
+			DeadLetterCategory: "dead_letter",
+			DeadLetterURL:      "http://localhost/",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with both DeadLetterQueue and DeadLetterCategory")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:            queueName,
+			DeadLetterQueue: queueName,
+			DeadLetterURL:   "http://localhost/",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with DeadLetterQueue of itself")
+		}
+	}()
+
+	func() {
+		category := "service_create_test_dead_letter"
+		if _, err := svc.routing.Add(category, queueName); err != nil {
+			t.Error(err)
+		}
+		defer svc.routing.DeleteByJobCategory(category)
+
+		q := &model.Queue{
+			Name:               queueName,
+			DeadLetterCategory: category,
+			DeadLetterURL:      "http://localhost/",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with DeadLetterCategory routed to itself")
+		}
+	}()
+
+	func() {
+		dlQueueName := "service_create_test_dead_letter_queue"
+		err := svc.AddJobQueue(&model.Queue{
+			Name:            dlQueueName,
+			DeadLetterQueue: queueName,
+			DeadLetterURL:   "http://localhost/",
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		defer svc.DeleteJobQueue(dlQueueName)
+
+		q := &model.Queue{
+			Name:            queueName,
+			DeadLetterQueue: dlQueueName,
+			DeadLetterURL:   "http://localhost/",
+		}
+		err = svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with DeadLetterQueue delivering dead letters back")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:            queueName,
+			DeadLetterQueue: "dead_letter_queue",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with DeadLetterQueue but without DeadLetterURL")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:          queueName,
+			DeadLetterURL: "http://localhost/",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with DeadLetterURL but without DeadLetterQueue nor DeadLetterCategory")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:         queueName,
+			ResponseMode: "xml",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an unknown ResponseMode")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:          queueName,
+			StatusMapping: map[string]string{"2xx": "success"},
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with StatusMapping but without ResponseMode of status")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:          queueName,
+			ResponseMode:  model.ResponseModeStatus,
+			StatusMapping: map[string]string{"2xx": "ok"},
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an invalid StatusMapping")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:        queueName,
+			TLSCertFile: "/path/to/cert.pem",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with TLSCertFile but without TLSKeyFile")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:          queueName,
+			TLSMinVersion: "2.0",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an unknown TLSMinVersion")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:     queueName,
+			ProxyURL: "ftp://proxy.example.com",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an unsupported ProxyURL")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:       queueName,
+			WorkerType: "ftp",
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with an unknown WorkerType")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:    queueName,
+			Command: []string{"true"},
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with Command but without WorkerType of command")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:       queueName,
+			WorkerType: model.WorkerTypeCommand,
+			Command:    []string{"true"},
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with WorkerType of command unless it is enabled")
+		}
+	}()
+
+	func() {
+		q := &model.Queue{
+			Name:                   queueName,
+			WorkerType:             model.WorkerTypePull,
+			MaxDispatchesPerSecond: 1,
+			MaxBurstSize:           1,
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with MaxDispatchesPerSecond and WorkerType of pull")
+		}
+	}()
+
+	config.Locally("dispatch_command_enabled", "true", func() {
+		worker.CommandInit()
+
+		q := &model.Queue{
+			Name:       queueName,
+			WorkerType: model.WorkerTypeCommand,
+		}
+		err := svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with WorkerType of command but without Command")
+		}
+
+		q = &model.Queue{
+			Name:       queueName,
+			WorkerType: model.WorkerTypeProcess,
+		}
+		err = svc.AddJobQueue(q)
+		if err == nil {
+			t.Error("AddJobQueue should fail with WorkerType of process but without Command")
+		}
+	})
+	worker.CommandInit()
 }
 
 func TestDeleteJobQueue(t *testing.T) {
@@ -321,6 +627,117 @@ func TestPushFailure(t *testing.T) {
 	}
 }
 
+func TestDeadLetter(t *testing.T) {
+	jobCategory := "service_dead_letter_test_job"
+	queueName := "service_dead_letter_test_queue"
+	dlCategory := "service_dead_letter_test_dead_letter"
+	dlQueueName := "service_dead_letter_test_dead_letter_queue"
+
+	svc := newService()
+	defer func() { <-svc.Stop() }()
+	defer svc.DeleteJobQueue(queueName)
+	defer svc.DeleteJobQueue(dlQueueName)
+
+	worker := newTestWorker(t)
+	defer worker.close()
+	dlWorker := newTestWorker(t)
+	defer dlWorker.close()
+
+	func() {
+		err := svc.AddJobQueue(&model.Queue{Name: dlQueueName})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if _, err := svc.routing.Add(dlCategory, dlQueueName); err != nil {
+		t.Error(err)
+	}
+
+	assertDeadLetter := func() {
+		var dl jobqueue.DeadLetter
+		if err := json.Unmarshal([]byte(dlWorker.wait(5*time.Second)), &dl); err != nil {
+			t.Error(err)
+			return
+		}
+		if dl.QueueName != queueName || dl.Category != jobCategory {
+			t.Errorf("Wrong dead letter: %v", dl)
+		}
+		if dl.URL != worker.url() {
+			t.Errorf("Wrong URL: %s", dl.URL)
+		}
+		if string(dl.Payload) != `{"status":"permanent-failure","message":"dead"}` {
+			t.Errorf("Wrong payload: %s", dl.Payload)
+		}
+		if dl.Result == nil || dl.Result.Status != jobqueue.ResultStatusPermanentFailure || dl.Result.Message != "dead" {
+			t.Errorf("Wrong result: %v", dl.Result)
+		}
+	}
+
+	job := &incomingJob{
+		category: jobCategory,
+		url:      worker.url(),
+		payload:  `{"status":"permanent-failure","message":"dead"}`,
+	}
+
+	func() {
+		err := svc.AddJobQueue(&model.Queue{
+			Name:            queueName,
+			DeadLetterQueue: dlQueueName,
+			DeadLetterURL:   dlWorker.url(),
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// A job category is routed only to a defined queue.
+	if _, err := svc.routing.Add(jobCategory, queueName); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := svc.Push(job); err != nil {
+		t.Error(err)
+	}
+	worker.wait(3 * time.Second)
+	assertDeadLetter()
+
+	func() {
+		err := svc.AddJobQueue(&model.Queue{
+			Name:               queueName,
+			DeadLetterCategory: dlCategory,
+			DeadLetterURL:      dlWorker.url(),
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if _, err := svc.Push(job); err != nil {
+		t.Error(err)
+	}
+	worker.wait(3 * time.Second)
+	assertDeadLetter()
+
+	// A dead letter failing in the queue of the original job should
+	// not be delivered again.
+	if _, err := svc.routing.Add(dlCategory, queueName); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := svc.Push(job); err != nil {
+		t.Error(err)
+	}
+	worker.wait(3 * time.Second)
+	assertDeadLetter()
+
+	select {
+	case req := <-dlWorker.worker.request:
+		t.Errorf("Dead letter should not be delivered again: %s", req)
+	case <-time.After(time.Second):
+	}
+}
+
 func TestFailingOver(t *testing.T) {
 	if test.If("driver", "in-memory") { // not supported
 		return
@@ -649,10 +1066,6 @@ func TestReloadingQueueDefinitions(t *testing.T) {
 }
 
 func TestFailureLogging(t *testing.T) {
-	if test.If("driver", "in-memory") { // not supported
-		return
-	}
-
 	jobCategory := "service_failure_log_test_job"
 	queueName := "service_failure_log_test_queue"
 
@@ -680,6 +1093,35 @@ func TestFailureLogging(t *testing.T) {
 
 	waitRequest := func() { worker.wait(10 * time.Second) }
 
+	// Failed jobs are ordered by their creation time in milliseconds;
+	// push each job in a later millisecond than the previous one.
+	push := func(job *incomingJob) {
+		if _, err := svc.Push(job); err != nil {
+			t.Error(err)
+		}
+		pushedAt := time.Now().UnixNano() / int64(time.Millisecond)
+		for time.Now().UnixNano()/int64(time.Millisecond) <= pushedAt {
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+
+	// Failures are logged after the worker responds; wait for the
+	// dispatcher to log the n-th failure before going on.  The queue
+	// may be reloaded meanwhile, so look it up every time.
+	waitFailures := func(n int) {
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); {
+			if q, ok := svc.GetJobQueue(queueName); ok {
+				if l, ok := q.FailureLog(); ok {
+					if r, err := l.FindAll(10, ""); err == nil && len(r.FailedJobs) >= n {
+						return
+					}
+				}
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for %d failures", n)
+	}
+
 	job0 := &incomingJob{
 		category: jobCategory,
 		url:      worker.url(),
@@ -720,56 +1162,41 @@ func TestFailureLogging(t *testing.T) {
 		payload:  `{"status": "failure", "message":"job4"}`,
 	}
 
-	if _, err := svc.Push(job0); err != nil {
-		t.Error(err)
-	}
+	push(job0)
 	waitRequest()
+	waitFailures(1)
 
-	if _, err := svc.Push(job1); err != nil {
-		t.Error(err)
-	}
+	push(job1)
 	waitRequest()
+	waitFailures(2)
 
-	if _, err := svc.Push(job2); err != nil {
-		t.Error(err)
-	}
+	push(job2)
 	waitRequest()
 
-	if _, err := svc.Push(job3); err != nil {
-		t.Error(err)
-	}
+	push(job3)
 	waitRequest()
 	waitRequest()
+	waitFailures(3)
 
-	if _, err := svc.Push(jobX); err != nil {
-		t.Error(err)
-	}
+	push(jobX)
 	waitRequest()
 	waitRequest()
 
-	if _, err := svc.Push(job4); err != nil {
-		t.Error(err)
-	}
+	push(job4)
 	waitRequest()
 	waitRequest()
+	waitFailures(4)
 
 	waitRequest()
-
-	time.Sleep(500 * time.Millisecond) // wait for dispatcher to complete
+	waitFailures(5)
 
 	q, ok := svc.GetJobQueue(queueName)
 	if !ok {
-		t.Error("Cannot get queue instance")
+		t.Fatal("Cannot get queue instance")
 	}
 	l, ok := q.FailureLog()
-	if test.If("driver", "in-memory") {
-		if l != nil || ok {
-			t.Error("Implemented?")
-		}
-		return
-	}
 	if !ok {
-		t.Error("Cannot get the failure log")
+		t.Fatal("Cannot get the failure log")
 	}
 
 	func() {
@@ -1055,6 +1482,18 @@ func (job *incomingJob) Timeout() uint {
 	return uint(0)
 }
 
+func (job *incomingJob) ConcurrencyKey() string {
+	return ""
+}
+
+func (job *incomingJob) ConcurrencyLimit() uint {
+	return uint(0)
+}
+
+func (job *incomingJob) GroupID() string {
+	return ""
+}
+
 func newService() *Service {
 	return NewService(repository.NewRepositories())
 }
@@ -0,0 +1,137 @@
+// Package signature provides signing and verification of requests
+// dispatched by Fireworq to workers.
+//
+// A request is signed by HMAC-SHA256 over its timestamp, its job ID
+// and its body using the secrets of the queue of the job.  While a
+// secret is being rotated, a queue has two active secrets and a
+// request carries a signature for each of them so that a worker can
+// verify it with either the old or the new secret.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a signed request.
+const (
+	HeaderSignature = "X-Fireworq-Signature"
+	HeaderTimestamp = "X-Fireworq-Timestamp"
+	HeaderJobID     = "X-Fireworq-Job-Id"
+)
+
+const scheme = "sha256="
+
+// DefaultTolerance is the maximum difference between the timestamp of
+// a request and the current time accepted by a Verifier by default.
+const DefaultTolerance = 5 * time.Minute
+
+// Errors returned by a Verifier.
+var (
+	ErrMissingSignature = errors.New("Missing signature")
+	ErrInvalidTimestamp = errors.New("Invalid timestamp")
+	ErrInvalidSignature = errors.New("Invalid signature")
+)
+
+// Compute returns a hex-encoded HMAC-SHA256 of a request.
+func Compute(secret string, timestamp int64, jobID uint64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(strconv.FormatUint(jobID, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign sets headers of a request of a job signed with each of
+// secrets.
+func Sign(h http.Header, secrets []string, t time.Time, jobID uint64, body []byte) {
+	timestamp := t.Unix()
+
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		signatures[i] = scheme + Compute(secret, timestamp, jobID, body)
+	}
+
+	h.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	h.Set(HeaderJobID, strconv.FormatUint(jobID, 10))
+	h.Set(HeaderSignature, strings.Join(signatures, ","))
+}
+
+// Fingerprint returns a short hex-encoded SHA-256 digest of a secret
+// which identifies the secret without revealing it.
+func Fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return scheme + hex.EncodeToString(sum[:8])
+}
+
+// Verifier verifies signed requests.
+type Verifier struct {
+	// Secrets are the active secrets of the queue, typically the
+	// current one and the previous one during a rotation.  A request
+	// signed with any of them is accepted.
+	Secrets []string
+	// Tolerance is the maximum difference between the timestamp of a
+	// request and the current time.  DefaultTolerance is used if it
+	// is zero.
+	Tolerance time.Duration
+}
+
+// Verify returns nil if the headers and the body are of a request
+// signed with one of the secrets.
+func (v *Verifier) Verify(h http.Header, body []byte) error {
+	header := h.Get(HeaderSignature)
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(h.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	diff := time.Since(time.Unix(timestamp, 0))
+	if diff > tolerance || diff < -tolerance {
+		return ErrInvalidTimestamp
+	}
+
+	jobID, err := strconv.ParseUint(h.Get(HeaderJobID), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	for _, secret := range v.Secrets {
+		expected := []byte(scheme + Compute(secret, timestamp, jobID, body))
+		for _, signature := range strings.Split(header, ",") {
+			if hmac.Equal([]byte(strings.TrimSpace(signature)), expected) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyRequest verifies a request and returns its body.  The body
+// of the request is restored so that it can be read again.
+func (v *Verifier) VerifyRequest(req *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, v.Verify(req.Header, body)
+}
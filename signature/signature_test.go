@@ -0,0 +1,115 @@
+package signature
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"foo":"bar"}`)
+
+	func() {
+		h := make(http.Header)
+		Sign(h, []string{"secret"}, time.Now(), 1, body)
+
+		v := &Verifier{Secrets: []string{"secret"}}
+		if err := v.Verify(h, body); err != nil {
+			t.Error(err)
+		}
+
+		if err := v.Verify(h, []byte(`{"foo":"baz"}`)); err != ErrInvalidSignature {
+			t.Error("A modified body should be rejected")
+		}
+
+		v = &Verifier{Secrets: []string{"another"}}
+		if err := v.Verify(h, body); err != ErrInvalidSignature {
+			t.Error("A request signed with another secret should be rejected")
+		}
+	}()
+
+	func() {
+		h := make(http.Header)
+		Sign(h, []string{"new", "old"}, time.Now(), 1, body)
+
+		for _, secrets := range [][]string{{"new"}, {"old"}, {"old", "new"}} {
+			v := &Verifier{Secrets: secrets}
+			if err := v.Verify(h, body); err != nil {
+				t.Errorf("A request should be verified with %v: %s", secrets, err)
+			}
+		}
+	}()
+
+	func() {
+		h := make(http.Header)
+		Sign(h, []string{"secret"}, time.Now(), 1, body)
+		h.Set(HeaderJobID, "2")
+
+		v := &Verifier{Secrets: []string{"secret"}}
+		if err := v.Verify(h, body); err != ErrInvalidSignature {
+			t.Error("A modified job ID should be rejected")
+		}
+	}()
+
+	func() {
+		h := make(http.Header)
+		Sign(h, []string{"secret"}, time.Now().Add(-10*time.Minute), 1, body)
+
+		v := &Verifier{Secrets: []string{"secret"}}
+		if err := v.Verify(h, body); err != ErrInvalidTimestamp {
+			t.Error("An old request should be rejected")
+		}
+
+		v = &Verifier{Secrets: []string{"secret"}, Tolerance: time.Hour}
+		if err := v.Verify(h, body); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	func() {
+		v := &Verifier{Secrets: []string{"secret"}}
+		if err := v.Verify(make(http.Header), body); err != ErrMissingSignature {
+			t.Error("An unsigned request should be rejected")
+		}
+	}()
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := `{"foo":"bar"}`
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	Sign(req.Header, []string{"secret"}, time.Now(), 1, []byte(body))
+
+	v := &Verifier{Secrets: []string{"secret"}}
+	b, err := v.VerifyRequest(req)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != body {
+		t.Errorf("Wrong body: %s", b)
+	}
+
+	b, err = ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != body {
+		t.Errorf("The body should be restored: %s", b)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	f := Fingerprint("secret")
+	if f != Fingerprint("secret") {
+		t.Error("A fingerprint should be stable")
+	}
+	if f == Fingerprint("another") {
+		t.Error("Fingerprints of different secrets should differ")
+	}
+	if strings.Contains(f, "secret") || len(f) != len("sha256=")+16 {
+		t.Errorf("Wrong fingerprint: %s", f)
+	}
+}
@@ -0,0 +1,144 @@
+// Package snapshot saves the data stores of in-memory driver to a
+// file and restores them so that they survive restarts.
+package snapshot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	jobqueue "github.com/fireworq/fireworq/jobqueue/inmemory"
+	repository "github.com/fireworq/fireworq/repository/inmemory"
+
+	"github.com/rs/zerolog/log"
+)
+
+type snapshot struct {
+	Repositories json.RawMessage `json:"repositories"`
+	JobQueues    json.RawMessage `json:"job_queues"`
+}
+
+// Snapshotter saves a snapshot to a file periodically.
+type Snapshotter struct {
+	path     string
+	stopC    chan struct{}
+	stoppedC chan struct{}
+}
+
+// Start restores the data stores from the file at path if it exists
+// and starts saving a snapshot to the file at every interval.  If
+// interval is not positive, a snapshot is saved only on Stop().
+func Start(path string, interval time.Duration) (*Snapshotter, error) {
+	if err := Load(path); err != nil {
+		return nil, err
+	}
+
+	s := &Snapshotter{
+		path:     path,
+		stopC:    make(chan struct{}, 1),
+		stoppedC: make(chan struct{}, 1),
+	}
+	go s.loop(interval)
+
+	return s, nil
+}
+
+// Stop stops saving snapshots periodically and saves the last one.
+func (s *Snapshotter) Stop() error {
+	s.stopC <- struct{}{}
+	<-s.stoppedC
+
+	return Save(s.path)
+}
+
+func (s *Snapshotter) loop(interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+Loop:
+	for {
+		select {
+		case <-tick:
+			if err := Save(s.path); err != nil {
+				log.Error().Msgf("Cannot save a snapshot: %s", err)
+			}
+		case <-s.stopC:
+			break Loop
+		}
+	}
+	s.stoppedC <- struct{}{}
+}
+
+// Save saves a snapshot of the data stores to the file at path.  The
+// file is replaced atomically so that a crash never leaves a broken
+// snapshot.
+func Save(path string) error {
+	var err error
+	s := &snapshot{}
+	if s.Repositories, err = repository.Snapshot(); err != nil {
+		return err
+	}
+	if s.JobQueues, err = jobqueue.Snapshot(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Saved a snapshot to %s", path)
+	return nil
+}
+
+// Load restores the data stores from the file at path.  Nothing
+// happens if the file doesn't exist.
+func Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if err := repository.Restore(s.Repositories); err != nil {
+		return err
+	}
+	if err := jobqueue.Restore(s.JobQueues); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Restored a snapshot from %s", path)
+	return nil
+}
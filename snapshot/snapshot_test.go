@@ -0,0 +1,96 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jobqueue "github.com/fireworq/fireworq/jobqueue/inmemory"
+	"github.com/fireworq/fireworq/model"
+	repository "github.com/fireworq/fireworq/repository/inmemory"
+)
+
+func TestSnapshotter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fireworq-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot", "fireworq.json")
+
+	s, err := Start(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal("A missing snapshot should be ignored")
+	}
+
+	q := &model.Queue{Name: "snapshot_test_queue", MaxWorkers: 3}
+	if _, err := repository.NewQueueRepository().Add(q); err != nil {
+		t.Error(err)
+	}
+	jobqueue.New(q)
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("A snapshot should be saved periodically: %s", err)
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Error(err)
+	}
+
+	if err := repository.NewQueueRepository().DeleteByName(q.Name); err != nil {
+		t.Error(err)
+	}
+	if err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := repository.NewQueueRepository().FindByName(q.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.MaxWorkers != q.MaxWorkers {
+		t.Errorf("Wrong queue definition: %v", restored)
+	}
+}
+
+func TestSnapshotterWithoutInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fireworq-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fireworq.json")
+
+	s, err := Start(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("A snapshot should not be saved periodically without an interval")
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("A snapshot should be saved on stopping: %s", err)
+	}
+}
+
+func TestLoadBroken(t *testing.T) {
+	file, err := ioutil.TempFile("", "fireworq-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("{")
+	file.Close()
+
+	if err := Load(file.Name()); err == nil {
+		t.Error("A broken snapshot should not be loaded")
+	}
+}
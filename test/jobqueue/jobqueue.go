@@ -14,6 +14,7 @@ type job struct {
 	retryCount uint
 	retryDelay uint
 	timeout    uint
+	groupID    string
 }
 
 func (j *job) Category() string {
@@ -48,8 +49,25 @@ func (j *job) Timeout() uint {
 	return j.timeout
 }
 
+func (j *job) ConcurrencyKey() string {
+	return ""
+}
+
+func (j *job) ConcurrencyLimit() uint {
+	return 0
+}
+
+func (j *job) GroupID() string {
+	return j.groupID
+}
+
 const retryCount = 3
 
+// NewTestJob creates a job to push in tests of a specific driver.
+func NewTestJob(category, url, data string) jobqueue.IncomingJob {
+	return newTestJob(category, url, data)
+}
+
 func newTestJob(category, url, data string) jobqueue.IncomingJob {
 	time.Sleep(10 * time.Millisecond)
 	return &job{
@@ -62,6 +80,12 @@ func newTestJob(category, url, data string) jobqueue.IncomingJob {
 	}
 }
 
+func newTestGroupJob(category, url, data, group string) jobqueue.IncomingJob {
+	j := newTestJob(category, url, data).(*job)
+	j.groupID = group
+	return j
+}
+
 type nextJob struct {
 	jobqueue.Job
 	nextDelay uint64
@@ -112,6 +136,10 @@ func TestSubtests(t *testing.T, runner SubtestRunner) {
 		subtestAsyncPop1,
 		subtestAsyncDelete1,
 		subtestAsyncUpdate1,
+		subtestPopGroupHeads,
+		subtestGroupRetry,
+		subtestGroupProceed,
+		subtestNextTry,
 	})
 }
 
@@ -653,3 +681,128 @@ func subtestAsyncUpdate1(t *testing.T, jq jobqueue.Impl) {
 
 	<-done
 }
+
+func subtestPopGroupHeads(t *testing.T, jq jobqueue.Impl) {
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "1", "g1"))
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "2", "g1"))
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "3", "g2"))
+	jq.Push(newTestJob("foo", "http://localhost/worker", "4"))
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "5", "g2"))
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 3 {
+		t.Errorf("Wrong queue length: %d", len(jobs))
+	}
+	for i, num := range []string{"1", "3", "4"} {
+		if i < len(jobs) && jobs[i].Payload() != num {
+			t.Errorf("Wrong job returned: %v", jobs[i])
+		}
+	}
+
+	jobs, err = jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("Jobs following running heads must not be popped: %d", len(jobs))
+	}
+}
+
+func subtestGroupRetry(t *testing.T, jq jobqueue.Impl) {
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "1", "g1"))
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "2", "g1"))
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("Wrong queue length: %d", len(jobs))
+		return
+	}
+	jq.Update(jobs[0], &nextJob{jobs[0], 300})
+
+	jobs, err = jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("A retrying head must block its group: %d", len(jobs))
+	}
+
+	time.Sleep(400 * time.Millisecond)
+
+	jobs, err = jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 1 || jobs[0].Payload() != "1" {
+		t.Errorf("A retrying head must be popped again: %v", jobs)
+	}
+}
+
+func subtestGroupProceed(t *testing.T, jq jobqueue.Impl) {
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "1", "g1"))
+	jq.Push(newTestGroupJob("foo", "http://localhost/worker", "2", "g1"))
+	time.Sleep(10 * time.Millisecond)
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("Wrong queue length: %d", len(jobs))
+		return
+	}
+	jq.Delete(jobs[0])
+
+	jobs, err = jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 1 || jobs[0].Payload() != "2" {
+		t.Errorf("The next job in the group must be popped: %v", jobs)
+	}
+}
+
+func subtestNextTry(t *testing.T, jq jobqueue.Impl) {
+	q, ok := jq.(jobqueue.HasNextTry)
+	if !ok {
+		return
+	}
+
+	if _, ok := q.NextTry(); ok {
+		t.Error("An empty queue must not have a next try")
+	}
+
+	jq.Push(newTestJob("foo", "http://localhost/worker", "1"))
+	time.Sleep(10 * time.Millisecond)
+
+	if _, ok := q.NextTry(); ok {
+		t.Error("A due job must not be a next try")
+	}
+
+	jobs, err := jq.Pop(10)
+	if err != nil {
+		t.Errorf("Failed to pop job: %s", err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("Wrong queue length: %d", len(jobs))
+		return
+	}
+	jq.Update(jobs[0], &nextJob{jobs[0], 300})
+
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	next, ok := q.NextTry()
+	if !ok {
+		t.Error("A deferred job must be a next try")
+	}
+	if next < now+200 || next > now+400 {
+		t.Errorf("Wrong next try: %d (now: %d)", next, now)
+	}
+}
@@ -0,0 +1,61 @@
+package postgrestest
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq" // initialize the driver
+)
+
+// With runs a block with locking the DB and truncating all tables in
+// the DB.
+func With(dsn string, block func()) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// An advisory lock belongs to a session.
+	db.SetMaxOpenConns(1)
+
+	lockName := "fireworq_postgrestest"
+	_, err = db.Exec(`SELECT pg_advisory_lock(hashtext($1))`, lockName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		db.Exec(`SELECT pg_advisory_unlock(hashtext($1))`, lockName)
+	}()
+
+	TruncateTables(dsn)
+
+	block()
+	return nil
+}
+
+// TruncateTables truncates all tables in the DB specified by a DSN.
+func TruncateTables(dsn string) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema()
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return err
+		}
+		_, err = db.Exec(fmt.Sprintf(`DELETE FROM "%s"`, table))
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package redistest
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// With runs a block with an in-process Redis server, which is closed
+// after the block.
+func With(block func(url string)) error {
+	s, err := miniredis.Run()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	block("redis://" + s.Addr())
+	return nil
+}
+
+// FlushAll removes all keys in the Redis server specified by a URL.
+func FlushAll(url string) error {
+	conn, err := redis.DialURL(url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Do("FLUSHALL")
+	return err
+}
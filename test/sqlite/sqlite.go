@@ -0,0 +1,58 @@
+package sqlitetest
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	_ "modernc.org/sqlite" // initialize the driver
+)
+
+// With runs a block with a temporary data directory, which is removed
+// after the block.
+func With(block func(dir string)) error {
+	dir, err := ioutil.TempDir("", "fireworq_sqlitetest")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	block(dir)
+	return nil
+}
+
+// TruncateTables truncates all tables in the DB specified by a DSN.
+func TruncateTables(dsn string) error {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		_, err = db.Exec(fmt.Sprintf(`DELETE FROM "%s"`, table))
+	}
+	return err
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/fireworq/fireworq/config"
 	"github.com/fireworq/fireworq/test/mysql"
+	"github.com/fireworq/fireworq/test/postgres"
+	"github.com/fireworq/fireworq/test/sqlite"
 )
 
 func runWithMySQL(block func()) error {
@@ -16,16 +18,39 @@ func runWithMySQL(block func()) error {
 	return mysqltest.With(dsn, block)
 }
 
+func runWithPostgres(block func()) error {
+	dsn := config.Get("postgres_dsn")
+	config.Set("repository_postgres_dsn", dsn)
+	config.Set("queue_postgres_dsn", dsn)
+
+	return postgrestest.With(dsn, block)
+}
+
+func runWithSQLite(block func()) error {
+	return sqlitetest.With(func(dir string) {
+		config.Locally("sqlite_dir", dir, block)
+	})
+}
+
 // Run runs a TestMain for a single "driver" configuration value.
 func Run(m *testing.M) (int, error) {
 	var status int
 	var err error
 
-	if config.Get("driver") == "mysql" {
+	switch config.Get("driver") {
+	case "mysql":
 		err = runWithMySQL(func() {
 			status = m.Run()
 		})
-	} else {
+	case "postgres":
+		err = runWithPostgres(func() {
+			status = m.Run()
+		})
+	case "sqlite":
+		err = runWithSQLite(func() {
+			status = m.Run()
+		})
+	default:
 		status = m.Run()
 	}
 
@@ -33,8 +58,12 @@ func Run(m *testing.M) (int, error) {
 }
 
 // RunAll runs a TestMain for all "driver" configuration values.
+// "postgres" is run only if "postgres_dsn" is configured.
 func RunAll(m *testing.M) {
-	drivers := []string{"mysql", "in-memory"}
+	drivers := []string{"mysql", "in-memory", "sqlite"}
+	if config.Get("postgres_dsn") != "" {
+		drivers = append(drivers, "postgres")
+	}
 	for _, driver := range drivers {
 		config.Locally("driver", driver, func() {
 			status, err := Run(m)
@@ -26,11 +26,13 @@ type Service interface {
 
 // Application is an interface of the application.
 type Application struct {
-	AccessLogWriter   io.Writer
-	Version           string
-	Service           Service
-	QueueRepository   repository.QueueRepository
-	RoutingRepository repository.RoutingRepository
+	AccessLogWriter       io.Writer
+	Version               string
+	Service               Service
+	QueueRepository       repository.QueueRepository
+	RoutingRepository     repository.RoutingRepository
+	HostLimitRepository   repository.HostLimitRepository
+	QueueSecretRepository repository.QueueSecretRepository
 }
 
 func (app *Application) newServer() *server {
@@ -40,20 +42,28 @@ func (app *Application) newServer() *server {
 	s.handle("/version", app.serveVersion)
 	s.handle("/settings", app.serveSettings)
 	s.mux.HandleFunc("/stats", stats.Handler)
+	s.handleStream("/events", app.serveEvents)
 	s.handle("/job/{category:.+}", app.serveJob)
 	s.handle("/queues", app.serveQueueList)
 	s.handle("/queues/stats", app.serveQueueListStats)
 	s.handle("/queue/{queue:[^/]+}", app.serveQueue)
 	s.handle("/queue/{queue:[^/]+}/node", app.serveQueueNode)
+	s.handle("/queue/{queue:[^/]+}/ping", app.serveQueuePing)
 	s.handle("/queue/{queue:[^/]+}/stats", app.serveQueueStats)
 	s.handle("/queue/{queue:[^/]+}/grabbed", app.serveQueueGrabbed)
 	s.handle("/queue/{queue:[^/]+}/waiting", app.serveQueueWaiting)
 	s.handle("/queue/{queue:[^/]+}/deferred", app.serveQueueDeferred)
 	s.handle("/queue/{queue:[^/]+}/job/{id:[^/]+}", app.serveQueueJob)
+	s.handle("/queue/{queue:[^/]+}/job/{id:[^/]+}/ack", app.serveQueueJobAck)
+	s.handle("/queue/{queue:[^/]+}/job/{id:[^/]+}/nack", app.serveQueueJobNack)
+	s.handle("/queue/{queue:[^/]+}/reserve", app.serveQueueReserve)
 	s.handle("/queue/{queue:[^/]+}/failed", app.serveQueueFailed)
 	s.handle("/queue/{queue:[^/]+}/failed/{id:[^/]+}", app.serveQueueFailedJob)
+	s.handle("/queue/{queue:[^/]+}/secret", app.serveQueueSecret)
 	s.handle("/routings", app.serveRoutingList)
 	s.handle("/routing/{category:.+}", app.serveRouting)
+	s.handle("/host_limits", app.serveHostLimitList)
+	s.handle("/host_limit/{host:[^/]+}", app.serveHostLimit)
 
 	return s
 }
@@ -103,4 +103,5 @@ const (
 	errBadRequest          = simpleClientError(http.StatusBadRequest)
 	errNotImplemented      = simpleServerError(http.StatusNotImplemented)
 	errInternalServerError = simpleServerError(http.StatusInternalServerError)
+	errServiceUnavailable  = simpleServerError(http.StatusServiceUnavailable)
 )
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue/logger"
+)
+
+// The number of events buffered for a client.  Events are dropped
+// while the buffer is full.
+const eventBufferSize = 1000
+
+// How often a comment is sent to keep an idle stream open.
+var eventKeepAliveInterval = 15 * time.Second
+
+func (app *Application) serveEvents(w http.ResponseWriter, req *http.Request) error {
+	if req.Method != "GET" {
+		return errMethodNotAllowed
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errNotImplemented.WithDetail("Streaming is not supported")
+	}
+
+	query := req.URL.Query()
+	sub := logger.Subscribe(query.Get("queue"), query.Get("category"), eventBufferSize)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	var dropped uint64
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return nil
+			}
+		case e := <-sub.C():
+			// Tell the client how many events have been lost since
+			// the last one.
+			if d := sub.Dropped(); d > dropped {
+				if _, err := fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", d-dropped); err != nil {
+					return nil
+				}
+				dropped = d
+			}
+
+			j, err := json.Marshal(e)
+			if err != nil {
+				return nil
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Action, j); err != nil {
+				return nil
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/jobqueue/logger"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s, _ := newMockServer(ctrl)
+	defer s.Close()
+
+	resp, err := http.Post(s.URL+"/events", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Error("POST /events should not be allowed")
+	}
+
+	resp, err = http.Get(s.URL + "/events?queue=queue1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /events should start a stream: %d", resp.StatusCode)
+	}
+
+	// Wait for the subscription.
+	time.Sleep(100 * time.Millisecond)
+	logger.Info("queue2", "push", &loggableJob{id: 1}, "")
+	logger.Info("queue1", "complete", &loggableJob{id: 2}, "Done")
+
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if lines[0] != "event: complete" {
+		t.Errorf("Wrong event: %s", lines[0])
+	}
+	var e logger.Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != 2 || e.Queue != "queue1" || e.Message != "Done" {
+		t.Errorf("Wrong event: %v", e)
+	}
+}
+
+type loggableJob struct {
+	id uint64
+}
+
+func (j *loggableJob) Category() string  { return "cat" }
+func (j *loggableJob) URL() string       { return "" }
+func (j *loggableJob) Payload() string   { return "" }
+func (j *loggableJob) ID() uint64        { return j.id }
+func (j *loggableJob) Status() string    { return "completed" }
+func (j *loggableJob) NextTry() uint64   { return 0 }
+func (j *loggableJob) RetryCount() uint  { return 0 }
+func (j *loggableJob) RetryDelay() uint  { return 0 }
+func (j *loggableJob) FailCount() uint   { return 0 }
+func (j *loggableJob) Timeout() uint     { return 0 }
+func (j *loggableJob) CreatedAt() uint64 { return 0 }
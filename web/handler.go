@@ -14,17 +14,33 @@ func (f handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	var rb responseBuffer
 	err := f(&rb, req)
 	if err != nil {
-		if ce, ok := err.(clientError); ok {
-			http.Error(w, ce.clientError(), ce.httpStatus())
-			return
-		}
-		if se, ok := err.(serverError); ok {
-			http.Error(w, se.serverError(), se.httpStatus())
-			return
-		}
-
-		se := errInternalServerError.WithDetail(err.Error())
-		http.Error(w, se.serverError(), se.httpStatus())
+		writeError(w, err)
+		return
 	}
 	rb.WriteTo(w)
 }
+
+// streamHandler is a handler which writes its response directly
+// without buffering.  It should return an error only before writing
+// anything.
+type streamHandler func(w http.ResponseWriter, req *http.Request) error
+
+func (f streamHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if err := f(w, req); err != nil {
+		writeError(w, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if ce, ok := err.(clientError); ok {
+		http.Error(w, ce.clientError(), ce.httpStatus())
+		return
+	}
+	if se, ok := err.(serverError); ok {
+		http.Error(w, se.serverError(), se.httpStatus())
+		return
+	}
+
+	se := errInternalServerError.WithDetail(err.Error())
+	http.Error(w, se.serverError(), se.httpStatus())
+}
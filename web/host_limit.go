@@ -0,0 +1,67 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fireworq/fireworq/model"
+
+	"github.com/gorilla/mux"
+)
+
+func (app *Application) serveHostLimitList(w http.ResponseWriter, req *http.Request) error {
+	limits, err := app.HostLimitRepository.FindAll()
+	if err != nil {
+		return err
+	}
+
+	json, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, json)
+
+	return nil
+}
+
+func (app *Application) serveHostLimit(w http.ResponseWriter, req *http.Request) error {
+	vars := mux.Vars(req)
+	host := vars["host"]
+	var definition model.HostLimit
+
+	if req.Method == "PUT" {
+		decoder := json.NewDecoder(req.Body)
+		if err := decoder.Decode(&definition); err != nil {
+			return errBadRequest.WithDetail(err.Error())
+		}
+		definition.Host = host
+
+		if definition.MaxRequestsPerSecond < 0.0 {
+			return errBadRequest.WithDetail("max_requests_per_second should be non-negative")
+		}
+
+		if _, err := app.HostLimitRepository.Add(&definition); err != nil {
+			return err
+		}
+	} else {
+		l, err := app.HostLimitRepository.FindByHost(host)
+		if err != nil {
+			return errNotFound
+		}
+		definition = *l
+
+		if req.Method == "DELETE" {
+			if err := app.HostLimitRepository.DeleteByHost(host); err != nil {
+				return err
+			}
+		}
+	}
+
+	j, err := json.Marshal(&definition)
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, j)
+	return nil
+}
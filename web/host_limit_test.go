@@ -0,0 +1,304 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/fireworq/fireworq/model"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestGetHostLimitList(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.HostLimitRepository.EXPECT().
+			FindAll().
+			Return([]model.HostLimit{}, errors.New("FindAll() failure"))
+
+		resp, err := http.Get(s.URL + "/host_limits")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Error("GET /host_limits should fail")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		limits := []model.HostLimit{
+			{
+				Host:           "worker1.example.com",
+				MaxConcurrency: 10,
+			},
+			{
+				Host:                 "worker2.example.com:8080",
+				MaxRequestsPerSecond: 2.5,
+				MaxBurstSize:         5,
+			},
+		}
+		mockApp.HostLimitRepository.EXPECT().
+			FindAll().
+			Return(limits, nil)
+
+		resp, err := http.Get(s.URL + "/host_limits")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("GET /host_limits should succeed")
+		}
+
+		var ls []model.HostLimit
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err := json.Unmarshal(buf, &ls); err != nil {
+			t.Error(err)
+		}
+		if len(ls) != len(limits) {
+			t.Error("GET /host_limits should return defined host limits")
+		}
+		for i, l := range ls {
+			if l != limits[i] {
+				t.Error("GET /host_limits should return defined host limits")
+			}
+		}
+	}()
+}
+
+func TestGetHostLimit(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.HostLimitRepository.EXPECT().
+			FindByHost("worker1.example.com").
+			Return(nil, errors.New("Host limit not found"))
+
+		resp, err := http.Get(s.URL + "/host_limit/worker1.example.com")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error("GET /host_limit/$host should return 404")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		limit := &model.HostLimit{Host: "worker2.example.com:8080", MaxConcurrency: 3}
+		mockApp.HostLimitRepository.EXPECT().
+			FindByHost(limit.Host).
+			Return(limit, nil)
+
+		resp, err := http.Get(s.URL + "/host_limit/worker2.example.com:8080")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("GET /host_limit/$host should succeed")
+		}
+
+		var l model.HostLimit
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err := json.Unmarshal(buf, &l); err != nil {
+			t.Error(err)
+		}
+		if l != *limit {
+			t.Error("GET /host_limit/$host should return a defined host limit")
+		}
+	}()
+}
+
+func TestPutHostLimit(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, _ := newMockServer(ctrl)
+		defer s.Close()
+
+		resp, err := putJSON(s.URL+"/host_limit/worker1.example.com", "foo")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("PUT /host_limit/$host should reject invalid input")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, _ := newMockServer(ctrl)
+		defer s.Close()
+
+		def := &model.HostLimit{MaxRequestsPerSecond: -1.0}
+
+		resp, err := putJSON(s.URL+"/host_limit/worker1.example.com", def)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("PUT /host_limit/$host should reject a negative rate")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		def := &model.HostLimit{Host: "worker1.example.com", MaxConcurrency: 5}
+		mockApp.HostLimitRepository.EXPECT().
+			Add(def).
+			Return(false, errors.New("Add() failure"))
+
+		resp, err := putJSON(s.URL+"/host_limit/worker1.example.com", def)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Error("PUT /host_limit/$host should fail")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		def := &model.HostLimit{
+			Host:                 "worker1.example.com",
+			MaxConcurrency:       5,
+			MaxRequestsPerSecond: 10,
+			MaxBurstSize:         2,
+		}
+		mockApp.HostLimitRepository.EXPECT().
+			Add(def).
+			Return(true, nil)
+
+		resp, err := putJSON(s.URL+"/host_limit/worker1.example.com", def)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("PUT /host_limit/$host should succeed")
+		}
+
+		var l model.HostLimit
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err := json.Unmarshal(buf, &l); err != nil {
+			t.Error(err)
+		}
+		if l != *def {
+			t.Error("PUT /host_limit/$host should return a defined host limit")
+		}
+	}()
+}
+
+func TestDeleteHostLimit(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.HostLimitRepository.EXPECT().
+			FindByHost("worker1.example.com").
+			Return(nil, errors.New("Host limit not found"))
+
+		resp, err := httpDelete(s.URL + "/host_limit/worker1.example.com")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error("DELETE /host_limit/$host should return 404")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		limit := &model.HostLimit{Host: "worker1.example.com", MaxConcurrency: 5}
+		mockApp.HostLimitRepository.EXPECT().
+			FindByHost(limit.Host).
+			Return(limit, nil)
+		mockApp.HostLimitRepository.EXPECT().
+			DeleteByHost(limit.Host).
+			Return(errors.New("DeleteByHost() failure"))
+
+		resp, err := httpDelete(s.URL + "/host_limit/worker1.example.com")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Error("DELETE /host_limit/$host should fail")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		limit := &model.HostLimit{Host: "worker1.example.com", MaxConcurrency: 5}
+		mockApp.HostLimitRepository.EXPECT().
+			FindByHost(limit.Host).
+			Return(limit, nil)
+		mockApp.HostLimitRepository.EXPECT().
+			DeleteByHost(limit.Host).
+			Return(nil)
+
+		resp, err := httpDelete(s.URL + "/host_limit/worker1.example.com")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("DELETE /host_limit/$host should succeed")
+		}
+
+		var l model.HostLimit
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err := json.Unmarshal(buf, &l); err != nil {
+			t.Error(err)
+		}
+		if l != *limit {
+			t.Error("DELETE /host_limit/$host should return a deleted host limit")
+		}
+	}()
+}
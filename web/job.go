@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/fireworq/fireworq/jobqueue"
+
 	"github.com/gorilla/mux"
 )
 
@@ -31,6 +33,9 @@ func (app *Application) serveJob(w http.ResponseWriter, req *http.Request) error
 
 	r, err := app.Service.Push(&job)
 	if err != nil {
+		if e, ok := err.(*jobqueue.UnsupportedJobError); ok {
+			return errBadRequest.WithDetail(e.Error())
+		}
 		return err
 	}
 
@@ -56,6 +61,11 @@ type IncomingJob struct {
 	TimeoutField    uint `json:"timeout"`     // seconds
 	RetryDelayField uint `json:"retry_delay"` // seconds
 	MaxRetriesField uint `json:"max_retries"`
+
+	ConcurrencyKeyField   string `json:"concurrency_key,omitempty"`
+	ConcurrencyLimitField uint   `json:"concurrency_limit,omitempty"`
+
+	GroupIDField string `json:"group_id,omitempty"`
 }
 
 // PushResult describes a job pushed to a queue.
@@ -127,3 +137,24 @@ func (job *IncomingJob) RetryDelay() uint {
 func (job *IncomingJob) Timeout() uint {
 	return job.TimeoutField
 }
+
+// ConcurrencyKey returns the key of the job.  Jobs sharing the same
+// key are not dispatched more than ConcurrencyLimit() at once.
+func (job *IncomingJob) ConcurrencyKey() string {
+	return job.ConcurrencyKeyField
+}
+
+// ConcurrencyLimit returns the maximum number of jobs of the same
+// ConcurrencyKey() running at once.
+func (job *IncomingJob) ConcurrencyLimit() uint {
+	if job.ConcurrencyKeyField != "" && job.ConcurrencyLimitField == 0 {
+		return 1
+	}
+	return job.ConcurrencyLimitField
+}
+
+// GroupID returns the group of the job.  Jobs in the same group are
+// processed one by one in the order they are pushed.
+func (job *IncomingJob) GroupID() string {
+	return job.GroupIDField
+}
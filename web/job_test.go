@@ -338,4 +338,28 @@ func TestIncomingJob(t *testing.T) {
 			t.Error("Wrong job property")
 		}
 	}
+
+	{
+		j := &IncomingJob{
+			CategoryField: "test_job",
+			URLField:      "http://example.com/",
+		}
+
+		if j.ConcurrencyKey() != "" || j.ConcurrencyLimit() != 0 {
+			t.Error("A job should have no concurrency key by default")
+		}
+
+		j.ConcurrencyKeyField = "user1"
+		if j.ConcurrencyKey() != "user1" {
+			t.Error("Wrong job property")
+		}
+		if j.ConcurrencyLimit() != uint(1) {
+			t.Error("A concurrency limit should default to 1")
+		}
+
+		j.ConcurrencyLimitField = 3
+		if j.ConcurrencyLimit() != uint(3) {
+			t.Error("Wrong job property")
+		}
+	}
 }
@@ -0,0 +1,181 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fireworq/fireworq/dispatcher"
+	"github.com/fireworq/fireworq/jobqueue"
+
+	"github.com/gorilla/mux"
+)
+
+// ReservedJob describes a job leased to a worker pulling jobs.
+type ReservedJob struct {
+	ID         uint64          `json:"id"`
+	Category   string          `json:"category"`
+	URL        string          `json:"url"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Timeout    uint            `json:"timeout"`
+	RetryCount uint            `json:"retry_count"`
+	FailCount  uint            `json:"fail_count"`
+	Deadline   time.Time       `json:"deadline"`
+	Token      string          `json:"token"`
+}
+
+func newReservedJob(l *dispatcher.Lease) *ReservedJob {
+	loggable := l.Job.ToLoggable()
+
+	payload := json.RawMessage(l.Job.Payload())
+	if len(payload) > 0 && !json.Valid(payload) {
+		payload, _ = json.Marshal(l.Job.Payload())
+	}
+
+	return &ReservedJob{
+		ID:         loggable.ID(),
+		Category:   loggable.Category(),
+		URL:        l.Job.URL(),
+		Payload:    payload,
+		Timeout:    l.Job.Timeout(),
+		RetryCount: l.Job.RetryCount(),
+		FailCount:  l.Job.FailCount(),
+		Deadline:   l.Deadline,
+		Token:      l.Token,
+	}
+}
+
+func (app *Application) puller(qn string) (dispatcher.Puller, error) {
+	q, ok := app.Service.GetJobQueue(qn)
+	if !ok {
+		return nil, errNotFound.WithDetail(fmt.Sprintf("No such queue: %s", qn))
+	}
+
+	p, ok := q.Puller()
+	if !ok {
+		return nil, errBadRequest.WithDetail(fmt.Sprintf("Jobs cannot be pulled from queue: %s", qn))
+	}
+	return p, nil
+}
+
+func (app *Application) serveQueueReserve(w http.ResponseWriter, req *http.Request) error {
+	if req.Method != "POST" {
+		return errMethodNotAllowed
+	}
+
+	vars := mux.Vars(req)
+	query := req.URL.Query()
+
+	p, err := app.puller(vars["queue"])
+	if err != nil {
+		return err
+	}
+
+	max := uint(1)
+	if v := query.Get("max"); v != "" {
+		m, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || m == 0 {
+			return errBadRequest.WithDetail(fmt.Sprintf("Invalid max: %s", v))
+		}
+		max = uint(m)
+	}
+
+	var visibility uint
+	if v := query.Get("visibility"); v != "" {
+		s, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return errBadRequest.WithDetail(fmt.Sprintf("Invalid visibility: %s", v))
+		}
+		visibility = uint(s)
+	}
+
+	leases, err := p.Reserve(max, visibility)
+	if err != nil {
+		switch err.(type) {
+		case *jobqueue.InactiveError, *jobqueue.ConnectionClosedError:
+			return errServiceUnavailable.WithDetail(err.Error())
+		}
+		return err
+	}
+
+	jobs := make([]*ReservedJob, 0, len(leases))
+	for _, l := range leases {
+		jobs = append(jobs, newReservedJob(l))
+	}
+
+	j, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, j)
+
+	return nil
+}
+
+// Nack describes a negative acknowledgement of a reserved job.
+type Nack struct {
+	Message string `json:"message"`
+}
+
+func (app *Application) serveQueueJobAck(w http.ResponseWriter, req *http.Request) error {
+	return app.serveQueueJobAcknowledgement(w, req, func(p dispatcher.Puller, id uint64, token string) error {
+		return p.Ack(id, token)
+	})
+}
+
+func (app *Application) serveQueueJobNack(w http.ResponseWriter, req *http.Request) error {
+	return app.serveQueueJobAcknowledgement(w, req, func(p dispatcher.Puller, id uint64, token string) error {
+		var delay uint
+		if v := req.URL.Query().Get("delay"); v != "" {
+			d, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return errBadRequest.WithDetail(fmt.Sprintf("Invalid delay: %s", v))
+			}
+			delay = uint(d)
+		}
+
+		var nack Nack
+		if err := json.NewDecoder(req.Body).Decode(&nack); err != nil && err != io.EOF {
+			return errBadRequest.WithDetail(err.Error())
+		}
+
+		return p.Nack(id, token, delay, nack.Message)
+	})
+}
+
+func (app *Application) serveQueueJobAcknowledgement(w http.ResponseWriter, req *http.Request, acknowledge func(dispatcher.Puller, uint64, string) error) error {
+	if req.Method != "POST" {
+		return errMethodNotAllowed
+	}
+
+	vars := mux.Vars(req)
+
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return errBadRequest
+	}
+
+	token := req.URL.Query().Get("token")
+	if token == "" {
+		return errBadRequest.WithDetail("Missing token")
+	}
+
+	p, err := app.puller(vars["queue"])
+	if err != nil {
+		return err
+	}
+
+	err = acknowledge(p, id, token)
+	if _, ok := err.(*dispatcher.NoSuchLeaseError); ok {
+		return errNotFound.WithDetail(err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, []byte(fmt.Sprintf(`{"id":%d}`, id)))
+	return nil
+}
@@ -0,0 +1,273 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fireworq/fireworq/dispatcher"
+	"github.com/fireworq/fireworq/jobqueue"
+	"github.com/fireworq/fireworq/jobqueue/logger"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestReserve(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.Service.EXPECT().
+			GetJobQueue("queue1").
+			Return(nil, false)
+
+		resp, err := http.Post(s.URL+"/queue/queue1/reserve", "application/json", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error("POST /queue/$name/reserve should return 404 for an undefined queue")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.Service.EXPECT().
+			GetJobQueue("queue1").
+			Return(newMockRunningQueue(NewMockJobQueue(ctrl), nil), true)
+
+		resp, err := http.Post(s.URL+"/queue/queue1/reserve", "application/json", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("POST /queue/$name/reserve should reject a queue which does not allow pulling")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		p := &mockPuller{}
+		mockApp.Service.EXPECT().
+			GetJobQueue("queue1").
+			Return(newMockPullingQueue(NewMockJobQueue(ctrl), p), true).
+			AnyTimes()
+
+		resp, err := http.Post(s.URL+"/queue/queue1/reserve?max=0", "application/json", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("POST /queue/$name/reserve should reject invalid max")
+		}
+
+		resp, err = http.Get(s.URL + "/queue/queue1/reserve")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Error("GET /queue/$name/reserve should not be allowed")
+		}
+
+		resp, err = http.Post(s.URL+"/queue/queue1/reserve?max=2&visibility=60", "application/json", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("POST /queue/$name/reserve should succeed")
+		}
+		if p.max != 2 || p.visibility != 60 {
+			t.Errorf("Wrong parameters: %d %d", p.max, p.visibility)
+		}
+
+		var jobs []ReservedJob
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err := json.Unmarshal(buf, &jobs); err != nil {
+			t.Error(err)
+		}
+		if len(jobs) != 2 ||
+			jobs[0].ID != 1 ||
+			string(jobs[0].Payload) != `{"foo":1}` ||
+			string(jobs[1].Payload) != `"bar"` ||
+			jobs[0].URL != "http://example.com/" ||
+			jobs[0].Token != "t1" {
+			t.Errorf("POST /queue/$name/reserve should return reserved jobs: %s", string(buf))
+		}
+
+		p.err = &jobqueue.InactiveError{}
+		resp, err = http.Post(s.URL+"/queue/queue1/reserve", "application/json", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Error("POST /queue/$name/reserve should fail on an inactive node")
+		}
+	}()
+}
+
+func TestAckNack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s, mockApp := newMockServer(ctrl)
+	defer s.Close()
+
+	p := &mockPuller{}
+	mockApp.Service.EXPECT().
+		GetJobQueue("queue1").
+		Return(newMockPullingQueue(NewMockJobQueue(ctrl), p), true).
+		AnyTimes()
+
+	resp, err := http.Post(s.URL+"/queue/queue1/job/1/ack?token=t1", "application/json", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || p.acked != 1 {
+		t.Error("POST /queue/$name/job/$id/ack should succeed")
+	}
+
+	resp, err = http.Post(s.URL+"/queue/queue1/job/100/ack?token=t100", "application/json", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error("POST /queue/$name/job/$id/ack should return 404 for a job not leased")
+	}
+
+	resp, err = http.Post(s.URL+"/queue/queue1/job/2/ack?token=t1", "application/json", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Error("POST /queue/$name/job/$id/ack should return 404 for a wrong token")
+	}
+
+	resp, err = http.Post(s.URL+"/queue/queue1/job/2/ack", "application/json", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error("POST /queue/$name/job/$id/ack should require a token")
+	}
+
+	resp, err = http.Post(s.URL+"/queue/queue1/job/2/nack?token=t2&delay=30", "application/json", strings.NewReader(`{"message":"oops"}`))
+	if err != nil {
+		t.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || p.nacked != 2 || p.delay != 30 || p.message != "oops" {
+		t.Error("POST /queue/$name/job/$id/nack should succeed")
+	}
+
+	resp, err = http.Post(s.URL+"/queue/queue1/job/2/nack?token=t2", "application/json", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || p.delay != 0 || p.message != "" {
+		t.Error("POST /queue/$name/job/$id/nack should succeed without a body")
+	}
+
+	resp, err = http.Post(s.URL+"/queue/queue1/job/2/nack?token=t2&delay=soon", "application/json", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error("POST /queue/$name/job/$id/nack should reject invalid delay")
+	}
+}
+
+type mockPullingQueue struct {
+	*mockRunningQueue
+	puller dispatcher.Puller
+}
+
+func newMockPullingQueue(jq jobQueue, p dispatcher.Puller) *mockPullingQueue {
+	return &mockPullingQueue{newMockRunningQueue(jq, nil), p}
+}
+
+func (q *mockPullingQueue) Puller() (dispatcher.Puller, bool) {
+	return q.puller, true
+}
+
+type mockPuller struct {
+	max        uint
+	visibility uint
+	err        error
+	acked      uint64
+	nacked     uint64
+	delay      uint
+	message    string
+}
+
+func (p *mockPuller) Reserve(max uint, visibility uint) ([]*dispatcher.Lease, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.max, p.visibility = max, visibility
+	deadline := time.Now().Add(time.Duration(visibility) * time.Second)
+	return []*dispatcher.Lease{
+		{Job: &pulledJob{id: 1, payload: `{"foo":1}`}, Deadline: deadline, Token: "t1"},
+		{Job: &pulledJob{id: 2, payload: `bar`}, Deadline: deadline, Token: "t2"},
+	}, nil
+}
+
+func (p *mockPuller) Ack(id uint64, token string) error {
+	if id > 2 || token != fmt.Sprintf("t%d", id) {
+		return &dispatcher.NoSuchLeaseError{ID: id}
+	}
+	p.acked = id
+	return nil
+}
+
+func (p *mockPuller) Nack(id uint64, token string, delay uint, message string) error {
+	if id > 2 || token != fmt.Sprintf("t%d", id) {
+		return &dispatcher.NoSuchLeaseError{ID: id}
+	}
+	p.nacked, p.delay, p.message = id, delay, message
+	return nil
+}
+
+type pulledJob struct {
+	jobqueue.Job
+	id      uint64
+	payload string
+}
+
+func (j *pulledJob) URL() string                    { return "http://example.com/" }
+func (j *pulledJob) Payload() string                { return j.payload }
+func (j *pulledJob) Timeout() uint                  { return 0 }
+func (j *pulledJob) RetryCount() uint               { return 0 }
+func (j *pulledJob) FailCount() uint                { return 0 }
+func (j *pulledJob) ToLoggable() logger.LoggableJob { return &loggablePulledJob{id: j.id} }
+
+type loggablePulledJob struct {
+	logger.LoggableJob
+	id uint64
+}
+
+func (j *loggablePulledJob) ID() uint64       { return j.id }
+func (j *loggablePulledJob) Category() string { return "pulled" }
@@ -123,6 +123,24 @@ func (app *Application) serveQueueNode(w http.ResponseWriter, req *http.Request)
 	return nil
 }
 
+func (app *Application) serveQueuePing(w http.ResponseWriter, req *http.Request) error {
+	if req.Method != "POST" {
+		return errMethodNotAllowed
+	}
+
+	vars := mux.Vars(req)
+
+	q, ok := app.Service.GetJobQueue(vars["queue"])
+	if !ok {
+		return errNotFound.WithDetail(fmt.Sprintf("No such queue: %s", vars["queue"]))
+	}
+
+	q.Ping()
+
+	writeJSON(w, []byte(fmt.Sprintf(`{"active":%t}`, q.IsActive())))
+	return nil
+}
+
 func (app *Application) serveQueueStats(w http.ResponseWriter, req *http.Request) error {
 	vars := mux.Vars(req)
 
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/signature"
+
+	"github.com/gorilla/mux"
+)
+
+func (app *Application) serveQueueSecret(w http.ResponseWriter, req *http.Request) error {
+	vars := mux.Vars(req)
+	qn := vars["queue"]
+	var definition model.QueueSecret
+
+	if req.Method == "PUT" {
+		decoder := json.NewDecoder(req.Body)
+		if err := decoder.Decode(&definition); err != nil {
+			return errBadRequest.WithDetail(err.Error())
+		}
+		definition.QueueName = qn
+
+		if len(definition.Secrets) == 0 || len(definition.Secrets) > model.MaxQueueSecrets {
+			return errBadRequest.WithDetail(fmt.Sprintf("secrets should have 1 to %d elements", model.MaxQueueSecrets))
+		}
+		for _, secret := range definition.Secrets {
+			if secret == "" {
+				return errBadRequest.WithDetail("secrets should not be empty")
+			}
+		}
+
+		if _, err := app.QueueSecretRepository.Add(&definition); err != nil {
+			return err
+		}
+	} else {
+		s, err := app.QueueSecretRepository.FindByQueueName(qn)
+		if err != nil {
+			return errNotFound
+		}
+		definition = *s
+
+		if req.Method == "DELETE" {
+			if err := app.QueueSecretRepository.DeleteByQueueName(qn); err != nil {
+				return err
+			}
+		}
+	}
+
+	j, err := json.Marshal(newQueueSecretView(&definition))
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, j)
+	return nil
+}
+
+// queueSecretView describes secrets of a queue in responses.  Secrets
+// are write-only and only their fingerprints are returned.
+type queueSecretView struct {
+	QueueName    string   `json:"queue_name"`
+	Fingerprints []string `json:"fingerprints"`
+}
+
+func newQueueSecretView(s *model.QueueSecret) *queueSecretView {
+	fingerprints := make([]string, len(s.Secrets))
+	for i, secret := range s.Secrets {
+		fingerprints[i] = signature.Fingerprint(secret)
+	}
+	return &queueSecretView{QueueName: s.QueueName, Fingerprints: fingerprints}
+}
@@ -0,0 +1,205 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fireworq/fireworq/model"
+	"github.com/fireworq/fireworq/signature"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestGetQueueSecret(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.QueueSecretRepository.EXPECT().
+			FindByQueueName("queue1").
+			Return(nil, errors.New("Queue secret not found"))
+
+		resp, err := http.Get(s.URL + "/queue/queue1/secret")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error("GET /queue/$queue/secret should return 404")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		secret := &model.QueueSecret{QueueName: "queue1", Secrets: []string{"new", "old"}}
+		mockApp.QueueSecretRepository.EXPECT().
+			FindByQueueName(secret.QueueName).
+			Return(secret, nil)
+
+		resp, err := http.Get(s.URL + "/queue/queue1/secret")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("GET /queue/$queue/secret should succeed")
+		}
+
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if strings.Contains(string(buf), "new") || strings.Contains(string(buf), "old") {
+			t.Error("GET /queue/$queue/secret should not return secrets")
+		}
+		var qs queueSecretView
+		if err := json.Unmarshal(buf, &qs); err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(&qs, newQueueSecretView(secret)) {
+			t.Error("GET /queue/$queue/secret should return fingerprints of defined secrets")
+		}
+	}()
+}
+
+func TestPutQueueSecret(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, _ := newMockServer(ctrl)
+		defer s.Close()
+
+		resp, err := putJSON(s.URL+"/queue/queue1/secret", "foo")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("PUT /queue/$queue/secret should reject invalid input")
+		}
+	}()
+
+	for _, secrets := range [][]string{{}, {"a", "b", "c"}, {"a", ""}} {
+		func() {
+			ctrl := gomock.NewController(t)
+			s, _ := newMockServer(ctrl)
+			defer s.Close()
+
+			resp, err := putJSON(s.URL+"/queue/queue1/secret", &model.QueueSecret{Secrets: secrets})
+			if err != nil {
+				t.Error(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("PUT /queue/$queue/secret should reject secrets %v", secrets)
+			}
+		}()
+	}
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		def := &model.QueueSecret{QueueName: "queue1", Secrets: []string{"secret"}}
+		mockApp.QueueSecretRepository.EXPECT().
+			Add(def).
+			Return(false, errors.New("Add() failure"))
+
+		resp, err := putJSON(s.URL+"/queue/queue1/secret", def)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Error("PUT /queue/$queue/secret should fail")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		def := &model.QueueSecret{QueueName: "queue1", Secrets: []string{"new", "old"}}
+		mockApp.QueueSecretRepository.EXPECT().
+			Add(def).
+			Return(true, nil)
+
+		resp, err := putJSON(s.URL+"/queue/queue1/secret", def)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("PUT /queue/$queue/secret should succeed")
+		}
+
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if strings.Contains(string(buf), "new") || strings.Contains(string(buf), "old") {
+			t.Error("PUT /queue/$queue/secret should not return secrets")
+		}
+		var qs queueSecretView
+		if err := json.Unmarshal(buf, &qs); err != nil {
+			t.Error(err)
+		}
+		if len(qs.Fingerprints) != 2 || qs.Fingerprints[0] != signature.Fingerprint("new") {
+			t.Error("PUT /queue/$queue/secret should return fingerprints of defined secrets")
+		}
+	}()
+}
+
+func TestDeleteQueueSecret(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.QueueSecretRepository.EXPECT().
+			FindByQueueName("queue1").
+			Return(nil, errors.New("Queue secret not found"))
+
+		resp, err := httpDelete(s.URL + "/queue/queue1/secret")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error("DELETE /queue/$queue/secret should return 404")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		secret := &model.QueueSecret{QueueName: "queue1", Secrets: []string{"secret"}}
+		mockApp.QueueSecretRepository.EXPECT().
+			FindByQueueName(secret.QueueName).
+			Return(secret, nil)
+		mockApp.QueueSecretRepository.EXPECT().
+			DeleteByQueueName(secret.QueueName).
+			Return(nil)
+
+		resp, err := httpDelete(s.URL + "/queue/queue1/secret")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("DELETE /queue/$queue/secret should succeed")
+		}
+	}()
+}
@@ -800,6 +800,7 @@ func TestGetQueueNode(t *testing.T) {
 		node := &jobqueue.Node{
 			ID:   "123",
 			Host: "192.168.1.2",
+			URL:  "http://192.168.1.2:8080",
 		}
 
 		mockJobQueue := NewMockJobQueue(ctrl)
@@ -827,12 +828,78 @@ func TestGetQueueNode(t *testing.T) {
 		if err := json.Unmarshal(buf, &result); err != nil {
 			t.Error(err)
 		}
-		if result.ID != node.ID || result.Host != node.Host {
+		if result.ID != node.ID || result.Host != node.Host || result.URL != node.URL {
 			t.Error("GET /queue/$name/node should return correct node info")
 		}
 	}()
 }
 
+func TestPostQueuePing(t *testing.T) {
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockApp.Service.EXPECT().
+			GetJobQueue(gomock.Any()).
+			Return(nil, false)
+
+		resp, err := http.Post(s.URL+"/queue/queue1/ping", "application/json", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error("POST /queue/$name/ping should return 404 for an undefined queue")
+		}
+	}()
+
+	func() {
+		ctrl := gomock.NewController(t)
+		s, mockApp := newMockServer(ctrl)
+		defer s.Close()
+
+		mockJobQueue := NewMockJobQueue(ctrl)
+		mockJobQueue.EXPECT().
+			IsActive().
+			Return(true)
+
+		q := newMockRunningQueue(mockJobQueue, nil)
+		mockApp.Service.EXPECT().
+			GetJobQueue(gomock.Any()).
+			Return(q, true).
+			AnyTimes()
+
+		resp, err := http.Get(s.URL + "/queue/queue1/ping")
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Error("GET /queue/$name/ping should not be allowed")
+		}
+
+		resp, err = http.Post(s.URL+"/queue/queue1/ping", "application/json", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error("POST /queue/$name/ping should succeed")
+		}
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(buf) != `{"active":true}` {
+			t.Errorf("POST /queue/$name/ping should return whether the queue is active: %s", string(buf))
+		}
+		if q.pinged != 1 {
+			t.Error("POST /queue/$name/ping should ping the queue")
+		}
+	}()
+}
+
 func TestGetQueueStats(t *testing.T) {
 	func() {
 		ctrl := gomock.NewController(t)
@@ -2883,11 +2950,12 @@ type jobQueue = jobqueue.JobQueue
 
 type mockRunningQueue struct {
 	jobQueue
-	stats *dispatcher.Stats
+	stats  *dispatcher.Stats
+	pinged int
 }
 
 func newMockRunningQueue(jq jobQueue, stats *dispatcher.Stats) *mockRunningQueue {
-	return &mockRunningQueue{jq, stats}
+	return &mockRunningQueue{jobQueue: jq, stats: stats}
 }
 
 func (q *mockRunningQueue) PollingInterval() uint {
@@ -2902,6 +2970,14 @@ func (q *mockRunningQueue) WorkerStats() *dispatcher.Stats {
 	return q.stats
 }
 
+func (q *mockRunningQueue) Puller() (dispatcher.Puller, bool) {
+	return nil, false
+}
+
+func (q *mockRunningQueue) Ping() {
+	q.pinged++
+}
+
 func (q *mockRunningQueue) Deactivate() <-chan struct{} {
 	deactivated := make(chan struct{})
 	go func() {
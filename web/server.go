@@ -51,6 +51,7 @@ type server struct {
 	addrs       []net.Addr
 	makeHandler func(h http.Handler) http.Handler
 	mux         *mux.Router
+	shutdown    chan struct{} // closed to end streams on shutdown
 }
 
 func newServer(out io.Writer) *server {
@@ -74,13 +75,15 @@ func newServer(out io.Writer) *server {
 		makeHandler: func(h http.Handler) http.Handler {
 			return hlog.NewHandler(logger)(accessLog(remoteAddr(ua(h))))
 		},
-		mux: mux.NewRouter(),
+		mux:      mux.NewRouter(),
+		shutdown: make(chan struct{}),
 	}
 	return s
 }
 
 func (s *server) start() (*http.Server, error) {
 	server := &http.Server{Handler: s.mux}
+	server.RegisterOnShutdown(func() { close(s.shutdown) })
 
 	listeners, err := serverstarter.ListenAll()
 	if err == serverstarter.ErrNoListeningTarget {
@@ -127,3 +130,22 @@ func (s *server) startGracefully(shutdownTimeout time.Duration) (time.Duration,
 func (s *server) handle(pattern string, h func(http.ResponseWriter, *http.Request) error) {
 	s.mux.Handle(pattern, s.makeHandler(handler(h)))
 }
+
+// handleStream registers a handler of a long-lived response, whose
+// request context is canceled when the server shuts down so that
+// Shutdown() does not wait for it.
+func (s *server) handleStream(pattern string, h func(http.ResponseWriter, *http.Request) error) {
+	stream := streamHandler(func(w http.ResponseWriter, req *http.Request) error {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-s.shutdown:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		return h(w, req.WithContext(ctx))
+	})
+	s.mux.Handle(pattern, s.makeHandler(stream))
+}
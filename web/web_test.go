@@ -1,5 +1,5 @@
 //go:generate mockgen -package web -destination mock_web_test.go github.com/fireworq/fireworq/web Service
-//go:generate mockgen -package web -destination mock_web_repository_test.go github.com/fireworq/fireworq/repository QueueRepository,RoutingRepository
+//go:generate mockgen -package web -destination mock_web_repository_test.go github.com/fireworq/fireworq/repository QueueRepository,RoutingRepository,HostLimitRepository,QueueSecretRepository
 //go:generate mockgen -package web -destination mock_jobqueue_test.go github.com/fireworq/fireworq/jobqueue JobQueue
 //go:generate mockgen -package web -destination mock_inspector_test.go github.com/fireworq/fireworq/jobqueue Inspector,FailureLog
 
@@ -62,28 +62,36 @@ func httpDelete(url string) (*http.Response, error) {
 func NewMockApplication(ctrl *gomock.Controller) *Application {
 	mockQueueRepo := NewMockQueueRepository(ctrl)
 	mockRoutingRepo := NewMockRoutingRepository(ctrl)
+	mockHostLimitRepo := NewMockHostLimitRepository(ctrl)
+	mockQueueSecretRepo := NewMockQueueSecretRepository(ctrl)
 	mockService := NewMockService(ctrl)
 	return &Application{
-		Service:           mockService,
-		QueueRepository:   mockQueueRepo,
-		RoutingRepository: mockRoutingRepo,
-		Version:           "Fireworq 0.1.0-TEST",
+		Service:               mockService,
+		QueueRepository:       mockQueueRepo,
+		RoutingRepository:     mockRoutingRepo,
+		HostLimitRepository:   mockHostLimitRepo,
+		QueueSecretRepository: mockQueueSecretRepo,
+		Version:               "Fireworq 0.1.0-TEST",
 	}
 }
 
 func newMockServer(ctrl *gomock.Controller) (*httptest.Server, *mockApplication) {
 	app := NewMockApplication(ctrl)
 	return httptest.NewServer(app.newServer().mux), &mockApplication{
-		Service:           app.Service.(*MockService),
-		QueueRepository:   app.QueueRepository.(*MockQueueRepository),
-		RoutingRepository: app.RoutingRepository.(*MockRoutingRepository),
+		Service:               app.Service.(*MockService),
+		QueueRepository:       app.QueueRepository.(*MockQueueRepository),
+		RoutingRepository:     app.RoutingRepository.(*MockRoutingRepository),
+		HostLimitRepository:   app.HostLimitRepository.(*MockHostLimitRepository),
+		QueueSecretRepository: app.QueueSecretRepository.(*MockQueueSecretRepository),
 	}
 }
 
 type mockApplication struct {
-	Service           *MockService
-	QueueRepository   *MockQueueRepository
-	RoutingRepository *MockRoutingRepository
+	Service               *MockService
+	QueueRepository       *MockQueueRepository
+	RoutingRepository     *MockRoutingRepository
+	HostLimitRepository   *MockHostLimitRepository
+	QueueSecretRepository *MockQueueSecretRepository
 }
 
 type emptyObject struct{}